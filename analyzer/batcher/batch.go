@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"time"
 
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
 	"github.com/nrf24l01/sniffly/capture_receiver/rabbit"
 	"github.com/nrf24l01/sniffly/capturer/snifpacket"
+	"google.golang.org/protobuf/proto"
 )
 
 type Batch struct {
@@ -14,7 +16,14 @@ type Batch struct {
 	To      time.Time
 }
 
-func (b *Batch) AddMessage(msg []byte) error {
+func (b *Batch) AddMessage(msg []byte, contentType string) error {
+	switch contentType {
+	case rabbit.ContentTypeProtobuf:
+		return b.addProtoMessage(msg)
+	case rabbit.ContentTypeProtobufBatch:
+		return b.addProtoBatch(msg)
+	}
+
 	var packet rabbit.Message
 	err := json.Unmarshal(msg, &packet)
 	if err != nil {
@@ -29,4 +38,51 @@ func (b *Batch) AddMessage(msg []byte) error {
 
 	b.Packets = append(b.Packets, snifPacket)
 	return nil
-}
\ No newline at end of file
+}
+
+func (b *Batch) addProtoMessage(msg []byte) error {
+	var packet pb.QueueMessage
+	if err := proto.Unmarshal(msg, &packet); err != nil {
+		return err
+	}
+
+	snifPacket, err := queuedPacket(&packet)
+	if err != nil {
+		return err
+	}
+
+	b.Packets = append(b.Packets, snifPacket)
+	return nil
+}
+
+// addProtoBatch adds every packet of a batch, or none if one is broken, so
+// the message can be requeued whole.
+func (b *Batch) addProtoBatch(msg []byte) error {
+	var queueBatch pb.QueueBatch
+	if err := proto.Unmarshal(msg, &queueBatch); err != nil {
+		return err
+	}
+
+	packets := make([]snifpacket.SnifPacket, 0, len(queueBatch.Messages))
+	for _, packet := range queueBatch.Messages {
+		snifPacket, err := queuedPacket(packet)
+		if err != nil {
+			return err
+		}
+		packets = append(packets, snifPacket)
+	}
+
+	b.Packets = append(b.Packets, packets...)
+	return nil
+}
+
+func queuedPacket(packet *pb.QueueMessage) (snifpacket.SnifPacket, error) {
+	if packet.Packet != nil {
+		return snifpacket.FromProto(packet.Packet), nil
+	}
+
+	// Typed envelope around a legacy JSON payload
+	var snifPacket snifpacket.SnifPacket
+	err := json.Unmarshal(packet.Payload, &snifPacket)
+	return snifPacket, err
+}
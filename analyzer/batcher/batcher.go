@@ -14,4 +14,5 @@ type Batcher struct {
 	RDB  *redisutil.RedisClient
 	CFG  *core.AnalyzerConfig
 	SnowflakeNode *snowflake.Node
+	DNS  *DNSCache
 }
\ No newline at end of file
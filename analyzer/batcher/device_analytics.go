@@ -3,6 +3,10 @@ package batcher
 import (
 	"encoding/json"
 	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nrf24l01/sniffly/analyzer/geoip"
@@ -12,6 +16,17 @@ import (
 func buildDeviceTraffic(batch Batch, device_id uuid.UUID) (DeviceTraffic, error) {
 	var dt DeviceTraffic
 	for _, b := range batch.Packets {
+		// Flow records carry counters for both directions
+		if b.Flow != nil {
+			dt.Requests += b.Flow.UpPackets
+			dt.UpBytes += b.Flow.UpBytes
+			dt.DownBytes += b.Flow.DownBytes
+			continue
+		}
+		if b.IsDownload() {
+			dt.DownBytes += uint64(b.Size)
+			continue
+		}
 		dt.Requests += 1
 		dt.UpBytes += uint64(b.Size)
 	}
@@ -20,29 +35,39 @@ func buildDeviceTraffic(batch Batch, device_id uuid.UUID) (DeviceTraffic, error)
 	return dt, nil
 }
 
-func buildDeviceDomain(batch Batch, device_id uuid.UUID) (DeviceDomain, error) {
-	domains := make(map[string]uint64)
-	for _, b := range batch.Packets {
-		if b.Details.Type == snifpacket.SnifPacketTypeHTTP && b.Details.HTTP != nil {
-			if b.Details.HTTP.Host == "" {
-				continue
+func (b *Batcher) buildDeviceDomain(batch Batch, device_id uuid.UUID) (DeviceDomain, error) {
+	// Answers first, so connections in the same bucket find their name
+	if b.DNS != nil {
+		for _, p := range batch.Packets {
+			if p.Details.Type == snifpacket.SnifPacketTypeDNS {
+				b.DNS.Learn(device_id, p)
 			}
-			domains[b.Details.HTTP.Host] += 1
-		} else if b.Details.Type == snifpacket.SnifPacketTypeTLS && b.Details.TLS != nil {
-			if b.Details.TLS.Sni == "" {
-				continue
-			}
-			domains[b.Details.TLS.Sni] += 1
 		}
 	}
+
+	domains := make(map[string]uint64)
+	sources := make(map[string]uint64)
+	for _, p := range batch.Packets {
+		domain, source := b.DNS.packetDomain(device_id, p)
+		if domain == "" {
+			continue
+		}
+		domains[domain] += 1
+		sources[source+":"+domain] += 1
+	}
 	result, err := json.Marshal(domains)
 	if err != nil {
 		return DeviceDomain{}, err
 	}
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		return DeviceDomain{}, err
+	}
 
 	var dt DeviceDomain
 	dt.DeviceID = device_id
 	dt.Domain = result
+	dt.Sources = sourcesJSON
 	dt.Bucket = batch.From
 	dt.Requests = uint64(len(batch.Packets))
 	return dt, nil
@@ -55,9 +80,9 @@ func (b *Batcher) buildDeviceCountryAndCompany(batch Batch, device_id uuid.UUID)
 	companies := make(map[string]uint64)
 
 	for _, p := range batch.Packets {
-		country, company, err := geoip.CityCompanyFromIP(p.DstIP, b.RDB, b.CFG.AppConfig)
+		country, company, err := geoip.CityCompanyFromIP(p.RemoteIP(), b.RDB, b.CFG.AppConfig)
 		if err != nil {
-			log.Printf("Error looking up geoip info for IP %s: %v", p.DstIP, err)
+			log.Printf("Error looking up geoip info for IP %s: %v", p.RemoteIP(), err)
 			continue
 		}
 		if country != "" {
@@ -105,6 +130,303 @@ func buildDeviceProto(batch Batch, device_id uuid.UUID) (DeviceProto, error) {
 	return dt, nil
 }
 
+func buildDeviceFingerprint(batch Batch, device_id uuid.UUID) (DeviceFingerprint, error) {
+	ja3 := make(map[string]uint64)
+	ja4 := make(map[string]uint64)
+	var hellos uint64
+	for _, p := range batch.Packets {
+		var fp3, fp4 string
+		switch {
+		case p.Details.TLS != nil:
+			fp3, fp4 = p.Details.TLS.JA3, p.Details.TLS.JA4
+		case p.Details.QUIC != nil:
+			fp3, fp4 = p.Details.QUIC.JA3, p.Details.QUIC.JA4
+		}
+		if fp3 == "" && fp4 == "" {
+			continue
+		}
+		hellos += 1
+		if fp3 != "" {
+			ja3[fp3] += 1
+		}
+		if fp4 != "" {
+			ja4[fp4] += 1
+		}
+	}
+	ja3JSON, err := json.Marshal(ja3)
+	if err != nil {
+		return DeviceFingerprint{}, err
+	}
+	ja4JSON, err := json.Marshal(ja4)
+	if err != nil {
+		return DeviceFingerprint{}, err
+	}
+
+	var df DeviceFingerprint
+	df.DeviceID = device_id
+	df.JA3 = ja3JSON
+	df.JA4 = ja4JSON
+	df.Bucket = batch.From
+	df.Requests = hellos
+	return df, nil
+}
+
+func buildDeviceHTTP(batch Batch, device_id uuid.UUID) (DeviceHTTP, error) {
+	userAgents := make(map[string]uint64)
+	statusCodes := make(map[string]uint64)
+	contentTypes := make(map[string]uint64)
+	var dh DeviceHTTP
+	for _, p := range batch.Packets {
+		h := p.Details.HTTP
+		if h == nil {
+			continue
+		}
+		dh.Requests += 1
+		if h.UserAgent != "" {
+			userAgents[h.UserAgent] += 1
+		}
+		if h.IsResponse && h.StatusCode > 0 {
+			statusCodes[strconv.Itoa(h.StatusCode)] += 1
+		}
+		if h.ContentType != "" {
+			// Drop parameters such as charset
+			ct, _, _ := strings.Cut(h.ContentType, ";")
+			contentTypes[strings.ToLower(strings.TrimSpace(ct))] += 1
+		}
+		if h.BodySize > 0 {
+			dh.BodyBytes += uint64(h.BodySize)
+		}
+	}
+
+	var err error
+	if dh.UserAgents, err = json.Marshal(userAgents); err != nil {
+		return DeviceHTTP{}, err
+	}
+	if dh.StatusCodes, err = json.Marshal(statusCodes); err != nil {
+		return DeviceHTTP{}, err
+	}
+	if dh.ContentTypes, err = json.Marshal(contentTypes); err != nil {
+		return DeviceHTTP{}, err
+	}
+	dh.DeviceID = device_id
+	dh.Bucket = batch.From
+	return dh, nil
+}
+
+func (b *Batcher) buildDeviceIoT(batch Batch, device_id uuid.UUID) (DeviceIoT, error) {
+	topics := make(map[string]uint64)
+	clientIDs := make(map[string]uint64)
+	endpoints := make(map[string]uint64)
+	var di DeviceIoT
+	for _, p := range batch.Packets {
+		if m := p.Details.MQTT; m != nil {
+			di.Requests += 1
+			// The broker publishes to the device what it subscribed to
+			published := "publish:"
+			if p.Flow == nil && p.IsDownload() {
+				published = "receive:"
+			}
+			for _, t := range m.Published {
+				topics[published+t] += 1
+			}
+			for _, t := range m.Subscribed {
+				topics["subscribe:"+t] += 1
+			}
+			if m.ClientID != "" {
+				clientIDs[m.ClientID] += 1
+			}
+		}
+		if c := p.Details.CoAP; c != nil {
+			di.Requests += 1
+			// Responses carry no URI
+			if c.URIPath == "" {
+				continue
+			}
+			host := c.URIHost
+			if host == "" {
+				host, _ = b.DNS.packetDomain(device_id, p)
+			}
+			if host == "" {
+				host = p.RemoteIP()
+			}
+			endpoints[c.Method+" "+host+c.URIPath] += 1
+		}
+	}
+
+	var err error
+	if di.Topics, err = json.Marshal(topics); err != nil {
+		return DeviceIoT{}, err
+	}
+	if di.ClientIDs, err = json.Marshal(clientIDs); err != nil {
+		return DeviceIoT{}, err
+	}
+	if di.Endpoints, err = json.Marshal(endpoints); err != nil {
+		return DeviceIoT{}, err
+	}
+	di.DeviceID = device_id
+	di.Bucket = batch.From
+	return di, nil
+}
+
+// buildDeviceTLSEndpoints collects the ServerHellos the device received,
+// one entry per server address and port.
+func (b *Batcher) buildDeviceTLSEndpoints(batches []Batch, device_id uuid.UUID) []DeviceTLSEndpoint {
+	endpoints := make(map[string]*DeviceTLSEndpoint)
+	var order []string
+	for _, batch := range batches {
+		for _, p := range batch.Packets {
+			s := p.Details.TLSServer
+			if s == nil {
+				continue
+			}
+			// The ServerHello comes from the server, flow records are
+			// oriented from the device instead
+			serverIP, serverPort := p.SrcIP, p.SrcPort
+			if p.Flow != nil {
+				serverIP, serverPort = p.RemoteIP(), p.DstPort
+			}
+			key := serverIP + "|" + serverPort
+			e, ok := endpoints[key]
+			if !ok {
+				e = &DeviceTLSEndpoint{ServerIP: serverIP, ServerPort: serverPort}
+				e.DeviceID = device_id
+				e.Bucket = batch.From
+				endpoints[key] = e
+				order = append(order, key)
+			}
+			e.Requests += 1
+			e.Version, e.CipherSuite, e.Cipher, e.ALPN = s.Version, s.CipherSuite, s.Cipher, s.ALPN
+			if s.Certificate != nil {
+				e.Certificate = s.Certificate
+			}
+			if domain, _ := b.DNS.packetDomain(device_id, p); domain != "" {
+				e.Domain = domain
+			}
+			e.LastSeen = time.Unix(p.Timestamp, 0).UTC()
+		}
+	}
+
+	result := make([]DeviceTLSEndpoint, 0, len(order))
+	for _, key := range order {
+		result = append(result, *endpoints[key])
+	}
+	return result
+}
+
+// buildDeviceAttributes collects what the device announced about itself
+// over mDNS and SSDP, and the addresses it claimed in ARP and neighbour
+// discovery, one entry per distinct source, key and value.
+func buildDeviceAttributes(batches []Batch, device_id uuid.UUID) []DeviceAttribute {
+	attrs := make(map[string]*DeviceAttribute)
+	var order []string
+	for _, batch := range batches {
+		for _, p := range batch.Packets {
+			seen := time.Unix(p.Timestamp, 0).UTC()
+			add := func(source, key, value string) {
+				if value == "" {
+					return
+				}
+				k := source + "|" + key + "|" + value
+				a, ok := attrs[k]
+				if !ok {
+					a = &DeviceAttribute{Source: source, Key: key, Value: value}
+					a.DeviceID = device_id
+					a.Bucket = batch.From
+					attrs[k] = a
+					order = append(order, k)
+				}
+				a.Requests += 1
+				a.LastSeen = seen
+			}
+
+			if m := p.Details.MDNS; m != nil {
+				add("mdns", "hostname", m.Hostname)
+				for _, s := range m.Services {
+					add("mdns", "service", s)
+				}
+				for _, i := range m.Instances {
+					add("mdns", "instance", i)
+				}
+				add("mdns", "model", m.Model)
+				add("mdns", "manufacturer", m.Manufacturer)
+				add("mdns", "version", m.Version)
+			}
+			if s := p.Details.SSDP; s != nil {
+				add("ssdp", "server", s.Server)
+				add("ssdp", "user_agent", s.UserAgent)
+				add("ssdp", "location", s.Location)
+				// The USN starts with the device UUID, followed by the
+				// announced type
+				usn, _, _ := strings.Cut(s.USN, "::")
+				add("ssdp", "usn", usn)
+				if strings.HasPrefix(s.Target, "urn:") && strings.Contains(s.Target, ":device:") {
+					add("ssdp", "device_type", s.Target)
+				}
+			}
+			// Bindings announced for another MAC belong to another device
+			if mac, ip, source, ok := p.AddressBinding(); ok && mac == p.DeviceMAC() {
+				add(source, "ip", ip)
+			}
+		}
+	}
+
+	result := make([]DeviceAttribute, 0, len(order))
+	for _, k := range order {
+		result = append(result, *attrs[k])
+	}
+	return result
+}
+
+// buildDeviceInsecureProtocols collects the FTP sessions of the device, one
+// entry per server. Data connections count towards the session that
+// announced them.
+func buildDeviceInsecureProtocols(batches []Batch, device_id uuid.UUID) []DeviceInsecureProtocol {
+	uses := make(map[string]*DeviceInsecureProtocol)
+	var order []string
+	for _, batch := range batches {
+		for _, p := range batch.Packets {
+			f := p.Details.FTP
+			if f == nil {
+				continue
+			}
+			_, server, _ := strings.Cut(f.Session, ">")
+			serverIP, serverPort, err := net.SplitHostPort(server)
+			if err != nil {
+				continue
+			}
+			key := "ftp|" + server
+			u, ok := uses[key]
+			if !ok {
+				u = &DeviceInsecureProtocol{Protocol: "ftp", ServerIP: serverIP, ServerPort: serverPort}
+				u.DeviceID = device_id
+				u.Bucket = batch.From
+				uses[key] = u
+				order = append(order, key)
+			}
+			u.LastSeen = time.Unix(p.Timestamp, 0).UTC()
+			if f.Data {
+				if p.Flow != nil {
+					u.DataBytes += p.Flow.UpBytes + p.Flow.DownBytes
+				} else {
+					u.DataBytes += uint64(p.Size)
+				}
+				continue
+			}
+			u.Requests += 1
+			// Anonymous logins expose no account
+			if f.Command == "USER" && !strings.EqualFold(f.Args, "anonymous") && !strings.EqualFold(f.Args, "ftp") {
+				u.Credentials = true
+			}
+		}
+	}
+
+	result := make([]DeviceInsecureProtocol, 0, len(order))
+	for _, key := range order {
+		result = append(result, *uses[key])
+	}
+	return result
+}
+
 func (b *Batcher) getDevicePackets(batches []Batch, device_id uuid.UUID) (CHBatch, error) {
 	var result CHBatch
 
@@ -117,7 +439,7 @@ func (b *Batcher) getDevicePackets(batches []Batch, device_id uuid.UUID) (CHBatc
 		result.DeviceTraffics = append(result.DeviceTraffics, traffic)
 
 		// Build device domain
-		domain, err := buildDeviceDomain(batch, device_id)
+		domain, err := b.buildDeviceDomain(batch, device_id)
 		if err != nil {
 			return CHBatch{}, err
 		}
@@ -136,6 +458,40 @@ func (b *Batcher) getDevicePackets(batches []Batch, device_id uuid.UUID) (CHBatc
 			return CHBatch{}, err
 		}
 		result.DeviceProtos = append(result.DeviceProtos, proto)
+
+		// Build device TLS fingerprints, buckets without hellos are skipped
+		fingerprint, err := buildDeviceFingerprint(batch, device_id)
+		if err != nil {
+			return CHBatch{}, err
+		}
+		if fingerprint.Requests > 0 {
+			result.DeviceFingerprints = append(result.DeviceFingerprints, fingerprint)
+		}
+
+		// Build device HTTP metadata, buckets without HTTP are skipped
+		httpStat, err := buildDeviceHTTP(batch, device_id)
+		if err != nil {
+			return CHBatch{}, err
+		}
+		if httpStat.Requests > 0 {
+			result.DeviceHTTPs = append(result.DeviceHTTPs, httpStat)
+		}
+
+		// Build device MQTT and CoAP usage, buckets without them are skipped
+		iot, err := b.buildDeviceIoT(batch, device_id)
+		if err != nil {
+			return CHBatch{}, err
+		}
+		if iot.Requests > 0 {
+			result.DeviceIoTs = append(result.DeviceIoTs, iot)
+		}
 	}
+
+	// TLS endpoints are stored per server, not per bucket
+	result.DeviceTLSEndpoints = b.buildDeviceTLSEndpoints(batches, device_id)
+	// Discovered attributes are stored per value, not per bucket
+	result.DeviceAttributes = buildDeviceAttributes(batches, device_id)
+	// Cleartext protocol use is stored per server
+	result.DeviceInsecureProtocols = buildDeviceInsecureProtocols(batches, device_id)
 	return result, nil
 }
\ No newline at end of file
@@ -0,0 +1,141 @@
+package batcher
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nrf24l01/sniffly/capturer/snifpacket"
+)
+
+// Where the domain of a packet came from, from most to least certain
+const (
+	DomainSourceSNI  = "sni"
+	DomainSourceHost = "host"
+	DomainSourceDNS  = "dns"
+)
+
+type dnsCacheEntry struct {
+	name string
+	// Capture time of the answer and the end of its TTL, unix seconds
+	from    int64
+	expires int64
+}
+
+// DNSCache remembers which name each device resolved to a remote IP, so
+// connections without HTTP Host or TLS SNI can still be given a domain.
+// Entries live for the answer TTL plus a grace period, measured in capture
+// time. It is used from the processing loop only and is not locked.
+type DNSCache struct {
+	devices    map[uuid.UUID]map[string]dnsCacheEntry
+	maxEntries int
+	grace      int64
+}
+
+func NewDNSCache(maxEntries int, grace time.Duration) *DNSCache {
+	return &DNSCache{
+		devices:    make(map[uuid.UUID]map[string]dnsCacheEntry),
+		maxEntries: maxEntries,
+		grace:      int64(grace / time.Second),
+	}
+}
+
+// Learn stores the addresses of a DNS response received by the device
+// under the name it asked for. Responses sent by the device, e.g. from a
+// resolver on the router, teach it nothing.
+func (c *DNSCache) Learn(device_id uuid.UUID, p snifpacket.SnifPacket) {
+	d := p.Details.DNS
+	if d == nil || d.IsQuery || len(d.Answers) == 0 || !p.IsDownload() {
+		return
+	}
+
+	name := ""
+	if len(d.Questions) > 0 {
+		name = d.Questions[0].Name
+	} else if len(d.Queries) > 0 {
+		name = d.Queries[0]
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "" {
+		return
+	}
+
+	entries, ok := c.devices[device_id]
+	if !ok {
+		entries = make(map[string]dnsCacheEntry)
+		c.devices[device_id] = entries
+	}
+
+	add := func(ip string, ttl uint32) {
+		if ip == "" {
+			return
+		}
+		if _, exists := entries[ip]; !exists && c.maxEntries > 0 && len(entries) >= c.maxEntries {
+			c.evict(entries, p.Timestamp)
+		}
+		entries[ip] = dnsCacheEntry{name: name, from: p.Timestamp, expires: p.Timestamp + int64(ttl)}
+	}
+
+	for _, a := range d.Answers {
+		switch a.Type {
+		case "A", "AAAA":
+			add(a.Data, a.TTL)
+		case "HTTPS", "SVCB":
+			for _, ip := range a.IPHints {
+				add(ip, a.TTL)
+			}
+		}
+	}
+}
+
+// Lookup returns the name the device resolved ip to, if the answer was
+// valid at ts.
+func (c *DNSCache) Lookup(device_id uuid.UUID, ip string, ts int64) string {
+	e, ok := c.devices[device_id][ip]
+	if !ok || ts < e.from || ts > e.expires+c.grace {
+		return ""
+	}
+	return e.name
+}
+
+// evict drops expired entries, or the one closest to expiry if none are.
+func (c *DNSCache) evict(entries map[string]dnsCacheEntry, now int64) {
+	oldestIP := ""
+	var oldest int64
+	for ip, e := range entries {
+		if now > e.expires+c.grace {
+			delete(entries, ip)
+			continue
+		}
+		if oldestIP == "" || e.expires < oldest {
+			oldestIP, oldest = ip, e.expires
+		}
+	}
+	if len(entries) >= c.maxEntries && oldestIP != "" {
+		delete(entries, oldestIP)
+	}
+}
+
+// packetDomain returns the domain of a packet and where it came from. HTTP
+// Host and TLS or QUIC SNI win; other traffic is labelled from the device's
+// DNS answers.
+func (c *DNSCache) packetDomain(device_id uuid.UUID, p snifpacket.SnifPacket) (string, string) {
+	switch {
+	case p.Details.Type == snifpacket.SnifPacketTypeTLS && p.Details.TLS != nil && p.Details.TLS.Sni != "":
+		return p.Details.TLS.Sni, DomainSourceSNI
+	case p.Details.Type == snifpacket.SnifPacketTypeQUIC && p.Details.QUIC != nil && p.Details.QUIC.Sni != "":
+		return p.Details.QUIC.Sni, DomainSourceSNI
+	case p.Details.Type == snifpacket.SnifPacketTypeHTTP && p.Details.HTTP != nil && p.Details.HTTP.Host != "":
+		return p.Details.HTTP.Host, DomainSourceHost
+	case p.Details.Type == snifpacket.SnifPacketTypeDNS:
+		// Lookups themselves go to the resolver
+		return "", ""
+	}
+	if c == nil {
+		return "", ""
+	}
+	if name := c.Lookup(device_id, p.RemoteIP(), p.Timestamp); name != "" {
+		return name, DomainSourceDNS
+	}
+	return "", ""
+}
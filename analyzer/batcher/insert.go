@@ -2,21 +2,29 @@ package batcher
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 func (c *CHBatch) Insert(ctx context.Context, b *Batcher) error {
 	// Use typed insert helper (fixed table names inside) to avoid dynamic SQL identifiers
-	log.Printf("Inserting %d device traffics, %d device domains, %d device countries, %d device protos",
-		len(c.DeviceTraffics), len(c.DeviceDomains), len(c.DeviceCountries), len(c.DeviceProtos))
+	log.Printf("Inserting %d device traffics, %d device domains, %d device countries, %d device protos, %d device fingerprints, %d TLS endpoints, %d device HTTP stats, %d device IoT stats, %d device attributes, %d insecure protocol uses",
+		len(c.DeviceTraffics), len(c.DeviceDomains), len(c.DeviceCountries), len(c.DeviceProtos), len(c.DeviceFingerprints), len(c.DeviceTLSEndpoints), len(c.DeviceHTTPs), len(c.DeviceIoTs), len(c.DeviceAttributes), len(c.DeviceInsecureProtocols))
 	insertAnyStat(ctx, c.DeviceTraffics, b)
 	insertAnyStat(ctx, c.DeviceDomains, b)
 	insertAnyStat(ctx, c.DeviceCountries, b)
 	insertAnyStat(ctx, c.DeviceProtos, b)
+	insertAnyStat(ctx, c.DeviceFingerprints, b)
+	insertAnyStat(ctx, c.DeviceTLSEndpoints, b)
+	insertAnyStat(ctx, c.DeviceHTTPs, b)
+	insertAnyStat(ctx, c.DeviceIoTs, b)
+	insertAnyStat(ctx, c.DeviceAttributes, b)
+	insertAnyStat(ctx, c.DeviceInsecureProtocols, b)
 
 	return nil
 }
@@ -43,6 +51,12 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 	var domains []DeviceDomain
 	var countries []DeviceCountry
 	var protos []DeviceProto
+	var fingerprints []DeviceFingerprint
+	var endpoints []DeviceTLSEndpoint
+	var httpStats []DeviceHTTP
+	var iots []DeviceIoT
+	var attributes []DeviceAttribute
+	var insecure []DeviceInsecureProtocol
 
 	for _, rec := range records {
 		switch r := any(rec).(type) {
@@ -54,6 +68,18 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 			countries = append(countries, r)
 		case DeviceProto:
 			protos = append(protos, r)
+		case DeviceFingerprint:
+			fingerprints = append(fingerprints, r)
+		case DeviceTLSEndpoint:
+			endpoints = append(endpoints, r)
+		case DeviceHTTP:
+			httpStats = append(httpStats, r)
+		case DeviceIoT:
+			iots = append(iots, r)
+		case DeviceAttribute:
+			attributes = append(attributes, r)
+		case DeviceInsecureProtocol:
+			insecure = append(insecure, r)
 		default:
 			return fmt.Errorf("unsupported record type: %T", rec)
 		}
@@ -71,17 +97,18 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 
 	// Batch DeviceTraffic
 	if len(traffics) > 0 {
-		cols := "bucket,device_id,up_bytes,req_count"
+		cols := "bucket,device_id,up_bytes,down_bytes,req_count"
 		var vals []string
 		var args []interface{}
 		for i, r := range traffics {
-			base := i * 4
-			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4))
-			args = append(args, r.Bucket, r.DeviceID, r.UpBytes, r.Requests)
+			base := i * 5
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5))
+			args = append(args, r.Bucket, r.DeviceID, r.UpBytes, r.DownBytes, r.Requests)
 		}
 		q := fmt.Sprintf(`INSERT INTO devices_traffics_5s (%s) VALUES %s
 			ON CONFLICT (device_id, bucket) DO UPDATE
 			SET up_bytes = devices_traffics_5s.up_bytes + EXCLUDED.up_bytes,
+				down_bytes = devices_traffics_5s.down_bytes + EXCLUDED.down_bytes,
 				req_count = devices_traffics_5s.req_count + EXCLUDED.req_count`, cols, strings.Join(vals, ","))
 		if err := exec(q, args...); err != nil {
 			return err
@@ -90,13 +117,13 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 
 	// Batch DeviceDomain
 	if len(domains) > 0 {
-		cols := "bucket,device_id,domain,requests"
+		cols := "bucket,device_id,domain,sources,requests"
 		var vals []string
 		var args []interface{}
 		for i, r := range domains {
-			base := i * 4
-			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4))
-			args = append(args, r.Bucket, r.DeviceID, string(r.Domain), r.Requests)
+			base := i * 5
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5))
+			args = append(args, r.Bucket, r.DeviceID, string(r.Domain), string(r.Sources), r.Requests)
 		}
 			q := fmt.Sprintf(`INSERT INTO devices_domains_5s (%s) VALUES %s
 				ON CONFLICT (device_id, bucket) DO UPDATE
@@ -110,6 +137,16 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 						GROUP BY k
 					) y
 				),
+				sources = (
+					SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+						SELECT k, sum(v::bigint) AS sum_v FROM (
+							SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_domains_5s.sources, '{}'::jsonb))
+							UNION ALL
+							SELECT key, value FROM jsonb_each_text(EXCLUDED.sources)
+						) x
+						GROUP BY k
+					) y
+				),
 				requests = devices_domains_5s.requests + EXCLUDED.requests`, cols, strings.Join(vals, ","))
 		if err := exec(q, args...); err != nil {
 			return err
@@ -183,6 +220,239 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 		}
 	}
 
+	// Batch DeviceFingerprint
+	if len(fingerprints) > 0 {
+		cols := "bucket,device_id,ja3,ja4,requests"
+		var vals []string
+		var args []interface{}
+		for i, r := range fingerprints {
+			base := i * 5
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5))
+			args = append(args, r.Bucket, r.DeviceID, string(r.JA3), string(r.JA4), r.Requests)
+		}
+		q := fmt.Sprintf(`INSERT INTO devices_fingerprints_5s (%s) VALUES %s
+			ON CONFLICT (device_id, bucket) DO UPDATE
+			SET ja3 = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_fingerprints_5s.ja3, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.ja3)
+					) x
+					GROUP BY k
+				) y
+			),
+			ja4 = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_fingerprints_5s.ja4, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.ja4)
+					) x
+					GROUP BY k
+				) y
+			),
+			requests = devices_fingerprints_5s.requests + EXCLUDED.requests`, cols, strings.Join(vals, ","))
+		if err := exec(q, args...); err != nil {
+			return err
+		}
+	}
+
+	// Batch DeviceTLSEndpoint, certificate columns are only replaced when
+	// the new handshake showed a certificate
+	if len(endpoints) > 0 {
+		cols := "device_id,server_ip,server_port,domain,version,cipher_suite,cipher,alpn,cert_subject,cert_issuer,cert_sans,cert_not_before,cert_not_after,cert_self_signed,cert_signature_algorithm,first_seen,last_seen,handshakes"
+		var vals []string
+		var args []interface{}
+		for i, r := range endpoints {
+			base := i * 18
+			ph := make([]string, 18)
+			for j := range ph {
+				ph[j] = fmt.Sprintf("$%d", base+j+1)
+			}
+			vals = append(vals, "("+strings.Join(ph, ",")+")")
+
+			var subject, issuer, sigAlg string
+			var notBefore, notAfter *time.Time
+			sans := []byte("[]")
+			selfSigned := false
+			if c := r.Certificate; c != nil {
+				subject, issuer, sigAlg, selfSigned = c.Subject, c.Issuer, c.SignatureAlgorithm, c.SelfSigned
+				nb, na := time.Unix(c.NotBefore, 0).UTC(), time.Unix(c.NotAfter, 0).UTC()
+				notBefore, notAfter = &nb, &na
+				if c.SANs != nil {
+					if raw, err := json.Marshal(c.SANs); err == nil {
+						sans = raw
+					}
+				}
+			}
+			args = append(args, r.DeviceID, r.ServerIP, r.ServerPort, r.Domain, r.Version, r.CipherSuite, r.Cipher, r.ALPN,
+				subject, issuer, string(sans), notBefore, notAfter, selfSigned, sigAlg, r.Bucket, r.LastSeen, r.Requests)
+		}
+		q := fmt.Sprintf(`INSERT INTO tls_endpoints (%s) VALUES %s
+			ON CONFLICT (device_id, server_ip, server_port) DO UPDATE
+			SET domain = CASE WHEN EXCLUDED.domain = '' THEN tls_endpoints.domain ELSE EXCLUDED.domain END,
+				version = EXCLUDED.version,
+				cipher_suite = EXCLUDED.cipher_suite,
+				cipher = EXCLUDED.cipher,
+				alpn = EXCLUDED.alpn,
+				cert_subject = CASE WHEN EXCLUDED.cert_not_after IS NULL THEN tls_endpoints.cert_subject ELSE EXCLUDED.cert_subject END,
+				cert_issuer = CASE WHEN EXCLUDED.cert_not_after IS NULL THEN tls_endpoints.cert_issuer ELSE EXCLUDED.cert_issuer END,
+				cert_sans = CASE WHEN EXCLUDED.cert_not_after IS NULL THEN tls_endpoints.cert_sans ELSE EXCLUDED.cert_sans END,
+				cert_not_before = COALESCE(EXCLUDED.cert_not_before, tls_endpoints.cert_not_before),
+				cert_not_after = COALESCE(EXCLUDED.cert_not_after, tls_endpoints.cert_not_after),
+				cert_self_signed = CASE WHEN EXCLUDED.cert_not_after IS NULL THEN tls_endpoints.cert_self_signed ELSE EXCLUDED.cert_self_signed END,
+				cert_signature_algorithm = CASE WHEN EXCLUDED.cert_not_after IS NULL THEN tls_endpoints.cert_signature_algorithm ELSE EXCLUDED.cert_signature_algorithm END,
+				first_seen = LEAST(tls_endpoints.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(tls_endpoints.last_seen, EXCLUDED.last_seen),
+				handshakes = tls_endpoints.handshakes + EXCLUDED.handshakes,
+				updated_at = now()`, cols, strings.Join(vals, ","))
+		if err := exec(q, args...); err != nil {
+			return err
+		}
+	}
+
+	// Batch DeviceHTTP
+	if len(httpStats) > 0 {
+		cols := "bucket,device_id,user_agents,status_codes,content_types,body_bytes,requests"
+		var vals []string
+		var args []interface{}
+		for i, r := range httpStats {
+			base := i * 7
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5, base+6, base+7))
+			args = append(args, r.Bucket, r.DeviceID, string(r.UserAgents), string(r.StatusCodes), string(r.ContentTypes), r.BodyBytes, r.Requests)
+		}
+		q := fmt.Sprintf(`INSERT INTO devices_http_5s (%s) VALUES %s
+			ON CONFLICT (device_id, bucket) DO UPDATE
+			SET user_agents = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_http_5s.user_agents, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.user_agents)
+					) x
+					GROUP BY k
+				) y
+			),
+			status_codes = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_http_5s.status_codes, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.status_codes)
+					) x
+					GROUP BY k
+				) y
+			),
+			content_types = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_http_5s.content_types, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.content_types)
+					) x
+					GROUP BY k
+				) y
+			),
+			body_bytes = devices_http_5s.body_bytes + EXCLUDED.body_bytes,
+			requests = devices_http_5s.requests + EXCLUDED.requests`, cols, strings.Join(vals, ","))
+		if err := exec(q, args...); err != nil {
+			return err
+		}
+	}
+
+	// Batch DeviceIoT
+	if len(iots) > 0 {
+		cols := "bucket,device_id,topics,client_ids,endpoints,requests"
+		var vals []string
+		var args []interface{}
+		for i, r := range iots {
+			base := i * 6
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5, base+6))
+			args = append(args, r.Bucket, r.DeviceID, string(r.Topics), string(r.ClientIDs), string(r.Endpoints), r.Requests)
+		}
+		q := fmt.Sprintf(`INSERT INTO devices_iot_5s (%s) VALUES %s
+			ON CONFLICT (device_id, bucket) DO UPDATE
+			SET topics = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_iot_5s.topics, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.topics)
+					) x
+					GROUP BY k
+				) y
+			),
+			client_ids = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_iot_5s.client_ids, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.client_ids)
+					) x
+					GROUP BY k
+				) y
+			),
+			endpoints = (
+				SELECT jsonb_object_agg(k, to_jsonb(sum_v)) FROM (
+					SELECT k, sum(v::bigint) AS sum_v FROM (
+						SELECT key AS k, value AS v FROM jsonb_each_text(coalesce(devices_iot_5s.endpoints, '{}'::jsonb))
+						UNION ALL
+						SELECT key, value FROM jsonb_each_text(EXCLUDED.endpoints)
+					) x
+					GROUP BY k
+				) y
+			),
+			requests = devices_iot_5s.requests + EXCLUDED.requests`, cols, strings.Join(vals, ","))
+		if err := exec(q, args...); err != nil {
+			return err
+		}
+	}
+
+	// Batch DeviceAttribute
+	if len(attributes) > 0 {
+		cols := "device_id,source,key,value,first_seen,last_seen,seen"
+		var vals []string
+		var args []interface{}
+		for i, r := range attributes {
+			base := i * 7
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5, base+6, base+7))
+			args = append(args, r.DeviceID, r.Source, r.Key, r.Value, r.Bucket, r.LastSeen, r.Requests)
+		}
+		q := fmt.Sprintf(`INSERT INTO device_attributes (%s) VALUES %s
+			ON CONFLICT (device_id, source, key, value) DO UPDATE
+			SET first_seen = LEAST(device_attributes.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(device_attributes.last_seen, EXCLUDED.last_seen),
+				seen = device_attributes.seen + EXCLUDED.seen,
+				updated_at = now()`, cols, strings.Join(vals, ","))
+		if err := exec(q, args...); err != nil {
+			return err
+		}
+	}
+
+	// Batch DeviceInsecureProtocol
+	if len(insecure) > 0 {
+		cols := "device_id,protocol,server_ip,server_port,credentials,data_bytes,messages,first_seen,last_seen"
+		var vals []string
+		var args []interface{}
+		for i, r := range insecure {
+			base := i * 9
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9))
+			args = append(args, r.DeviceID, r.Protocol, r.ServerIP, r.ServerPort, r.Credentials, r.DataBytes, r.Requests, r.Bucket, r.LastSeen)
+		}
+		q := fmt.Sprintf(`INSERT INTO insecure_protocol_uses (%s) VALUES %s
+			ON CONFLICT (device_id, protocol, server_ip, server_port) DO UPDATE
+			SET credentials = insecure_protocol_uses.credentials OR EXCLUDED.credentials,
+				data_bytes = insecure_protocol_uses.data_bytes + EXCLUDED.data_bytes,
+				messages = insecure_protocol_uses.messages + EXCLUDED.messages,
+				first_seen = LEAST(insecure_protocol_uses.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(insecure_protocol_uses.last_seen, EXCLUDED.last_seen),
+				updated_at = now()`, cols, strings.Join(vals, ","))
+		if err := exec(q, args...); err != nil {
+			return err
+		}
+	}
+
 	// Update day cache versions: increment by 1 for each distinct day we modified.
 	// Collect unique days from all record types (bucket -> date string YYYY-MM-DD).
 	uniqueDays := make(map[string]struct{})
@@ -198,6 +468,15 @@ func insertStatPerDevice[T DeviceStatLike](ctx context.Context, records []T, b *
 	for _, r := range protos {
 		uniqueDays[r.Bucket.UTC().Format("2006-01-02")] = struct{}{}
 	}
+	for _, r := range fingerprints {
+		uniqueDays[r.Bucket.UTC().Format("2006-01-02")] = struct{}{}
+	}
+	for _, r := range httpStats {
+		uniqueDays[r.Bucket.UTC().Format("2006-01-02")] = struct{}{}
+	}
+	for _, r := range iots {
+		uniqueDays[r.Bucket.UTC().Format("2006-01-02")] = struct{}{}
+	}
 
 	if len(uniqueDays) > 0 {
 		var vals []string
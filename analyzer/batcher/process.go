@@ -2,52 +2,70 @@ package batcher
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nrf24l01/sniffly/capturer/snifpacket"
 )
 
+// deviceKey identifies the local device of a packet: by MAC, or by IP on
+// links without one (PPP, WireGuard, tun)
+type deviceKey struct {
+	mac string
+	ip  string
+}
+
+func packetDeviceKey(packet snifpacket.SnifPacket) deviceKey {
+	if mac := packet.DeviceMAC(); mac != "" {
+		return deviceKey{mac: mac}
+	}
+	return deviceKey{ip: packet.DeviceIP()}
+}
+
 func (b *Batcher) Process(ctx context.Context, batch Batch) error {
-	// Grouping packets by device MAC
-	per_device_mac := make(map[string][]snifpacket.SnifPacket)
+	// Grouping packets by device MAC (source for uploads, destination for downloads),
+	// or by IP on links without MACs
+	per_device_mac := make(map[deviceKey][]snifpacket.SnifPacket)
 	for _, packet := range batch.Packets {
-		per_device_mac[packet.SrcMAC] = append(per_device_mac[packet.SrcMAC], packet)
+		key := packetDeviceKey(packet)
+		per_device_mac[key] = append(per_device_mac[key], packet)
 	}
 
 	// Retrieving or creating device IDs
-	per_device_mac_device_id := make(map[string]uuid.UUID)
-	for device_id, _ := range per_device_mac {
-		rows, err := b.PGDB.Raw("SELECT id FROM device_info WHERE mac = ?", device_id).Rows()
+	per_device_mac_device_id := make(map[deviceKey]uuid.UUID)
+	for device_key, packets := range per_device_mac {
+		iface := lastInterface(packets)
+		found_device_id, err := b.deviceID(device_key, iface, packets[0].DeviceIP())
 		if err != nil {
 			return err
 		}
-		var found_device_id uuid.UUID
-		if rows.Next() {
-			if err = rows.Scan(&found_device_id); err != nil {
-				rows.Close()
+		per_device_mac_device_id[device_key] = found_device_id
+		if device_key.mac == "" {
+			// DHCP and ARP describe devices by MAC
+			continue
+		}
+
+		if dhcp := lastDHCP(packets); dhcp != nil {
+			if err := b.updateDeviceDHCP(found_device_id, dhcp); err != nil {
 				return err
 			}
-			per_device_mac_device_id[device_id] = found_device_id
-			rows.Close()
-		} else {
-			rows.Close()
-			// insert and return generated id in a single query
-			if err := b.PGDB.Raw(
-				"INSERT INTO device_info (mac, ip) VALUES (?, ?) RETURNING id",
-				device_id, per_device_mac[device_id][0].SrcIP,
-			).Row().Scan(&found_device_id); err != nil {
+		}
+		// ARP shows the address actually in use, after any DHCP request
+		if ip := lastARPAddress(device_key.mac, packets); ip != "" {
+			if err := b.PGDB.Exec("UPDATE device_info SET ip = ? WHERE id = ?", ip, found_device_id).Error; err != nil {
 				return err
 			}
-			per_device_mac_device_id[device_id] = found_device_id
 		}
 	}
 
 	// Grouping packets by device ID
 	per_device_id := make(map[uuid.UUID][]snifpacket.SnifPacket)
-	for mac, packets := range per_device_mac {
-		device_id := per_device_mac_device_id[mac]
-		per_device_id[device_id] = packets
+	for device_key, packets := range per_device_mac {
+		// A device seen by MAC and by IP only (e.g. LAN and WireGuard)
+		// resolves to the same ID under both keys
+		device_id := per_device_mac_device_id[device_key]
+		per_device_id[device_id] = append(per_device_id[device_id], packets...)
 	}
 
 	var bigBatch CHBatch
@@ -62,6 +80,12 @@ func (b *Batcher) Process(ctx context.Context, batch Batch) error {
 		bigBatch.DeviceDomains = append(bigBatch.DeviceDomains, chBatch.DeviceDomains...)
 		bigBatch.DeviceCountries = append(bigBatch.DeviceCountries, chBatch.DeviceCountries...)
 		bigBatch.DeviceProtos = append(bigBatch.DeviceProtos, chBatch.DeviceProtos...)
+		bigBatch.DeviceFingerprints = append(bigBatch.DeviceFingerprints, chBatch.DeviceFingerprints...)
+		bigBatch.DeviceTLSEndpoints = append(bigBatch.DeviceTLSEndpoints, chBatch.DeviceTLSEndpoints...)
+		bigBatch.DeviceHTTPs = append(bigBatch.DeviceHTTPs, chBatch.DeviceHTTPs...)
+		bigBatch.DeviceIoTs = append(bigBatch.DeviceIoTs, chBatch.DeviceIoTs...)
+		bigBatch.DeviceAttributes = append(bigBatch.DeviceAttributes, chBatch.DeviceAttributes...)
+		bigBatch.DeviceInsecureProtocols = append(bigBatch.DeviceInsecureProtocols, chBatch.DeviceInsecureProtocols...)
 	}
 
 	return bigBatch.Insert(ctx, b)
@@ -119,4 +143,112 @@ func (b *Batcher) processDevicBigBatch(ctx context.Context, device_id uuid.UUID,
 	}
 
 	return chBatch, nil
-}
\ No newline at end of file
+}
+// deviceID returns the ID of a device, creating it when it is new. Devices
+// without a MAC are looked up by IP, preferring one whose MAC is known.
+func (b *Batcher) deviceID(key deviceKey, iface, ip string) (uuid.UUID, error) {
+	var rows *sql.Rows
+	var err error
+	if key.mac != "" {
+		rows, err = b.PGDB.Raw("SELECT id, interface FROM device_info WHERE mac = ?", key.mac).Rows()
+	} else {
+		rows, err = b.PGDB.Raw("SELECT id, interface FROM device_info WHERE ip = ? ORDER BY mac IS NULL LIMIT 1", key.ip).Rows()
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var found_device_id uuid.UUID
+	var found_iface string
+	if rows.Next() {
+		if err = rows.Scan(&found_device_id, &found_iface); err != nil {
+			rows.Close()
+			return uuid.Nil, err
+		}
+		rows.Close()
+		// Devices can move between segments (e.g. LAN to guest Wi-Fi)
+		if key.mac != "" && iface != "" && iface != found_iface {
+			if err := b.PGDB.Exec("UPDATE device_info SET interface = ? WHERE id = ?", iface, found_device_id).Error; err != nil {
+				return uuid.Nil, err
+			}
+		}
+		return found_device_id, nil
+	}
+	rows.Close()
+
+	// The MAC stays NULL for devices known by IP only
+	var mac interface{}
+	if key.mac != "" {
+		mac = key.mac
+	}
+	// insert and return generated id in a single query
+	if err := b.PGDB.Raw(
+		"INSERT INTO device_info (mac, ip, interface) VALUES (?, ?, ?) RETURNING id",
+		mac, ip, iface,
+	).Row().Scan(&found_device_id); err != nil {
+		return uuid.Nil, err
+	}
+	return found_device_id, nil
+}
+
+// lastInterface returns the capture interface of the newest packet that has one.
+func lastInterface(packets []snifpacket.SnifPacket) string {
+	iface := ""
+	var ts int64
+	for _, packet := range packets {
+		if packet.Interface != "" && packet.Timestamp >= ts {
+			iface, ts = packet.Interface, packet.Timestamp
+		}
+	}
+	return iface
+}
+
+// lastDHCP returns the newest DHCP client message among packets.
+func lastDHCP(packets []snifpacket.SnifPacket) *snifpacket.SnifPacketDetailsDHCP {
+	var dhcp *snifpacket.SnifPacketDetailsDHCP
+	var ts int64
+	for _, packet := range packets {
+		if packet.Details.DHCP != nil && packet.Timestamp >= ts {
+			dhcp, ts = packet.Details.DHCP, packet.Timestamp
+		}
+	}
+	return dhcp
+}
+
+// lastARPAddress returns the newest IPv4 address mac claimed in an ARP
+// message among packets.
+func lastARPAddress(mac string, packets []snifpacket.SnifPacket) string {
+	ip := ""
+	var ts int64
+	for _, packet := range packets {
+		binding, addr, source, ok := packet.AddressBinding()
+		if ok && source == "arp" && binding == mac && packet.Timestamp >= ts {
+			ip, ts = addr, packet.Timestamp
+		}
+	}
+	return ip
+}
+
+// updateDeviceDHCP stores what a DHCP client said about itself, keeping
+// known values for options the message left out.
+func (b *Batcher) updateDeviceDHCP(device_id uuid.UUID, dhcp *snifpacket.SnifPacketDetailsDHCP) error {
+	updates := make(map[string]interface{})
+	if dhcp.Hostname != "" {
+		updates["hostname"] = dhcp.Hostname
+	}
+	if dhcp.VendorClass != "" {
+		updates["vendor_class"] = dhcp.VendorClass
+	}
+	if dhcp.ParamRequestList != "" {
+		updates["dhcp_fingerprint"] = dhcp.ParamRequestList
+	}
+	// The address being asked for, or the one already in use on renewals
+	if dhcp.RequestedIP != "" {
+		updates["ip"] = dhcp.RequestedIP
+	} else if dhcp.ClientIP != "" {
+		updates["ip"] = dhcp.ClientIP
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return b.PGDB.Table("device_info").Where("id = ?", device_id).Updates(updates).Error
+}
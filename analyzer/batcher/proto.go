@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nrf24l01/sniffly/capturer/snifpacket"
 )
 
 type BaseDeviceStat struct {
@@ -15,11 +16,14 @@ type BaseDeviceStat struct {
 type DeviceTraffic struct {
 	BaseDeviceStat
 	UpBytes          uint64
+	DownBytes        uint64
 }
 
 type DeviceDomain struct {
 	BaseDeviceStat
 	Domain           []byte
+	// Sources counts "source:domain" pairs, e.g. "dns:broker.example.com"
+	Sources          []byte
 }
 
 type DeviceCountry struct {
@@ -33,6 +37,74 @@ type DeviceProto struct {
 	Proto            []byte
 }
 
+// DeviceFingerprint counts the TLS client fingerprints a device sent
+type DeviceFingerprint struct {
+	BaseDeviceStat
+	JA3              []byte
+	JA4              []byte
+}
+
+// DeviceHTTP counts HTTP metadata of a device: user agents of its requests,
+// status codes and content types of both directions.
+type DeviceHTTP struct {
+	BaseDeviceStat
+	UserAgents       []byte
+	StatusCodes      []byte
+	ContentTypes     []byte
+	BodyBytes        uint64
+}
+
+// DeviceIoT counts the MQTT topics ("publish:", "subscribe:" or "receive:"
+// followed by the topic), MQTT client IDs and CoAP endpoints ("POST
+// host/path") of a device.
+type DeviceIoT struct {
+	BaseDeviceStat
+	Topics           []byte
+	ClientIDs        []byte
+	Endpoints        []byte
+}
+
+// DeviceTLSEndpoint is what a server the device talks TLS to chose in its
+// ServerHello. Bucket is when it was first seen in the batch, Requests
+// counts the handshakes.
+type DeviceTLSEndpoint struct {
+	BaseDeviceStat
+	ServerIP         string
+	ServerPort       string
+	Domain           string
+	Version          string
+	CipherSuite      uint16
+	Cipher           string
+	ALPN             string
+	Certificate      *snifpacket.SnifPacketTLSCertificate
+	LastSeen         time.Time
+}
+
+// DeviceInsecureProtocol is the use of a cleartext protocol with one server.
+// Bucket is when it was first seen in the batch, Requests counts the
+// control messages.
+type DeviceInsecureProtocol struct {
+	BaseDeviceStat
+	Protocol         string
+	ServerIP         string
+	ServerPort       string
+	// Credentials is set once a login was seen in clear text
+	Credentials      bool
+	DataBytes        uint64
+	LastSeen         time.Time
+}
+
+// DeviceAttribute is one thing a device announced about itself over mDNS or
+// SSDP. Bucket is when it was first seen in the batch, Requests counts the
+// announcements.
+type DeviceAttribute struct {
+	BaseDeviceStat
+	Source           string
+	Key              string
+	Value            string
+	LastSeen         time.Time
+}
+
 type DeviceStatLike interface {
 	GetBucket() time.Time
 	GetDeviceID() uuid.UUID
@@ -62,4 +134,10 @@ type CHBatch struct {
 	DeviceDomains    []DeviceDomain
 	DeviceCountries  []DeviceCountry
 	DeviceProtos     []DeviceProto
+	DeviceFingerprints []DeviceFingerprint
+	DeviceTLSEndpoints []DeviceTLSEndpoint
+	DeviceHTTPs      []DeviceHTTP
+	DeviceIoTs       []DeviceIoT
+	DeviceAttributes []DeviceAttribute
+	DeviceInsecureProtocols []DeviceInsecureProtocol
 }
\ No newline at end of file
@@ -26,7 +26,7 @@ func (b *Batcher) RecordBatch(duration time.Duration) (Batch, error) {
 	for {
 		select {
 		case msg := <-msgs:
-			if err := batch.AddMessage(msg.Body); err != nil {
+			if err := batch.AddMessage(msg.Body, msg.ContentType); err != nil {
 				log.Printf("Error getting message: %v", err)
 				if nackErr := msg.Nack(false, true); nackErr != nil {
 					log.Printf("failed to Nack message: %v", nackErr)
@@ -67,7 +67,7 @@ func (b *Batcher) LoadAllRecords() (Batch, error) {
 			if !ok {
 				return batch, nil
 			}
-			if err := batch.AddMessage(msg.Body); err != nil {
+			if err := batch.AddMessage(msg.Body, msg.ContentType); err != nil {
 				log.Printf("Error getting message: %v", err)
 				if nackErr := msg.Nack(false, true); nackErr != nil {
 					log.Printf("failed to Nack message: %v", nackErr)
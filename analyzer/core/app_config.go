@@ -10,6 +10,10 @@ type AppConfig struct {
 	CapturePacketsTopic  string `env:"CAPTURE_PACKETS_TOPIC" envDefault:"sniffed"`
 	GeoIPCacheTTL        int    `env:"GEOIP_CACHE_TTL" envDefault:"86400"`
 	GeoIPCacheKeyPrefix  string `env:"GEOIP_CACHE_KEY_PREFIX" envDefault:"geoip-cache:"`
+	// DNS answers per device used to label traffic without Host or SNI.
+	// Entries stay valid DNSCacheGrace seconds past their TTL.
+	DNSCacheMaxEntries   int    `env:"DNS_CACHE_MAX_ENTRIES" envDefault:"4096"`
+	DNSCacheGrace        int    `env:"DNS_CACHE_GRACE" envDefault:"600"`
 }
 
 func LoadAppConfigFromEnv() *AppConfig {
@@ -5,19 +5,19 @@ go 1.25.4
 require (
 	github.com/bwmarrin/snowflake v0.3.0
 	github.com/caarlos0/env/v11 v11.3.1
+	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
-	github.com/lib/pq v1.10.9
 	github.com/nrf24l01/go-web-utils v1.6.2
 	github.com/nrf24l01/sniffly/capture_receiver v0.0.0-20251116194204-969e62f55109
 	github.com/nrf24l01/sniffly/capturer v0.0.0-20251118083453-c083ff4c589a
 	github.com/redis/go-redis/v9 v9.16.0
+	google.golang.org/protobuf v1.36.10
 	gorm.io/gorm v1.31.1
 )
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/gopacket/gopacket v1.4.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
@@ -34,6 +34,9 @@ require (
 	golang.org/x/text v0.29.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
 	google.golang.org/grpc v1.76.0 // indirect
-	google.golang.org/protobuf v1.36.10 // indirect
 	gorm.io/driver/postgres v1.6.0 // indirect
 )
+
+replace github.com/nrf24l01/sniffly/capture_receiver => ../capture_receiver
+
+replace github.com/nrf24l01/sniffly/capturer => ../capturer
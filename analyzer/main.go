@@ -40,6 +40,8 @@ func main() {
 	pg_db, err := pg_kit.RegisterPostgres(cfg.PGConfig,
 		&postgres.DeviceInfo{},
 		&postgres.DeviceCountry5s{}, &postgres.DeviceDomain5s{}, &postgres.DeviceProto5s{}, &postgres.DeviceTraffic5s{},
+		&postgres.DeviceFingerprint5s{}, &postgres.TLSEndpoint{}, &postgres.DeviceHTTP5s{}, &postgres.DeviceIoT5s{},
+		&postgres.DeviceAttribute{}, &postgres.InsecureProtocolUse{},
 		&postgres.DayCacheVersion{},
 	)
 	if err != nil {
@@ -78,6 +80,7 @@ func main() {
 		CFG:  cfg,
 		SnowflakeNode: node,
 		RDB: rdb,
+		DNS: batcher.NewDNSCache(cfg.AppConfig.DNSCacheMaxEntries, time.Duration(cfg.AppConfig.DNSCacheGrace)*time.Second),
 	}
 
 	for {
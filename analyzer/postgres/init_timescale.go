@@ -11,6 +11,9 @@ func InitTimescaleDB(db *gorm.DB) error {
         SELECT create_hypertable('devices_domains_5s', 'bucket', if_not_exists => TRUE);
         SELECT create_hypertable('devices_countries_5s', 'bucket', if_not_exists => TRUE);
         SELECT create_hypertable('devices_protos_5s', 'bucket', if_not_exists => TRUE);
+        SELECT create_hypertable('devices_fingerprints_5s', 'bucket', if_not_exists => TRUE);
+        SELECT create_hypertable('devices_http_5s', 'bucket', if_not_exists => TRUE);
+        SELECT create_hypertable('devices_iot_5s', 'bucket', if_not_exists => TRUE);
 
         -- Ensure unique indexes/constraints that match ON CONFLICT targets exist.
         -- ON CONFLICT (device_id, bucket) is used for traffics and countries.
@@ -20,6 +23,9 @@ func InitTimescaleDB(db *gorm.DB) error {
         -- For domains and protos we need uniqueness including domain/proto column as used in ON CONFLICT.
         CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_domains_bucket_device_domain ON devices_domains_5s (device_id, bucket);
         CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_protos_bucket_device_proto ON devices_protos_5s (device_id, bucket);
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_fingerprints_bucket_device ON devices_fingerprints_5s (device_id, bucket);
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_http_bucket_device ON devices_http_5s (device_id, bucket);
+        CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_iot_bucket_device ON devices_iot_5s (device_id, bucket);
     `)
 	return tx.Error
 }
\ No newline at end of file
@@ -10,10 +10,16 @@ import (
 type DeviceInfo struct {
 	pg_kit.BaseModel
 
+	// NULL for devices seen only on links without MACs, known by IP
 	MAC       string    `gorm:"unique;size:17"`
     IP        string    `gorm:"default:''"`
     Label     string    `gorm:"default:'interface'"`
     Hostname  string    `gorm:"default:''"`
+    Interface string    `gorm:"default:''"`
+    // From DHCP client messages; the parameter request list tells
+    // operating systems apart
+    VendorClass     string `gorm:"default:''"`
+    DHCPFingerprint string `gorm:"default:''"`
 }
 
 func (DeviceInfo) TableName() string {
@@ -26,6 +32,7 @@ type DeviceTraffic5s struct {
 	Bucket   time.Time `gorm:"not null;primaryKey;uniqueIndex:idx_bucket_device"`
     DeviceID uuid.UUID `gorm:"type:uuid;primaryKey;not null;uniqueIndex:idx_bucket_device"`
     UpBytes  uint64    `gorm:"default:0"`
+    DownBytes uint64   `gorm:"default:0"`
     ReqCount uint64    `gorm:"default:0"`
 
     Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
@@ -41,6 +48,8 @@ type DeviceDomain5s struct {
 	Bucket   time.Time `gorm:"not null;primaryKey;uniqueIndex:idx_bucket_device"`
 	DeviceID uuid.UUID `gorm:"type:uuid;primaryKey;not null;uniqueIndex:idx_bucket_device"`
 	Domain   string    `gorm:"type:jsonb;default:'{}'"`
+	// Sources counts "source:domain" keys, source is sni, host or dns
+	Sources  string    `gorm:"type:jsonb;default:'{}'"`
 	Requests uint64    `gorm:"default:0"`
 
 	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
@@ -79,4 +88,137 @@ type DeviceProto5s struct {
 
 func (DeviceProto5s) TableName() string {
 	return "devices_protos_5s"
-}
\ No newline at end of file
+}
+
+type DeviceFingerprint5s struct {
+	pg_kit.BaseModel
+
+	Bucket   time.Time `gorm:"not null;primaryKey;uniqueIndex:idx_bucket_device"`
+	DeviceID uuid.UUID `gorm:"type:uuid;primaryKey;not null;uniqueIndex:idx_bucket_device"`
+	// JA3 and JA4 count the TLS and QUIC ClientHellos sent by the device
+	JA3      string    `gorm:"column:ja3;type:jsonb;default:'{}'"`
+	JA4      string    `gorm:"column:ja4;type:jsonb;default:'{}'"`
+	Requests uint64    `gorm:"default:0"`
+
+	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (DeviceFingerprint5s) TableName() string {
+	return "devices_fingerprints_5s"
+}
+
+type DeviceHTTP5s struct {
+	pg_kit.BaseModel
+
+	Bucket       time.Time `gorm:"not null;primaryKey;uniqueIndex:idx_bucket_device"`
+	DeviceID     uuid.UUID `gorm:"type:uuid;primaryKey;not null;uniqueIndex:idx_bucket_device"`
+	UserAgents   string    `gorm:"type:jsonb;default:'{}'"`
+	StatusCodes  string    `gorm:"type:jsonb;default:'{}'"`
+	ContentTypes string    `gorm:"type:jsonb;default:'{}'"`
+	// BodyBytes is only filled by capturers with HTTP_BODY_SIZES=true
+	BodyBytes    uint64    `gorm:"default:0"`
+	Requests     uint64    `gorm:"default:0"`
+
+	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (DeviceHTTP5s) TableName() string {
+	return "devices_http_5s"
+}
+
+// DeviceIoT5s counts MQTT topics and client IDs and CoAP endpoints per
+// device and bucket.
+type DeviceIoT5s struct {
+	pg_kit.BaseModel
+
+	Bucket    time.Time `gorm:"not null;primaryKey;uniqueIndex:idx_bucket_device"`
+	DeviceID  uuid.UUID `gorm:"type:uuid;primaryKey;not null;uniqueIndex:idx_bucket_device"`
+	// Keyed by "publish:", "subscribe:" or "receive:" and the topic
+	Topics    string    `gorm:"type:jsonb;default:'{}'"`
+	ClientIDs string    `gorm:"column:client_ids;type:jsonb;default:'{}'"`
+	// Keyed by method, host and path, e.g. "POST gw.local/v1/readings"
+	Endpoints string    `gorm:"type:jsonb;default:'{}'"`
+	Requests  uint64    `gorm:"default:0"`
+
+	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (DeviceIoT5s) TableName() string {
+	return "devices_iot_5s"
+}
+
+// TLSEndpoint is the latest ServerHello and certificate each device got from
+// a TLS server. Certificate columns stay empty for TLS 1.3 servers.
+type TLSEndpoint struct {
+	pg_kit.BaseModel
+
+	DeviceID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_tls_endpoint"`
+	ServerIP    string    `gorm:"not null;uniqueIndex:idx_tls_endpoint"`
+	ServerPort  string    `gorm:"not null;uniqueIndex:idx_tls_endpoint"`
+	Domain      string    `gorm:"default:''"`
+	Version     string    `gorm:"default:''"`
+	CipherSuite int       `gorm:"default:0"`
+	Cipher      string    `gorm:"default:''"`
+	ALPN        string    `gorm:"column:alpn;default:''"`
+
+	CertSubject            string     `gorm:"default:''"`
+	CertIssuer             string     `gorm:"default:''"`
+	CertSANs               string     `gorm:"column:cert_sans;type:jsonb;default:'[]'"`
+	CertNotBefore          *time.Time
+	CertNotAfter           *time.Time
+	CertSelfSigned         bool       `gorm:"default:false"`
+	CertSignatureAlgorithm string     `gorm:"default:''"`
+
+	FirstSeen  time.Time `gorm:"not null"`
+	LastSeen   time.Time `gorm:"not null;index"`
+	Handshakes uint64    `gorm:"default:0"`
+
+	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (TLSEndpoint) TableName() string {
+	return "tls_endpoints"
+}
+// DeviceAttribute is something a device announced about itself over mDNS
+// (services, instance names, TXT model and version) or SSDP (server, USN,
+// description location).
+type DeviceAttribute struct {
+	pg_kit.BaseModel
+
+	DeviceID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_device_attribute"`
+	Source    string    `gorm:"not null;uniqueIndex:idx_device_attribute"`
+	Key       string    `gorm:"not null;uniqueIndex:idx_device_attribute"`
+	Value     string    `gorm:"not null;uniqueIndex:idx_device_attribute"`
+	FirstSeen time.Time `gorm:"not null"`
+	LastSeen  time.Time `gorm:"not null;index"`
+	Seen      uint64    `gorm:"default:0"`
+
+	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (DeviceAttribute) TableName() string {
+	return "device_attributes"
+}
+
+// InsecureProtocolUse is a device talking a cleartext protocol (FTP) to a
+// server, kept as a finding.
+type InsecureProtocolUse struct {
+	pg_kit.BaseModel
+
+	DeviceID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_insecure_protocol_use"`
+	Protocol    string    `gorm:"not null;uniqueIndex:idx_insecure_protocol_use"`
+	ServerIP    string    `gorm:"not null;uniqueIndex:idx_insecure_protocol_use"`
+	ServerPort  string    `gorm:"not null;uniqueIndex:idx_insecure_protocol_use"`
+	// Credentials is set once a non-anonymous login was seen
+	Credentials bool      `gorm:"default:false"`
+	DataBytes   uint64    `gorm:"default:0"`
+	Messages    uint64    `gorm:"default:0"`
+	FirstSeen   time.Time `gorm:"not null"`
+	LastSeen    time.Time `gorm:"not null;index"`
+
+	Device DeviceInfo `gorm:"foreignKey:DeviceID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (InsecureProtocolUse) TableName() string {
+	return "insecure_protocol_uses"
+}
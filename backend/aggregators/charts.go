@@ -186,9 +186,13 @@ func compressDomainBuckets(stats []DomainStat) []DomainStat {
 		if x.Domains == nil {
 			x.Domains = make(map[string]uint64)
 		}
+		if x.Sources == nil {
+			x.Sources = make(DomainSources)
+		}
 		for k, v := range s.Domains {
 			x.Domains[k] += v
 		}
+		x.Sources.merge(s.Sources)
 		x.ReqCount += s.ReqCount
 		acc[s.Bucket] = x
 	}
@@ -256,9 +260,71 @@ func compressCountryBuckets(stats []CountryStat) []CountryStat {
 	return out
 }
 
+func compressFingerprintBuckets(stats []FingerprintStat) []FingerprintStat {
+	if len(stats) == 0 {
+		return nil
+	}
+	acc := make(map[int64]FingerprintStat, len(stats))
+	for _, s := range stats {
+		x := acc[s.Bucket]
+		x.Bucket = s.Bucket
+		if x.JA3 == nil {
+			x.JA3 = make(map[string]uint64)
+		}
+		if x.JA4 == nil {
+			x.JA4 = make(map[string]uint64)
+		}
+		for k, v := range s.JA3 {
+			x.JA3[k] += v
+		}
+		for k, v := range s.JA4 {
+			x.JA4[k] += v
+		}
+		x.ReqCount += s.ReqCount
+		acc[s.Bucket] = x
+	}
+	out := make([]FingerprintStat, 0, len(acc))
+	for _, v := range acc {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bucket < out[j].Bucket })
+	return out
+}
+
+func compressIoTBuckets(stats []IoTStat) []IoTStat {
+	if len(stats) == 0 {
+		return nil
+	}
+	acc := make(map[int64]IoTStat, len(stats))
+	for _, s := range stats {
+		x := acc[s.Bucket]
+		x.Bucket = s.Bucket
+		if x.Topics == nil {
+			x.Topics = make(map[string]uint64)
+		}
+		if x.Endpoints == nil {
+			x.Endpoints = make(map[string]uint64)
+		}
+		for k, v := range s.Topics {
+			x.Topics[k] += v
+		}
+		for k, v := range s.Endpoints {
+			x.Endpoints[k] += v
+		}
+		x.ReqCount += s.ReqCount
+		acc[s.Bucket] = x
+	}
+	out := make([]IoTStat, 0, len(acc))
+	for _, v := range acc {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bucket < out[j].Bucket })
+	return out
+}
+
 func GetTrafficChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.Config, timerange TimeRange, deviceIDs []uuid.UUID) (TrafficChartResponse, error) {
 	merged, err := GetGenericChartData(
-		db, rdb, config, timerange, "v2_traffic_",
+		db, rdb, config, timerange, "v3_traffic_",
 		loadFromPostgres,
 		func(models []analyzerModels.DeviceTraffic5s) []TrafficChartData {
 			var result []TrafficChartData
@@ -268,7 +334,7 @@ func GetTrafficChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.C
 					Stats: []Traffic{{
 						Bucket:    entry.Bucket.Unix(),
 						UpBytes:   entry.UpBytes,
-						DownBytes: 0,
+						DownBytes: entry.DownBytes,
 						ReqCount:  entry.ReqCount,
 					}},
 				})
@@ -298,7 +364,7 @@ func GetTrafficChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.C
 
 func GetDomainChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.Config, timerange TimeRange, deviceIDs []uuid.UUID) (DomainChartResponse, error) {
 	merged, err := GetGenericChartData(
-		db, rdb, config, timerange, "v2_domain_",
+		db, rdb, config, timerange, "v3_domain_",
 		loadDomainsFromPostgres,
 		func(models []analyzerModels.DeviceDomain5s) []DomainChartData {
 			var result []DomainChartData
@@ -312,6 +378,7 @@ func GetDomainChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.Co
 					Stats: []DomainStat{{
 						Bucket:   entry.Bucket.Unix(),
 						Domains:  domains,
+						Sources:  parseDomainSources(entry.Sources),
 						ReqCount: entry.Requests,
 					}},
 				})
@@ -444,6 +511,96 @@ func GetCountryChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.C
 	return CountryChartResponse{Stats: stats}, nil
 }
 
+func GetFingerprintChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.Config, timerange TimeRange, deviceIDs []uuid.UUID) (FingerprintChartResponse, error) {
+	merged, err := GetGenericChartData(
+		db, rdb, config, timerange, "v1_fingerprint_",
+		loadFingerprintsFromPostgres,
+		func(models []analyzerModels.DeviceFingerprint5s) []FingerprintChartData {
+			var result []FingerprintChartData
+			for _, entry := range models {
+				var ja3, ja4 map[string]uint64
+				if err := json.Unmarshal([]byte(entry.JA3), &ja3); err != nil {
+					continue
+				}
+				if err := json.Unmarshal([]byte(entry.JA4), &ja4); err != nil {
+					continue
+				}
+				result = append(result, FingerprintChartData{
+					Device: Device{MAC: "__all__"},
+					Stats: []FingerprintStat{{
+						Bucket:   entry.Bucket.Unix(),
+						JA3:      ja3,
+						JA4:      ja4,
+						ReqCount: entry.Requests,
+					}},
+				})
+			}
+			return result
+		},
+		func(t FingerprintChartData) int64 { return t.Stats[0].Bucket },
+		func(t FingerprintChartData) string { return t.Device.MAC },
+		func(a, b FingerprintChartData) FingerprintChartData {
+			a.Stats = append(a.Stats, b.Stats...)
+			return a
+		},
+		filterFingerprintsByRange,
+		deviceIDs,
+	)
+	if err != nil {
+		return FingerprintChartResponse{}, err
+	}
+	if len(merged) == 0 {
+		return FingerprintChartResponse{Stats: []FingerprintStat{}}, nil
+	}
+	stats := compressFingerprintBuckets(merged[0].Stats)
+	return FingerprintChartResponse{Stats: stats}, nil
+}
+
+func GetIoTChartData(db *gorm.DB, rdb *redisutil.RedisClient, config *core.Config, timerange TimeRange, deviceIDs []uuid.UUID) (IoTChartResponse, error) {
+	merged, err := GetGenericChartData(
+		db, rdb, config, timerange, "v1_iot_",
+		loadIoTFromPostgres,
+		func(models []analyzerModels.DeviceIoT5s) []IoTChartData {
+			var result []IoTChartData
+			for _, entry := range models {
+				var topics, endpoints map[string]uint64
+				if err := json.Unmarshal([]byte(entry.Topics), &topics); err != nil {
+					continue
+				}
+				if err := json.Unmarshal([]byte(entry.Endpoints), &endpoints); err != nil {
+					continue
+				}
+				result = append(result, IoTChartData{
+					Device: Device{MAC: "__all__"},
+					Stats: []IoTStat{{
+						Bucket:    entry.Bucket.Unix(),
+						Topics:    topics,
+						Endpoints: endpoints,
+						ReqCount:  entry.Requests,
+					}},
+				})
+			}
+			return result
+		},
+		func(t IoTChartData) int64 { return t.Stats[0].Bucket },
+		func(t IoTChartData) string { return t.Device.MAC },
+		func(a, b IoTChartData) IoTChartData {
+			a.Stats = append(a.Stats, b.Stats...)
+			return a
+		},
+		filterIoTByRange,
+		deviceIDs,
+	)
+	if err != nil {
+		return IoTChartResponse{}, err
+	}
+	if len(merged) == 0 {
+		return IoTChartResponse{Stats: []IoTStat{}}, nil
+	}
+	stats := compressIoTBuckets(merged[0].Stats)
+	return IoTChartResponse{Stats: stats}, nil
+}
+
 func filterTrafficByRange(data []TrafficChartData, tr TimeRange) []TrafficChartData {
 	return filterByRange(data, tr, func(t TrafficChartData) []int64 {
 		out := make([]int64, 0, len(t.Stats))
@@ -520,6 +677,44 @@ func filterCountriesByRange(data []CountryChartData, tr TimeRange) []CountryChar
 	})
 }
 
+func filterIoTByRange(data []IoTChartData, tr TimeRange) []IoTChartData {
+	return filterByRange(data, tr, func(t IoTChartData) []int64 {
+		out := make([]int64, 0, len(t.Stats))
+		for _, s := range t.Stats {
+			out = append(out, s.Bucket)
+		}
+		return out
+	}, func(t IoTChartData, keep []bool) IoTChartData {
+		stats := t.Stats[:0]
+		for i, s := range t.Stats {
+			if keep[i] {
+				stats = append(stats, s)
+			}
+		}
+		t.Stats = stats
+		return t
+	})
+}
+
+func filterFingerprintsByRange(data []FingerprintChartData, tr TimeRange) []FingerprintChartData {
+	return filterByRange(data, tr, func(t FingerprintChartData) []int64 {
+		out := make([]int64, 0, len(t.Stats))
+		for _, s := range t.Stats {
+			out = append(out, s.Bucket)
+		}
+		return out
+	}, func(t FingerprintChartData, keep []bool) FingerprintChartData {
+		stats := t.Stats[:0]
+		for i, s := range t.Stats {
+			if keep[i] {
+				stats = append(stats, s)
+			}
+		}
+		t.Stats = stats
+		return t
+	})
+}
+
 // filterByRange removes bucket entries outside the requested timerange.
 // It keeps devices that still have at least one bucket after filtering.
 func filterByRange[T any](data []T, tr TimeRange, buckets func(T) []int64, apply func(T, []bool) T) []T {
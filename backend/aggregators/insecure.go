@@ -0,0 +1,73 @@
+package aggregators
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	analyzerModels "github.com/nrf24l01/sniffly/analyzer/postgres"
+	"gorm.io/gorm"
+)
+
+// Findings reported for cleartext protocol use
+const (
+	InsecureIssueCleartext   = "cleartext_protocol"
+	InsecureIssueCredentials = "cleartext_credentials"
+)
+
+// GetInsecureProtocolTableData lists the servers devices talked cleartext
+// protocols to in the range, and the findings per device.
+func GetInsecureProtocolTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (InsecureProtocolTableResponse, error) {
+	entries := []analyzerModels.InsecureProtocolUse{}
+	q := db.Model(&analyzerModels.InsecureProtocolUse{}).
+		Where("last_seen >= ? AND first_seen <= ?", time.Unix(timerange.Start, 0), time.Unix(timerange.End, 0)).
+		Order("last_seen DESC")
+
+	if len(deviceIDs) > 0 {
+		q = q.Where("device_id IN ?", deviceIDs)
+	}
+
+	if err := q.Find(&entries).Error; err != nil {
+		return InsecureProtocolTableResponse{}, err
+	}
+
+	out := InsecureProtocolTableResponse{
+		Uses:           make([]InsecureProtocolRow, 0, len(entries)),
+		FlaggedDevices: make(map[string][]string),
+	}
+	flagged := make(map[string]map[string]struct{})
+	for _, e := range entries {
+		row := InsecureProtocolRow{
+			DeviceID:   e.DeviceID.String(),
+			Protocol:   e.Protocol,
+			ServerIP:   e.ServerIP,
+			ServerPort: e.ServerPort,
+			Messages:   e.Messages,
+			DataBytes:  e.DataBytes,
+			FirstSeen:  e.FirstSeen.Unix(),
+			LastSeen:   e.LastSeen.Unix(),
+			Issues:     []string{InsecureIssueCleartext},
+		}
+		if e.Credentials {
+			row.Issues = append(row.Issues, InsecureIssueCredentials)
+		}
+		out.Uses = append(out.Uses, row)
+
+		if flagged[row.DeviceID] == nil {
+			flagged[row.DeviceID] = make(map[string]struct{})
+		}
+		for _, issue := range row.Issues {
+			flagged[row.DeviceID][issue] = struct{}{}
+		}
+	}
+
+	for device, issues := range flagged {
+		list := make([]string, 0, len(issues))
+		for issue := range issues {
+			list = append(list, issue)
+		}
+		sort.Strings(list)
+		out.FlaggedDevices[device] = list
+	}
+	return out, nil
+}
@@ -154,4 +154,62 @@ func loadProtosFromPostgres(db *gorm.DB, times_to_load []time.Time, deviceIDs []
 	}
 
 	return results, nil
-}
\ No newline at end of file
+}
+
+func loadFingerprintsFromPostgres(db *gorm.DB, times_to_load []time.Time, deviceIDs []uuid.UUID) ([]analyzerModels.DeviceFingerprint5s, error) {
+	results := make([]analyzerModels.DeviceFingerprint5s, 0)
+
+	batchSize := 100
+	for i := 0; i < len(times_to_load); i += batchSize {
+		end := i + batchSize
+		if end > len(times_to_load) {
+			end = len(times_to_load)
+		}
+
+		batch := times_to_load[i:end]
+		dayStart := batch[0].Truncate(24 * time.Hour)
+		dayEnd := batch[len(batch)-1].Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+		batchResults := make([]analyzerModels.DeviceFingerprint5s, 0)
+		q := db.Where("bucket >= ? AND bucket < ?", dayStart, dayEnd)
+		if len(deviceIDs) > 0 {
+			q = q.Where("device_id IN ?", deviceIDs)
+		}
+		if err := q.Find(&batchResults).Error; err != nil {
+			return nil, err
+		}
+
+		results = append(results, batchResults...)
+	}
+
+	return results, nil
+}
+
+func loadIoTFromPostgres(db *gorm.DB, times_to_load []time.Time, deviceIDs []uuid.UUID) ([]analyzerModels.DeviceIoT5s, error) {
+	results := make([]analyzerModels.DeviceIoT5s, 0)
+
+	batchSize := 100
+	for i := 0; i < len(times_to_load); i += batchSize {
+		end := i + batchSize
+		if end > len(times_to_load) {
+			end = len(times_to_load)
+		}
+
+		batch := times_to_load[i:end]
+		dayStart := batch[0].Truncate(24 * time.Hour)
+		dayEnd := batch[len(batch)-1].Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+		batchResults := make([]analyzerModels.DeviceIoT5s, 0)
+		q := db.Where("bucket >= ? AND bucket < ?", dayStart, dayEnd)
+		if len(deviceIDs) > 0 {
+			q = q.Where("device_id IN ?", deviceIDs)
+		}
+		if err := q.Find(&batchResults).Error; err != nil {
+			return nil, err
+		}
+
+		results = append(results, batchResults...)
+	}
+
+	return results, nil
+}
@@ -1,5 +1,10 @@
 package aggregators
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type TimeRange struct {
 	Start int64 `json:"start"`
 	End   int64 `json:"end"`
@@ -25,9 +30,13 @@ type TrafficChartData struct {
 	Stats  []Traffic `json:"stats"`
 }
 
+// DomainSources counts per domain where its label came from: sni, host or dns.
+type DomainSources map[string]map[string]uint64
+
 type DomainStat struct {
 	Bucket   int64             `json:"bucket"`
 	Domains  map[string]uint64 `json:"domains"`
+	Sources  DomainSources     `json:"sources"`
 	ReqCount uint64            `json:"req_count"`
 }
 
@@ -59,6 +68,32 @@ type CountryChartData struct {
 	Stats  []CountryStat `json:"stats"`
 }
 
+type FingerprintStat struct {
+	Bucket   int64             `json:"bucket"`
+	JA3      map[string]uint64 `json:"ja3"`
+	JA4      map[string]uint64 `json:"ja4"`
+	ReqCount uint64            `json:"req_count"`
+}
+
+type FingerprintChartData struct {
+	Device Device            `json:"device"`
+	Stats  []FingerprintStat `json:"stats"`
+}
+
+// IoTStat holds MQTT topics, keyed by "publish:", "subscribe:" or
+// "receive:" and the topic, and CoAP endpoints ("POST host/path").
+type IoTStat struct {
+	Bucket    int64             `json:"bucket"`
+	Topics    map[string]uint64 `json:"topics"`
+	Endpoints map[string]uint64 `json:"endpoints"`
+	ReqCount  uint64            `json:"req_count"`
+}
+
+type IoTChartData struct {
+	Device Device    `json:"device"`
+	Stats  []IoTStat `json:"stats"`
+}
+
 // Aggregated (device-less) API responses
 
 type TrafficChartResponse struct {
@@ -85,7 +120,8 @@ type TrafficTableResponse struct {
 }
 
 type DomainTableResponse struct {
-	Stats map[string]uint64 `json:"stats"`
+	Stats   map[string]uint64 `json:"stats"`
+	Sources DomainSources     `json:"sources"`
 }
 
 type CountryTableResponse struct {
@@ -99,3 +135,137 @@ type ProtoTableResponse struct {
 type CompanyTableResponse struct {
 	Stats map[string]uint64 `json:"stats"`
 }
+
+type FingerprintChartResponse struct {
+	Stats []FingerprintStat `json:"stats"`
+}
+
+// FingerprintSeen is one fingerprint of a device within the requested range.
+// New is set when the device used other fingerprints before the range but
+// never this one, e.g. after a firmware update or a compromise.
+type FingerprintSeen struct {
+	Fingerprint string `json:"fingerprint"`
+	Count       uint64 `json:"count"`
+	FirstSeen   int64  `json:"first_seen"`
+	LastSeen    int64  `json:"last_seen"`
+	New         bool   `json:"new"`
+}
+
+type DeviceFingerprints struct {
+	DeviceID string            `json:"device_id"`
+	JA3      []FingerprintSeen `json:"ja3"`
+	JA4      []FingerprintSeen `json:"ja4"`
+	// Changed is set when any JA4 fingerprint is new
+	Changed bool `json:"changed"`
+}
+
+type HTTPTableResponse struct {
+	UserAgents   map[string]uint64 `json:"user_agents"`
+	StatusCodes  map[string]uint64 `json:"status_codes"`
+	ContentTypes map[string]uint64 `json:"content_types"`
+	BodyBytes    uint64            `json:"body_bytes"`
+	// DeviceUserAgents maps device IDs to their user agents
+	DeviceUserAgents map[string]map[string]uint64 `json:"device_user_agents"`
+}
+
+type TLSCertificateInfo struct {
+	Subject            string   `json:"subject"`
+	Issuer             string   `json:"issuer"`
+	SANs               []string `json:"sans"`
+	NotBefore          int64    `json:"not_before"`
+	NotAfter           int64    `json:"not_after"`
+	SelfSigned         bool     `json:"self_signed"`
+	SignatureAlgorithm string   `json:"signature_algorithm"`
+}
+
+type TLSEndpointRow struct {
+	DeviceID    string              `json:"device_id"`
+	ServerIP    string              `json:"server_ip"`
+	ServerPort  string              `json:"server_port"`
+	Domain      string              `json:"domain"`
+	Version     string              `json:"version"`
+	Cipher      string              `json:"cipher"`
+	ALPN        string              `json:"alpn"`
+	Certificate *TLSCertificateInfo `json:"certificate"`
+	FirstSeen   int64               `json:"first_seen"`
+	LastSeen    int64               `json:"last_seen"`
+	Handshakes  uint64              `json:"handshakes"`
+	Issues      []string            `json:"issues"`
+}
+
+type TLSEndpointTableResponse struct {
+	Endpoints []TLSEndpointRow `json:"endpoints"`
+	// FlaggedDevices maps device IDs to the findings of their endpoints
+	FlaggedDevices map[string][]string `json:"flagged_devices"`
+}
+
+type IoTChartResponse struct {
+	Stats []IoTStat `json:"stats"`
+}
+
+type IoTDeviceStats struct {
+	Topics    map[string]uint64 `json:"topics"`
+	ClientIDs map[string]uint64 `json:"client_ids"`
+	Endpoints map[string]uint64 `json:"endpoints"`
+}
+
+type IoTTableResponse struct {
+	Topics    map[string]uint64 `json:"topics"`
+	ClientIDs map[string]uint64 `json:"client_ids"`
+	Endpoints map[string]uint64 `json:"endpoints"`
+	// Devices maps device IDs to their own topics, client IDs and endpoints
+	Devices map[string]IoTDeviceStats `json:"devices"`
+}
+
+type InsecureProtocolRow struct {
+	DeviceID   string   `json:"device_id"`
+	Protocol   string   `json:"protocol"`
+	ServerIP   string   `json:"server_ip"`
+	ServerPort string   `json:"server_port"`
+	Messages   uint64   `json:"messages"`
+	DataBytes  uint64   `json:"data_bytes"`
+	FirstSeen  int64    `json:"first_seen"`
+	LastSeen   int64    `json:"last_seen"`
+	Issues     []string `json:"issues"`
+}
+
+type InsecureProtocolTableResponse struct {
+	Uses []InsecureProtocolRow `json:"uses"`
+	// FlaggedDevices maps device IDs to their findings
+	FlaggedDevices map[string][]string `json:"flagged_devices"`
+}
+
+type FingerprintTableResponse struct {
+	Devices []DeviceFingerprints `json:"devices"`
+}
+
+// parseDomainSources reads the "source:domain" counters stored by the analyzer.
+func parseDomainSources(raw string) DomainSources {
+	out := make(DomainSources)
+	var flat map[string]uint64
+	if err := json.Unmarshal([]byte(raw), &flat); err != nil {
+		return out
+	}
+	for key, v := range flat {
+		source, domain, ok := strings.Cut(key, ":")
+		if !ok {
+			continue
+		}
+		if out[domain] == nil {
+			out[domain] = make(map[string]uint64)
+		}
+		out[domain][source] += v
+	}
+	return out
+}
+
+func (s DomainSources) merge(other DomainSources) {
+	for domain, sources := range other {
+		if s[domain] == nil {
+			s[domain] = make(map[string]uint64)
+		}
+		for source, v := range sources {
+			s[domain][source] += v
+		}
+	}
+}
@@ -2,6 +2,7 @@ package aggregators
 
 import (
 	"encoding/json"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,11 +13,12 @@ import (
 
 func GetTrafficTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (TrafficTableResponse, error) {
 	type row struct {
-		UpBytes uint64
+		UpBytes   uint64
+		DownBytes uint64
 	}
 
 	q := db.Model(&analyzerModels.DeviceTraffic5s{}).
-		Select("COALESCE(SUM(up_bytes), 0) as up_bytes").
+		Select("COALESCE(SUM(up_bytes), 0) as up_bytes, COALESCE(SUM(down_bytes), 0) as down_bytes").
 		Where("bucket >= ? AND bucket <= ?", time.Unix(timerange.Start, 0), time.Unix(timerange.End, 0))
 
 	if len(deviceIDs) > 0 {
@@ -30,7 +32,7 @@ func GetTrafficTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID
 
 	out := TrafficTableResponse{}
 	out.Stats.UpBytes = r.UpBytes
-	out.Stats.DownBytes = 0
+	out.Stats.DownBytes = r.DownBytes
 	return out, nil
 }
 
@@ -48,6 +50,7 @@ func GetDomainTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID)
 	}
 
 	stats := make(map[string]uint64)
+	sources := make(DomainSources)
 	for _, e := range entries {
 		var domains map[string]uint64
 		if err := json.Unmarshal([]byte(e.Domain), &domains); err != nil {
@@ -57,9 +60,10 @@ func GetDomainTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID)
 		for k, v := range domains {
 			stats[k] += v
 		}
+		sources.merge(parseDomainSources(e.Sources))
 	}
 
-	return DomainTableResponse{Stats: stats}, nil
+	return DomainTableResponse{Stats: stats, Sources: sources}, nil
 }
 
 func GetCountryTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (CountryTableResponse, error) {
@@ -175,3 +179,216 @@ func GetProtoTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID)
 
 	return ProtoTableResponse{Stats: stats}, nil
 }
+
+func GetHTTPTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (HTTPTableResponse, error) {
+	entries := []analyzerModels.DeviceHTTP5s{}
+	q := db.Model(&analyzerModels.DeviceHTTP5s{}).
+		Where("bucket >= ? AND bucket <= ?", time.Unix(timerange.Start, 0), time.Unix(timerange.End, 0))
+
+	if len(deviceIDs) > 0 {
+		q = q.Where("device_id IN ?", deviceIDs)
+	}
+
+	if err := q.Find(&entries).Error; err != nil {
+		return HTTPTableResponse{}, err
+	}
+
+	out := HTTPTableResponse{
+		UserAgents:       make(map[string]uint64),
+		StatusCodes:      make(map[string]uint64),
+		ContentTypes:     make(map[string]uint64),
+		DeviceUserAgents: make(map[string]map[string]uint64),
+	}
+	sum := func(dst map[string]uint64, raw string) map[string]uint64 {
+		var counts map[string]uint64
+		if err := json.Unmarshal([]byte(raw), &counts); err != nil {
+			return nil
+		}
+		for k, v := range counts {
+			dst[k] += v
+		}
+		return counts
+	}
+	for _, e := range entries {
+		if agents := sum(out.UserAgents, e.UserAgents); len(agents) > 0 {
+			device := e.DeviceID.String()
+			if out.DeviceUserAgents[device] == nil {
+				out.DeviceUserAgents[device] = make(map[string]uint64)
+			}
+			for k, v := range agents {
+				out.DeviceUserAgents[device][k] += v
+			}
+		}
+		sum(out.StatusCodes, e.StatusCodes)
+		sum(out.ContentTypes, e.ContentTypes)
+		out.BodyBytes += e.BodyBytes
+	}
+
+	return out, nil
+}
+
+// GetIoTTableData sums MQTT topics and client IDs and CoAP endpoints over
+// the range, overall and per device.
+func GetIoTTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (IoTTableResponse, error) {
+	entries := []analyzerModels.DeviceIoT5s{}
+	q := db.Model(&analyzerModels.DeviceIoT5s{}).
+		Where("bucket >= ? AND bucket <= ?", time.Unix(timerange.Start, 0), time.Unix(timerange.End, 0))
+
+	if len(deviceIDs) > 0 {
+		q = q.Where("device_id IN ?", deviceIDs)
+	}
+
+	if err := q.Find(&entries).Error; err != nil {
+		return IoTTableResponse{}, err
+	}
+
+	out := IoTTableResponse{
+		Topics:    make(map[string]uint64),
+		ClientIDs: make(map[string]uint64),
+		Endpoints: make(map[string]uint64),
+		Devices:   make(map[string]IoTDeviceStats),
+	}
+	sum := func(total, device map[string]uint64, raw string) {
+		var counts map[string]uint64
+		if err := json.Unmarshal([]byte(raw), &counts); err != nil {
+			return
+		}
+		for k, v := range counts {
+			total[k] += v
+			device[k] += v
+		}
+	}
+	for _, e := range entries {
+		id := e.DeviceID.String()
+		d, ok := out.Devices[id]
+		if !ok {
+			d = IoTDeviceStats{
+				Topics:    make(map[string]uint64),
+				ClientIDs: make(map[string]uint64),
+				Endpoints: make(map[string]uint64),
+			}
+			out.Devices[id] = d
+		}
+		sum(out.Topics, d.Topics, e.Topics)
+		sum(out.ClientIDs, d.ClientIDs, e.ClientIDs)
+		sum(out.Endpoints, d.Endpoints, e.Endpoints)
+	}
+
+	return out, nil
+}
+
+// GetFingerprintTableData lists the TLS fingerprints of each device in the
+// range and marks the ones the device had not used before it.
+func GetFingerprintTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (FingerprintTableResponse, error) {
+	entries := []analyzerModels.DeviceFingerprint5s{}
+	q := db.Model(&analyzerModels.DeviceFingerprint5s{}).
+		Where("bucket >= ? AND bucket <= ?", time.Unix(timerange.Start, 0), time.Unix(timerange.End, 0)).
+		Order("bucket, device_id")
+
+	if len(deviceIDs) > 0 {
+		q = q.Where("device_id IN ?", deviceIDs)
+	}
+
+	if err := q.Find(&entries).Error; err != nil {
+		return FingerprintTableResponse{}, err
+	}
+
+	out := FingerprintTableResponse{Devices: []DeviceFingerprints{}}
+	if len(entries) == 0 {
+		return out, nil
+	}
+	inRange := make(map[uuid.UUID]struct{})
+	for _, e := range entries {
+		inRange[e.DeviceID] = struct{}{}
+	}
+	ids := make([]uuid.UUID, 0, len(inRange))
+	for id := range inRange {
+		ids = append(ids, id)
+	}
+
+	// Fingerprints each device used before the range
+	type knownRow struct {
+		DeviceID uuid.UUID
+		Kind     string
+		Value    string
+	}
+	var known []knownRow
+	kq := db.Raw(`SELECT DISTINCT device_id, 'ja3' AS kind, jsonb_object_keys(ja3) AS value FROM devices_fingerprints_5s WHERE bucket < ? AND device_id IN ?
+		UNION SELECT DISTINCT device_id, 'ja4', jsonb_object_keys(ja4) FROM devices_fingerprints_5s WHERE bucket < ? AND device_id IN ?`,
+		time.Unix(timerange.Start, 0), ids, time.Unix(timerange.Start, 0), ids)
+	if err := kq.Scan(&known).Error; err != nil {
+		return FingerprintTableResponse{}, err
+	}
+	history := make(map[uuid.UUID]map[string]bool)
+	for _, k := range known {
+		if history[k.DeviceID] == nil {
+			history[k.DeviceID] = make(map[string]bool)
+		}
+		history[k.DeviceID][k.Kind+":"+k.Value] = true
+	}
+
+	type seenKey struct {
+		device uuid.UUID
+		kind   string
+		value  string
+	}
+	seen := make(map[seenKey]*FingerprintSeen)
+	var order []seenKey
+	add := func(device uuid.UUID, kind, raw string, bucket int64) {
+		var counts map[string]uint64
+		if err := json.Unmarshal([]byte(raw), &counts); err != nil {
+			return
+		}
+		for fp, v := range counts {
+			key := seenKey{device, kind, fp}
+			s, ok := seen[key]
+			if !ok {
+				s = &FingerprintSeen{Fingerprint: fp, FirstSeen: bucket}
+				seen[key] = s
+				order = append(order, key)
+			}
+			s.Count += v
+			s.LastSeen = bucket
+		}
+	}
+	for _, e := range entries {
+		add(e.DeviceID, "ja3", e.JA3, e.Bucket.Unix())
+		add(e.DeviceID, "ja4", e.JA4, e.Bucket.Unix())
+	}
+
+	devices := make(map[uuid.UUID]*DeviceFingerprints)
+	var deviceOrder []uuid.UUID
+	for _, key := range order {
+		d, ok := devices[key.device]
+		if !ok {
+			d = &DeviceFingerprints{DeviceID: key.device.String(), JA3: []FingerprintSeen{}, JA4: []FingerprintSeen{}}
+			devices[key.device] = d
+			deviceOrder = append(deviceOrder, key.device)
+		}
+		s := *seen[key]
+		s.New = len(history[key.device]) > 0 && !history[key.device][key.kind+":"+key.value]
+		if key.kind == "ja3" {
+			d.JA3 = append(d.JA3, s)
+		} else {
+			d.JA4 = append(d.JA4, s)
+			d.Changed = d.Changed || s.New
+		}
+	}
+	// Fingerprints of the same bucket come from a JSON object, order them
+	// so the table is stable between requests
+	bySeen := func(list []FingerprintSeen) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].FirstSeen != list[j].FirstSeen {
+				return list[i].FirstSeen < list[j].FirstSeen
+			}
+			return list[i].Fingerprint < list[j].Fingerprint
+		})
+	}
+	for _, id := range deviceOrder {
+		d := devices[id]
+		bySeen(d.JA3)
+		bySeen(d.JA4)
+		out.Devices = append(out.Devices, *d)
+	}
+	return out, nil
+}
@@ -0,0 +1,128 @@
+package aggregators
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	analyzerModels "github.com/nrf24l01/sniffly/analyzer/postgres"
+	"gorm.io/gorm"
+)
+
+// Findings reported for TLS endpoints
+const (
+	TLSIssueExpired       = "expired"
+	TLSIssueNotYetValid   = "not_yet_valid"
+	TLSIssueSelfSigned    = "self_signed"
+	TLSIssueWeakCipher    = "weak_cipher"
+	TLSIssueWeakVersion   = "weak_version"
+	TLSIssueWeakSignature = "weak_signature"
+)
+
+var weakCipherMarkers = []string{"_NULL_", "_EXPORT_", "_anon_", "_RC4_", "_RC2_", "_DES_", "_3DES_", "_MD5"}
+
+// tlsEndpointIssues judges an endpoint by what it presented at its last
+// handshake.
+func tlsEndpointIssues(e analyzerModels.TLSEndpoint) []string {
+	issues := []string{}
+	if e.CertNotAfter != nil && e.LastSeen.After(*e.CertNotAfter) {
+		issues = append(issues, TLSIssueExpired)
+	}
+	if e.CertNotBefore != nil && e.LastSeen.Before(*e.CertNotBefore) {
+		issues = append(issues, TLSIssueNotYetValid)
+	}
+	if e.CertSelfSigned {
+		issues = append(issues, TLSIssueSelfSigned)
+	}
+	for _, marker := range weakCipherMarkers {
+		if strings.Contains(e.Cipher, marker) {
+			issues = append(issues, TLSIssueWeakCipher)
+			break
+		}
+	}
+	switch e.Version {
+	case "SSL 3.0", "TLS 1.0", "TLS 1.1":
+		issues = append(issues, TLSIssueWeakVersion)
+	}
+	if sig := strings.ToUpper(e.CertSignatureAlgorithm); strings.Contains(sig, "SHA1") || strings.Contains(sig, "MD5") || strings.Contains(sig, "MD2") {
+		issues = append(issues, TLSIssueWeakSignature)
+	}
+	return issues
+}
+
+// GetTLSEndpointTableData lists the TLS servers devices completed a
+// handshake with in the range, with their findings, and the devices that
+// talk to at least one flagged endpoint.
+func GetTLSEndpointTableData(db *gorm.DB, timerange TimeRange, deviceIDs []uuid.UUID) (TLSEndpointTableResponse, error) {
+	entries := []analyzerModels.TLSEndpoint{}
+	q := db.Model(&analyzerModels.TLSEndpoint{}).
+		Where("last_seen >= ? AND first_seen <= ?", time.Unix(timerange.Start, 0), time.Unix(timerange.End, 0)).
+		Order("last_seen DESC")
+
+	if len(deviceIDs) > 0 {
+		q = q.Where("device_id IN ?", deviceIDs)
+	}
+
+	if err := q.Find(&entries).Error; err != nil {
+		return TLSEndpointTableResponse{}, err
+	}
+
+	out := TLSEndpointTableResponse{
+		Endpoints:      make([]TLSEndpointRow, 0, len(entries)),
+		FlaggedDevices: make(map[string][]string),
+	}
+	flagged := make(map[string]map[string]struct{})
+	for _, e := range entries {
+		row := TLSEndpointRow{
+			DeviceID:   e.DeviceID.String(),
+			ServerIP:   e.ServerIP,
+			ServerPort: e.ServerPort,
+			Domain:     e.Domain,
+			Version:    e.Version,
+			Cipher:     e.Cipher,
+			ALPN:       e.ALPN,
+			FirstSeen:  e.FirstSeen.Unix(),
+			LastSeen:   e.LastSeen.Unix(),
+			Handshakes: e.Handshakes,
+			Issues:     tlsEndpointIssues(e),
+		}
+		if e.CertNotAfter != nil {
+			cert := &TLSCertificateInfo{
+				Subject:            e.CertSubject,
+				Issuer:             e.CertIssuer,
+				SANs:               []string{},
+				NotAfter:           e.CertNotAfter.Unix(),
+				SelfSigned:         e.CertSelfSigned,
+				SignatureAlgorithm: e.CertSignatureAlgorithm,
+			}
+			if e.CertNotBefore != nil {
+				cert.NotBefore = e.CertNotBefore.Unix()
+			}
+			_ = json.Unmarshal([]byte(e.CertSANs), &cert.SANs)
+			row.Certificate = cert
+		}
+		out.Endpoints = append(out.Endpoints, row)
+
+		if len(row.Issues) == 0 {
+			continue
+		}
+		if flagged[row.DeviceID] == nil {
+			flagged[row.DeviceID] = make(map[string]struct{})
+		}
+		for _, issue := range row.Issues {
+			flagged[row.DeviceID][issue] = struct{}{}
+		}
+	}
+
+	for device, issues := range flagged {
+		list := make([]string, 0, len(issues))
+		for issue := range issues {
+			list = append(list, issue)
+		}
+		sort.Strings(list)
+		out.FlaggedDevices[device] = list
+	}
+	return out, nil
+}
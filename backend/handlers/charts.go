@@ -94,4 +94,42 @@ func (h *Handler) GetChartsCountriesHandler(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, data)
-}
\ No newline at end of file
+}
+
+func (h *Handler) GetChartsFingerprintsHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetFingerprintChartData(h.DB, h.RDB, h.Config, aggregators.TimeRange{
+		Start: req.From,
+		End:   req.To,
+	}, deviceIDs)
+	if err != nil {
+		log.Printf("GetFingerprintChartData error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetChartsIoTHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetIoTChartData(h.DB, h.RDB, h.Config, aggregators.TimeRange{
+		Start: req.From,
+		End:   req.To,
+	}, deviceIDs)
+	if err != nil {
+		log.Printf("GetIoTChartData error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
@@ -16,6 +16,11 @@ func (h *Handler) GetDevicesHandler(c echo.Context) error {
         return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
     }
 
+    attributes, err := loadDeviceAttributes(h.DB)
+    if err != nil {
+        return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+    }
+
     resp := make([]schemas.DeviceListItem, 0, len(devices))
     for _, d := range devices {
         resp = append(resp, schemas.DeviceListItem{
@@ -23,6 +28,11 @@ func (h *Handler) GetDevicesHandler(c echo.Context) error {
             MAC:       d.MAC,
             IP:        d.IP,
             UserLabel: d.Label,
+            Interface: d.Interface,
+            Hostname:        d.Hostname,
+            VendorClass:     d.VendorClass,
+            DHCPFingerprint: d.DHCPFingerprint,
+            Attributes:      deviceAttributes(attributes, d.ID.String()),
         })
     }
 
@@ -42,7 +52,13 @@ func (h *Handler) UpdateDeviceLabelHandler(c echo.Context) error {
     }
 
     device.Label = req.UserLabel
-    if err := h.DB.Save(&device).Error; err != nil {
+    // Only the label, saving the whole row would turn a NULL MAC into ''
+    if err := h.DB.Model(&device).Update("label", req.UserLabel).Error; err != nil {
+        return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+    }
+
+    attributes, err := loadDeviceAttributes(h.DB.Where("device_id = ?", device.ID))
+    if err != nil {
         return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
     }
 
@@ -51,7 +67,41 @@ func (h *Handler) UpdateDeviceLabelHandler(c echo.Context) error {
         MAC:       device.MAC,
         IP:        device.IP,
         UserLabel: device.Label,
+        Interface: device.Interface,
+        Hostname:        device.Hostname,
+        VendorClass:     device.VendorClass,
+        DHCPFingerprint: device.DHCPFingerprint,
+        Attributes:      deviceAttributes(attributes, device.ID.String()),
     }
 
     return c.JSON(http.StatusOK, resp)
 }
+
+// loadDeviceAttributes groups the discovered attributes matched by q by
+// device ID.
+func loadDeviceAttributes(q *gorm.DB) (map[string][]schemas.DeviceAttribute, error) {
+    var rows []analyzerModels.DeviceAttribute
+    if err := q.Order("source, key, value").Find(&rows).Error; err != nil {
+        return nil, err
+    }
+
+    out := make(map[string][]schemas.DeviceAttribute)
+    for _, a := range rows {
+        id := a.DeviceID.String()
+        out[id] = append(out[id], schemas.DeviceAttribute{
+            Source:    a.Source,
+            Key:       a.Key,
+            Value:     a.Value,
+            FirstSeen: a.FirstSeen.Unix(),
+            LastSeen:  a.LastSeen.Unix(),
+        })
+    }
+    return out, nil
+}
+
+func deviceAttributes(attributes map[string][]schemas.DeviceAttribute, id string) []schemas.DeviceAttribute {
+    if list, ok := attributes[id]; ok {
+        return list
+    }
+    return []schemas.DeviceAttribute{}
+}
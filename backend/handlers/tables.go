@@ -89,3 +89,83 @@ func (h *Handler) GetTablesCompaniesHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, data)
 }
+
+func (h *Handler) GetTablesFingerprintsHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetFingerprintTableData(h.DB, aggregators.TimeRange{Start: req.From, End: req.To}, deviceIDs)
+	if err != nil {
+		log.Printf("GetTablesFingerprintsHandler error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetTablesTLSEndpointsHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetTLSEndpointTableData(h.DB, aggregators.TimeRange{Start: req.From, End: req.To}, deviceIDs)
+	if err != nil {
+		log.Printf("GetTablesTLSEndpointsHandler error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetTablesHTTPHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetHTTPTableData(h.DB, aggregators.TimeRange{Start: req.From, End: req.To}, deviceIDs)
+	if err != nil {
+		log.Printf("GetTablesHTTPHandler error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetTablesIoTHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetIoTTableData(h.DB, aggregators.TimeRange{Start: req.From, End: req.To}, deviceIDs)
+	if err != nil {
+		log.Printf("GetTablesIoTHandler error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetTablesInsecureHandler(c echo.Context) error {
+	req := c.Get("validatedQuery").(*schemas.ChartDataRangeRequest)
+	deviceIDs, err := parseDeviceIDs(req.DeviceIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echokitSchemas.DefaultBadRequestResponse)
+	}
+
+	data, err := aggregators.GetInsecureProtocolTableData(h.DB, aggregators.TimeRange{Start: req.From, End: req.To}, deviceIDs)
+	if err != nil {
+		log.Printf("GetTablesInsecureHandler error: %v", err)
+		return c.JSON(http.StatusInternalServerError, echokitSchemas.DefaultInternalErrorResponse)
+	}
+
+	return c.JSON(http.StatusOK, data)
+}
@@ -28,5 +28,13 @@ func RegisterChartsRoutes(e *echo.Echo, h *handlers.Handler) {
 	group.GET("/countries", h.GetChartsCountriesHandler, echokitMW.QueryValidationMiddleware(func() interface{} {
 		return &schemas.ChartDataRangeRequest{}
 	}))
+
+	group.GET("/fingerprints", h.GetChartsFingerprintsHandler, echokitMW.QueryValidationMiddleware(func() interface{} {
+		return &schemas.ChartDataRangeRequest{}
+	}))
+
+	group.GET("/iot", h.GetChartsIoTHandler, echokitMW.QueryValidationMiddleware(func() interface{} {
+		return &schemas.ChartDataRangeRequest{}
+	}))
 }
 
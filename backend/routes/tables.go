@@ -19,4 +19,9 @@ func RegisterTablesRoutes(e *echo.Echo, h *handlers.Handler) {
 	group.GET("/countries", h.GetTablesCountriesHandler, echokitMW.QueryValidationMiddleware(validator))
 	group.GET("/protos", h.GetTablesProtosHandler, echokitMW.QueryValidationMiddleware(validator))
 	group.GET("/companies", h.GetTablesCompaniesHandler, echokitMW.QueryValidationMiddleware(validator))
+	group.GET("/fingerprints", h.GetTablesFingerprintsHandler, echokitMW.QueryValidationMiddleware(validator))
+	group.GET("/tls", h.GetTablesTLSEndpointsHandler, echokitMW.QueryValidationMiddleware(validator))
+	group.GET("/http", h.GetTablesHTTPHandler, echokitMW.QueryValidationMiddleware(validator))
+	group.GET("/iot", h.GetTablesIoTHandler, echokitMW.QueryValidationMiddleware(validator))
+	group.GET("/insecure", h.GetTablesInsecureHandler, echokitMW.QueryValidationMiddleware(validator))
 }
@@ -5,6 +5,21 @@ type DeviceListItem struct {
 	MAC       string `json:"mac"`
 	IP        string `json:"ip"`
 	UserLabel string `json:"user_label"`
+	Interface string `json:"interface"`
+	// Learned from DHCP client messages
+	Hostname        string `json:"hostname"`
+	VendorClass     string `json:"vendor_class"`
+	DHCPFingerprint string `json:"dhcp_fingerprint"`
+	// Announced over mDNS and SSDP
+	Attributes []DeviceAttribute `json:"attributes"`
+}
+
+type DeviceAttribute struct {
+	Source    string `json:"source"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	FirstSeen int64  `json:"first_seen"`
+	LastSeen  int64  `json:"last_seen"`
 }
 
 type UpdateDeviceLabelRequest struct {
@@ -0,0 +1,123 @@
+package batch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxDecodedBytes caps the size of a decompressed batch.
+const MaxDecodedBytes = 64 << 20
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecodedBytes))
+)
+
+// ParseCompressions parses a comma separated list such as "zstd,gzip".
+func ParseCompressions(list string) ([]pb.Compression, error) {
+	var out []pb.Compression
+	for _, name := range strings.Split(list, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "":
+		case "zstd":
+			out = append(out, pb.Compression_COMPRESSION_ZSTD)
+		case "gzip":
+			out = append(out, pb.Compression_COMPRESSION_GZIP)
+		case "none":
+			out = append(out, pb.Compression_COMPRESSION_NONE)
+		default:
+			return nil, fmt.Errorf("unknown compression %q", name)
+		}
+	}
+	return out, nil
+}
+
+// Choose picks the first offered compression that is also allowed.
+func Choose(offered, allowed []pb.Compression) pb.Compression {
+	for _, c := range offered {
+		for _, a := range allowed {
+			if c == a {
+				return c
+			}
+		}
+	}
+	return pb.Compression_COMPRESSION_NONE
+}
+
+// Encode serializes packets into a batch compressed with c.
+func Encode(sourceID string, sequence uint64, packets []*pb.Packet, c pb.Compression) (*pb.PacketBatch, error) {
+	data, err := proto.Marshal(&pb.PacketList{Packets: packets})
+	if err != nil {
+		return nil, err
+	}
+
+	switch c {
+	case pb.Compression_COMPRESSION_NONE:
+	case pb.Compression_COMPRESSION_ZSTD:
+		data = zstdEncoder.EncodeAll(data, nil)
+	case pb.Compression_COMPRESSION_GZIP:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", c)
+	}
+
+	return &pb.PacketBatch{
+		SourceId:    sourceID,
+		Sequence:    sequence,
+		Count:       uint32(len(packets)),
+		Compression: c,
+		Packets:     data,
+	}, nil
+}
+
+// Decode returns the packets carried by a batch.
+func Decode(b *pb.PacketBatch) ([]*pb.Packet, error) {
+	data := b.GetPackets()
+
+	switch b.GetCompression() {
+	case pb.Compression_COMPRESSION_NONE:
+	case pb.Compression_COMPRESSION_ZSTD:
+		var err error
+		if data, err = zstdDecoder.DecodeAll(data, nil); err != nil {
+			return nil, err
+		}
+	case pb.Compression_COMPRESSION_GZIP:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(io.LimitReader(zr, MaxDecodedBytes+1)); err != nil {
+			return nil, err
+		}
+		if len(data) > MaxDecodedBytes {
+			return nil, fmt.Errorf("batch exceeds %d bytes", MaxDecodedBytes)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", b.GetCompression())
+	}
+
+	var list pb.PacketList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if uint32(len(list.Packets)) != b.GetCount() {
+		return nil, fmt.Errorf("batch %d: expected %d packets, got %d", b.GetSequence(), b.GetCount(), len(list.Packets))
+	}
+	return list.Packets, nil
+}
@@ -11,6 +11,15 @@ type CaptureConfig struct {
 	AppHost 		      string `env:"CAPTURE_APP_HOST" envDefault:":50051"`
 	PacketsTopic	    string `env:"CAPTURE_PACKETS_TOPIC" envDefault:"sniffed_packets"`
 	PingEnabled       bool   `env:"CAPTURE_PING_ENABLED" envDefault:"false"`
+	// Compressions accepted for batched streams, in server preference order
+	BatchCompressions string `env:"CAPTURE_BATCH_COMPRESSIONS" envDefault:"zstd,gzip"`
+
+	// TLS is enabled when both the certificate and the key are set. With a
+	// client CA, capturers may also present certificates signed by it.
+	TLSCertFile          string `env:"CAPTURE_TLS_CERT_FILE" envDefault:""`
+	TLSKeyFile           string `env:"CAPTURE_TLS_KEY_FILE" envDefault:""`
+	TLSClientCAFile      string `env:"CAPTURE_TLS_CLIENT_CA_FILE" envDefault:""`
+	TLSRequireClientCert bool   `env:"CAPTURE_TLS_REQUIRE_CLIENT_CERT" envDefault:"false"`
 }
 
 
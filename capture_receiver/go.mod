@@ -5,7 +5,9 @@ go 1.24.6
 toolchain go1.24.10
 
 require (
+	github.com/caarlos0/env/v11 v11.3.1
 	github.com/joho/godotenv v1.5.1
+	github.com/klauspost/compress v1.18.0
 	github.com/nrf24l01/go-web-utils v1.6.2
 	github.com/rabbitmq/amqp091-go v1.10.0
 	google.golang.org/grpc v1.76.0
@@ -14,7 +16,6 @@ require (
 )
 
 require (
-	github.com/caarlos0/env/v11 v11.3.1 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
@@ -6,12 +6,13 @@ import (
 	"io"
 	"log"
 
+	"github.com/nrf24l01/sniffly/capture_receiver/batch"
 	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
 	"github.com/nrf24l01/sniffly/capture_receiver/rabbit"
 )
 
 func (s *PacketGatewayServer) PublishPacket(ctx context.Context, pkt *pb.Packet) (*pb.PublishResponse, error) {
-	msg := rabbit.NewMessage(pkt.Payload, pkt.Timestamp, pkt.SourceId, s.RMQTopic)
+	msg := rabbit.NewPacketMessage(pkt, s.RMQTopic)
 	if err := msg.ToRabbitMQMessage(s.RMQ, ctx, false); err != nil {
 		return nil, fmt.Errorf("failed to publish message to RabbitMQ: %w", err)
 	}
@@ -31,7 +32,7 @@ func (s *PacketGatewayServer) StreamPackets(stream pb.PacketGateway_StreamPacket
 		if err != nil {
 			return err
 		}
-		msg := rabbit.NewMessage(pkt.Payload, pkt.Timestamp, pkt.SourceId, s.RMQTopic)
+		msg := rabbit.NewPacketMessage(pkt, s.RMQTopic)
 		if err := msg.ToRabbitMQMessage(s.RMQ, ctx, false); err != nil {
 			return fmt.Errorf("failed to publish message to RabbitMQ: %w", err)
 		}
@@ -41,4 +42,55 @@ func (s *PacketGatewayServer) StreamPackets(stream pb.PacketGateway_StreamPacket
 			return err
 		}
 	}
-}
\ No newline at end of file
+}
+func (s *PacketGatewayServer) Negotiate(ctx context.Context, req *pb.NegotiateRequest) (*pb.NegotiateResponse, error) {
+	allowed, err := batch.ParseCompressions(s.Config.CaptureConfig.BatchCompressions)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch compressions config: %w", err)
+	}
+	compression := batch.Choose(req.Compressions, allowed)
+	log.Printf("[Negotiate] %s offered %v, using %v", req.SourceId, req.Compressions, compression)
+	return &pb.NegotiateResponse{Compression: compression}, nil
+}
+
+func (s *PacketGatewayServer) StreamBatches(stream pb.PacketGateway_StreamBatchesServer) error {
+	ctx := stream.Context()
+	log.Printf("[Batch] Started receiving batches...")
+	for {
+		b, err := stream.Recv()
+		if err == io.EOF {
+			log.Println("[Batch] End of stream")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		packets, err := batch.Decode(b)
+		if err != nil {
+			// A corrupt batch won't decode on resend either, report and move on
+			log.Printf("[Batch] failed to decode batch %d from %s: %v", b.Sequence, b.SourceId, err)
+			resp := &pb.BatchResponse{Sequence: b.Sequence, Count: b.Count, Success: false, Error: err.Error()}
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, pkt := range packets {
+			if pkt.SourceId == "" {
+				pkt.SourceId = b.SourceId
+			}
+		}
+		// One publish per batch rather than per packet
+		msg := rabbit.NewBatchMessage(packets, s.RMQTopic)
+		if err := msg.ToRabbitMQMessage(s.RMQ, ctx, false); err != nil {
+			return fmt.Errorf("failed to publish batch to RabbitMQ: %w", err)
+		}
+
+		resp := &pb.BatchResponse{Sequence: b.Sequence, Count: b.Count, Success: true}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
@@ -21,11 +21,201 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type PacketType int32
+
+const (
+	PacketType_PACKET_TYPE_HTTP  PacketType = 0
+	PacketType_PACKET_TYPE_TLS   PacketType = 1
+	PacketType_PACKET_TYPE_DNS   PacketType = 2
+	PacketType_PACKET_TYPE_FTP   PacketType = 3
+	PacketType_PACKET_TYPE_TCP   PacketType = 4
+	PacketType_PACKET_TYPE_UDP   PacketType = 5
+	PacketType_PACKET_TYPE_QUIC  PacketType = 6
+	PacketType_PACKET_TYPE_DHCP  PacketType = 7
+	PacketType_PACKET_TYPE_MDNS  PacketType = 8
+	PacketType_PACKET_TYPE_SSDP  PacketType = 9
+	PacketType_PACKET_TYPE_MQTT  PacketType = 10
+	PacketType_PACKET_TYPE_COAP  PacketType = 11
+	PacketType_PACKET_TYPE_ICMP  PacketType = 12
+	PacketType_PACKET_TYPE_ARP   PacketType = 13
+	PacketType_PACKET_TYPE_IPSEC PacketType = 14
+	PacketType_PACKET_TYPE_IP    PacketType = 15 // Прочие протоколы поверх IP (IGMP, GRE, ...)
+)
+
+// Enum value maps for PacketType.
+var (
+	PacketType_name = map[int32]string{
+		0:  "PACKET_TYPE_HTTP",
+		1:  "PACKET_TYPE_TLS",
+		2:  "PACKET_TYPE_DNS",
+		3:  "PACKET_TYPE_FTP",
+		4:  "PACKET_TYPE_TCP",
+		5:  "PACKET_TYPE_UDP",
+		6:  "PACKET_TYPE_QUIC",
+		7:  "PACKET_TYPE_DHCP",
+		8:  "PACKET_TYPE_MDNS",
+		9:  "PACKET_TYPE_SSDP",
+		10: "PACKET_TYPE_MQTT",
+		11: "PACKET_TYPE_COAP",
+		12: "PACKET_TYPE_ICMP",
+		13: "PACKET_TYPE_ARP",
+		14: "PACKET_TYPE_IPSEC",
+		15: "PACKET_TYPE_IP",
+	}
+	PacketType_value = map[string]int32{
+		"PACKET_TYPE_HTTP":  0,
+		"PACKET_TYPE_TLS":   1,
+		"PACKET_TYPE_DNS":   2,
+		"PACKET_TYPE_FTP":   3,
+		"PACKET_TYPE_TCP":   4,
+		"PACKET_TYPE_UDP":   5,
+		"PACKET_TYPE_QUIC":  6,
+		"PACKET_TYPE_DHCP":  7,
+		"PACKET_TYPE_MDNS":  8,
+		"PACKET_TYPE_SSDP":  9,
+		"PACKET_TYPE_MQTT":  10,
+		"PACKET_TYPE_COAP":  11,
+		"PACKET_TYPE_ICMP":  12,
+		"PACKET_TYPE_ARP":   13,
+		"PACKET_TYPE_IPSEC": 14,
+		"PACKET_TYPE_IP":    15,
+	}
+)
+
+func (x PacketType) Enum() *PacketType {
+	p := new(PacketType)
+	*p = x
+	return p
+}
+
+func (x PacketType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PacketType) Descriptor() protoreflect.EnumDescriptor {
+	return file_capture_proto_enumTypes[0].Descriptor()
+}
+
+func (PacketType) Type() protoreflect.EnumType {
+	return &file_capture_proto_enumTypes[0]
+}
+
+func (x PacketType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PacketType.Descriptor instead.
+func (PacketType) EnumDescriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{0}
+}
+
+type PacketDirection int32
+
+const (
+	PacketDirection_PACKET_DIRECTION_UNSPECIFIED PacketDirection = 0
+	PacketDirection_PACKET_DIRECTION_UP          PacketDirection = 1
+	PacketDirection_PACKET_DIRECTION_DOWN        PacketDirection = 2
+	PacketDirection_PACKET_DIRECTION_LAN         PacketDirection = 3 // Оба адреса в домашних сетях
+)
+
+// Enum value maps for PacketDirection.
+var (
+	PacketDirection_name = map[int32]string{
+		0: "PACKET_DIRECTION_UNSPECIFIED",
+		1: "PACKET_DIRECTION_UP",
+		2: "PACKET_DIRECTION_DOWN",
+		3: "PACKET_DIRECTION_LAN",
+	}
+	PacketDirection_value = map[string]int32{
+		"PACKET_DIRECTION_UNSPECIFIED": 0,
+		"PACKET_DIRECTION_UP":          1,
+		"PACKET_DIRECTION_DOWN":        2,
+		"PACKET_DIRECTION_LAN":         3,
+	}
+)
+
+func (x PacketDirection) Enum() *PacketDirection {
+	p := new(PacketDirection)
+	*p = x
+	return p
+}
+
+func (x PacketDirection) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PacketDirection) Descriptor() protoreflect.EnumDescriptor {
+	return file_capture_proto_enumTypes[1].Descriptor()
+}
+
+func (PacketDirection) Type() protoreflect.EnumType {
+	return &file_capture_proto_enumTypes[1]
+}
+
+func (x PacketDirection) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PacketDirection.Descriptor instead.
+func (PacketDirection) EnumDescriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{1}
+}
+
+type Compression int32
+
+const (
+	Compression_COMPRESSION_NONE Compression = 0
+	Compression_COMPRESSION_GZIP Compression = 1
+	Compression_COMPRESSION_ZSTD Compression = 2
+)
+
+// Enum value maps for Compression.
+var (
+	Compression_name = map[int32]string{
+		0: "COMPRESSION_NONE",
+		1: "COMPRESSION_GZIP",
+		2: "COMPRESSION_ZSTD",
+	}
+	Compression_value = map[string]int32{
+		"COMPRESSION_NONE": 0,
+		"COMPRESSION_GZIP": 1,
+		"COMPRESSION_ZSTD": 2,
+	}
+)
+
+func (x Compression) Enum() *Compression {
+	p := new(Compression)
+	*p = x
+	return p
+}
+
+func (x Compression) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Compression) Descriptor() protoreflect.EnumDescriptor {
+	return file_capture_proto_enumTypes[2].Descriptor()
+}
+
+func (Compression) Type() protoreflect.EnumType {
+	return &file_capture_proto_enumTypes[2]
+}
+
+func (x Compression) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Compression.Descriptor instead.
+func (Compression) EnumDescriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{2}
+}
+
 type Packet struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"` // Идентификатор клиента или сенсора
-	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`                   // Сырые данные пакета
+	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`                   // Сырые данные пакета (устаревший JSON)
 	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`              // Unix timestamp, когда пакет был создан
+	Packet        *CapturedPacket        `protobuf:"bytes,4,opt,name=packet,proto3" json:"packet,omitempty"`                     // Типизированный пакет, заменяет payload
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,29 +271,44 @@ func (x *Packet) GetTimestamp() int64 {
 	return 0
 }
 
-type PublishResponse struct {
+func (x *Packet) GetPacket() *CapturedPacket {
+	if x != nil {
+		return x.Packet
+	}
+	return nil
+}
+
+type HTTPDetails struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // ID сообщения в RabbitMQ (или сгенерированный UUID)
-	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`                          // Текст ошибки, если success == false
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Host          string                 `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Body          string                 `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"` // Всегда пустое, тело не сохраняется
+	Sni           string                 `protobuf:"bytes,5,opt,name=sni,proto3" json:"sni,omitempty"`
+	IsResponse    bool                   `protobuf:"varint,6,opt,name=is_response,json=isResponse,proto3" json:"is_response,omitempty"` // У ответов есть только status_code и заголовки
+	StatusCode    int32                  `protobuf:"varint,7,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	UserAgent     string                 `protobuf:"bytes,8,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	ContentType   string                 `protobuf:"bytes,9,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	ContentLength int64                  `protobuf:"varint,10,opt,name=content_length,json=contentLength,proto3" json:"content_length,omitempty"`
+	BodySize      int64                  `protobuf:"varint,11,opt,name=body_size,json=bodySize,proto3" json:"body_size,omitempty"` // Только при HTTP_BODY_SIZES=true
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PublishResponse) Reset() {
-	*x = PublishResponse{}
+func (x *HTTPDetails) Reset() {
+	*x = HTTPDetails{}
 	mi := &file_capture_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PublishResponse) String() string {
+func (x *HTTPDetails) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PublishResponse) ProtoMessage() {}
+func (*HTTPDetails) ProtoMessage() {}
 
-func (x *PublishResponse) ProtoReflect() protoreflect.Message {
+func (x *HTTPDetails) ProtoReflect() protoreflect.Message {
 	mi := &file_capture_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -115,49 +320,2598 @@ func (x *PublishResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
-func (*PublishResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use HTTPDetails.ProtoReflect.Descriptor instead.
+func (*HTTPDetails) Descriptor() ([]byte, []int) {
 	return file_capture_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *PublishResponse) GetSuccess() bool {
+func (x *HTTPDetails) GetMethod() string {
 	if x != nil {
-		return x.Success
+		return x.Method
+	}
+	return ""
+}
+
+func (x *HTTPDetails) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *HTTPDetails) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *HTTPDetails) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *HTTPDetails) GetSni() string {
+	if x != nil {
+		return x.Sni
+	}
+	return ""
+}
+
+func (x *HTTPDetails) GetIsResponse() bool {
+	if x != nil {
+		return x.IsResponse
 	}
 	return false
 }
 
-func (x *PublishResponse) GetMessageId() string {
+func (x *HTTPDetails) GetStatusCode() int32 {
 	if x != nil {
-		return x.MessageId
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *HTTPDetails) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
 	}
 	return ""
 }
 
-func (x *PublishResponse) GetError() string {
+func (x *HTTPDetails) GetContentType() string {
 	if x != nil {
-		return x.Error
+		return x.ContentType
 	}
 	return ""
 }
 
-var File_capture_proto protoreflect.FileDescriptor
+func (x *HTTPDetails) GetContentLength() int64 {
+	if x != nil {
+		return x.ContentLength
+	}
+	return 0
+}
 
-const file_capture_proto_rawDesc = "" +
-	"\n" +
-	"\rcapture.proto\x12\x10capture_receiver\"]\n" +
-	"\x06Packet\x12\x1b\n" +
-	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x18\n" +
-	"\apayload\x18\x02 \x01(\fR\apayload\x12\x1c\n" +
-	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"`\n" +
-	"\x0fPublishResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
-	"\n" +
-	"message_id\x18\x02 \x01(\tR\tmessageId\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error2\xaf\x01\n" +
+func (x *HTTPDetails) GetBodySize() int64 {
+	if x != nil {
+		return x.BodySize
+	}
+	return 0
+}
+
+type TLSDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sni           string                 `protobuf:"bytes,1,opt,name=sni,proto3" json:"sni,omitempty"`
+	TlsVersion    string                 `protobuf:"bytes,2,opt,name=tls_version,json=tlsVersion,proto3" json:"tls_version,omitempty"` // Из supported_versions, если расширение есть
+	Ja3           string                 `protobuf:"bytes,3,opt,name=ja3,proto3" json:"ja3,omitempty"`
+	Ja4           string                 `protobuf:"bytes,4,opt,name=ja4,proto3" json:"ja4,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TLSDetails) Reset() {
+	*x = TLSDetails{}
+	mi := &file_capture_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TLSDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TLSDetails) ProtoMessage() {}
+
+func (x *TLSDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TLSDetails.ProtoReflect.Descriptor instead.
+func (*TLSDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *TLSDetails) GetSni() string {
+	if x != nil {
+		return x.Sni
+	}
+	return ""
+}
+
+func (x *TLSDetails) GetTlsVersion() string {
+	if x != nil {
+		return x.TlsVersion
+	}
+	return ""
+}
+
+func (x *TLSDetails) GetJa3() string {
+	if x != nil {
+		return x.Ja3
+	}
+	return ""
+}
+
+func (x *TLSDetails) GetJa4() string {
+	if x != nil {
+		return x.Ja4
+	}
+	return ""
+}
+
+type TLSCertificate struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Subject            string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Issuer             string                 `protobuf:"bytes,2,opt,name=issuer,proto3" json:"issuer,omitempty"`
+	Sans               []string               `protobuf:"bytes,3,rep,name=sans,proto3" json:"sans,omitempty"` // DNS-имена и IP-адреса
+	NotBefore          int64                  `protobuf:"varint,4,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	NotAfter           int64                  `protobuf:"varint,5,opt,name=not_after,json=notAfter,proto3" json:"not_after,omitempty"`
+	SelfSigned         bool                   `protobuf:"varint,6,opt,name=self_signed,json=selfSigned,proto3" json:"self_signed,omitempty"`
+	SignatureAlgorithm string                 `protobuf:"bytes,7,opt,name=signature_algorithm,json=signatureAlgorithm,proto3" json:"signature_algorithm,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *TLSCertificate) Reset() {
+	*x = TLSCertificate{}
+	mi := &file_capture_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TLSCertificate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TLSCertificate) ProtoMessage() {}
+
+func (x *TLSCertificate) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TLSCertificate.ProtoReflect.Descriptor instead.
+func (*TLSCertificate) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TLSCertificate) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *TLSCertificate) GetIssuer() string {
+	if x != nil {
+		return x.Issuer
+	}
+	return ""
+}
+
+func (x *TLSCertificate) GetSans() []string {
+	if x != nil {
+		return x.Sans
+	}
+	return nil
+}
+
+func (x *TLSCertificate) GetNotBefore() int64 {
+	if x != nil {
+		return x.NotBefore
+	}
+	return 0
+}
+
+func (x *TLSCertificate) GetNotAfter() int64 {
+	if x != nil {
+		return x.NotAfter
+	}
+	return 0
+}
+
+func (x *TLSCertificate) GetSelfSigned() bool {
+	if x != nil {
+		return x.SelfSigned
+	}
+	return false
+}
+
+func (x *TLSCertificate) GetSignatureAlgorithm() string {
+	if x != nil {
+		return x.SignatureAlgorithm
+	}
+	return ""
+}
+
+// Выбор сервера из ServerHello; сертификат виден только до TLS 1.2
+type TLSServerDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	CipherSuite   uint32                 `protobuf:"varint,2,opt,name=cipher_suite,json=cipherSuite,proto3" json:"cipher_suite,omitempty"`
+	Cipher        string                 `protobuf:"bytes,3,opt,name=cipher,proto3" json:"cipher,omitempty"`
+	Alpn          string                 `protobuf:"bytes,4,opt,name=alpn,proto3" json:"alpn,omitempty"`
+	Certificate   *TLSCertificate        `protobuf:"bytes,5,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TLSServerDetails) Reset() {
+	*x = TLSServerDetails{}
+	mi := &file_capture_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TLSServerDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TLSServerDetails) ProtoMessage() {}
+
+func (x *TLSServerDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TLSServerDetails.ProtoReflect.Descriptor instead.
+func (*TLSServerDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TLSServerDetails) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *TLSServerDetails) GetCipherSuite() uint32 {
+	if x != nil {
+		return x.CipherSuite
+	}
+	return 0
+}
+
+func (x *TLSServerDetails) GetCipher() string {
+	if x != nil {
+		return x.Cipher
+	}
+	return ""
+}
+
+func (x *TLSServerDetails) GetAlpn() string {
+	if x != nil {
+		return x.Alpn
+	}
+	return ""
+}
+
+func (x *TLSServerDetails) GetCertificate() *TLSCertificate {
+	if x != nil {
+		return x.Certificate
+	}
+	return nil
+}
+
+type DNSQuestion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // QTYPE, например A, AAAA, HTTPS
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSQuestion) Reset() {
+	*x = DNSQuestion{}
+	mi := &file_capture_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSQuestion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSQuestion) ProtoMessage() {}
+
+func (x *DNSQuestion) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSQuestion.ProtoReflect.Descriptor instead.
+func (*DNSQuestion) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DNSQuestion) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DNSQuestion) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type DNSAnswer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Ttl           uint32                 `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Data          string                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`                      // Адрес, CNAME или SVCB в текстовом виде
+	IpHints       []string               `protobuf:"bytes,5,rep,name=ip_hints,json=ipHints,proto3" json:"ip_hints,omitempty"` // ipv4hint/ipv6hint из SVCB и HTTPS
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSAnswer) Reset() {
+	*x = DNSAnswer{}
+	mi := &file_capture_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSAnswer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSAnswer) ProtoMessage() {}
+
+func (x *DNSAnswer) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSAnswer.ProtoReflect.Descriptor instead.
+func (*DNSAnswer) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DNSAnswer) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DNSAnswer) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DNSAnswer) GetTtl() uint32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *DNSAnswer) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *DNSAnswer) GetIpHints() []string {
+	if x != nil {
+		return x.IpHints
+	}
+	return nil
+}
+
+type DNSDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queries       []string               `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+	IsQuery       bool                   `protobuf:"varint,2,opt,name=is_query,json=isQuery,proto3" json:"is_query,omitempty"`
+	Id            uint32                 `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Questions     []*DNSQuestion         `protobuf:"bytes,4,rep,name=questions,proto3" json:"questions,omitempty"`
+	Answers       []*DNSAnswer           `protobuf:"bytes,5,rep,name=answers,proto3" json:"answers,omitempty"`
+	Rcode         string                 `protobuf:"bytes,6,opt,name=rcode,proto3" json:"rcode,omitempty"` // Код ответа, например NOERROR или NXDOMAIN
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSDetails) Reset() {
+	*x = DNSDetails{}
+	mi := &file_capture_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSDetails) ProtoMessage() {}
+
+func (x *DNSDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSDetails.ProtoReflect.Descriptor instead.
+func (*DNSDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DNSDetails) GetQueries() []string {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+func (x *DNSDetails) GetIsQuery() bool {
+	if x != nil {
+		return x.IsQuery
+	}
+	return false
+}
+
+func (x *DNSDetails) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DNSDetails) GetQuestions() []*DNSQuestion {
+	if x != nil {
+		return x.Questions
+	}
+	return nil
+}
+
+func (x *DNSDetails) GetAnswers() []*DNSAnswer {
+	if x != nil {
+		return x.Answers
+	}
+	return nil
+}
+
+func (x *DNSDetails) GetRcode() string {
+	if x != nil {
+		return x.Rcode
+	}
+	return ""
+}
+
+type QUICDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sni           string                 `protobuf:"bytes,1,opt,name=sni,proto3" json:"sni,omitempty"`
+	Alpn          []string               `protobuf:"bytes,2,rep,name=alpn,proto3" json:"alpn,omitempty"`
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"` // v1, v2 или draft-29
+	Ja3           string                 `protobuf:"bytes,4,opt,name=ja3,proto3" json:"ja3,omitempty"`
+	Ja4           string                 `protobuf:"bytes,5,opt,name=ja4,proto3" json:"ja4,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QUICDetails) Reset() {
+	*x = QUICDetails{}
+	mi := &file_capture_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QUICDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QUICDetails) ProtoMessage() {}
+
+func (x *QUICDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QUICDetails.ProtoReflect.Descriptor instead.
+func (*QUICDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QUICDetails) GetSni() string {
+	if x != nil {
+		return x.Sni
+	}
+	return ""
+}
+
+func (x *QUICDetails) GetAlpn() []string {
+	if x != nil {
+		return x.Alpn
+	}
+	return nil
+}
+
+func (x *QUICDetails) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *QUICDetails) GetJa3() string {
+	if x != nil {
+		return x.Ja3
+	}
+	return ""
+}
+
+func (x *QUICDetails) GetJa4() string {
+	if x != nil {
+		return x.Ja4
+	}
+	return ""
+}
+
+// Сообщение клиента DHCPv4 или DHCPv6
+type DHCPDetails struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Version          uint32                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`                           // 4 или 6
+	MessageType      string                 `protobuf:"bytes,2,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // Например Discover, Request, Solicit
+	ClientMac        string                 `protobuf:"bytes,3,opt,name=client_mac,json=clientMac,proto3" json:"client_mac,omitempty"`
+	ClientIp         string                 `protobuf:"bytes,4,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	RequestedIp      string                 `protobuf:"bytes,5,opt,name=requested_ip,json=requestedIp,proto3" json:"requested_ip,omitempty"`
+	Hostname         string                 `protobuf:"bytes,6,opt,name=hostname,proto3" json:"hostname,omitempty"`                                           // Опция 12 или Client FQDN
+	VendorClass      string                 `protobuf:"bytes,7,opt,name=vendor_class,json=vendorClass,proto3" json:"vendor_class,omitempty"`                  // Опция 60 или Vendor Class
+	ParamRequestList string                 `protobuf:"bytes,8,opt,name=param_request_list,json=paramRequestList,proto3" json:"param_request_list,omitempty"` // Коды опции 55 или ORO через запятую
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DHCPDetails) Reset() {
+	*x = DHCPDetails{}
+	mi := &file_capture_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DHCPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DHCPDetails) ProtoMessage() {}
+
+func (x *DHCPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DHCPDetails.ProtoReflect.Descriptor instead.
+func (*DHCPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DHCPDetails) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *DHCPDetails) GetMessageType() string {
+	if x != nil {
+		return x.MessageType
+	}
+	return ""
+}
+
+func (x *DHCPDetails) GetClientMac() string {
+	if x != nil {
+		return x.ClientMac
+	}
+	return ""
+}
+
+func (x *DHCPDetails) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *DHCPDetails) GetRequestedIp() string {
+	if x != nil {
+		return x.RequestedIp
+	}
+	return ""
+}
+
+func (x *DHCPDetails) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *DHCPDetails) GetVendorClass() string {
+	if x != nil {
+		return x.VendorClass
+	}
+	return ""
+}
+
+func (x *DHCPDetails) GetParamRequestList() string {
+	if x != nil {
+		return x.ParamRequestList
+	}
+	return ""
+}
+
+// Что ответ mDNS сообщает об отправителе
+type MDNSDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hostname      string                 `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Services      []string               `protobuf:"bytes,2,rep,name=services,proto3" json:"services,omitempty"`   // Типы сервисов, например _ipp._tcp
+	Instances     []string               `protobuf:"bytes,3,rep,name=instances,proto3" json:"instances,omitempty"` // Имена экземпляров сервисов
+	Model         string                 `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`         // Из TXT-ключей md, usb_MDL и т.п.
+	Manufacturer  string                 `protobuf:"bytes,5,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	Version       string                 `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MDNSDetails) Reset() {
+	*x = MDNSDetails{}
+	mi := &file_capture_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MDNSDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MDNSDetails) ProtoMessage() {}
+
+func (x *MDNSDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MDNSDetails.ProtoReflect.Descriptor instead.
+func (*MDNSDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MDNSDetails) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *MDNSDetails) GetServices() []string {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+func (x *MDNSDetails) GetInstances() []string {
+	if x != nil {
+		return x.Instances
+	}
+	return nil
+}
+
+func (x *MDNSDetails) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *MDNSDetails) GetManufacturer() string {
+	if x != nil {
+		return x.Manufacturer
+	}
+	return ""
+}
+
+func (x *MDNSDetails) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type SSDPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"` // NOTIFY, M-SEARCH или RESPONSE
+	Server        string                 `protobuf:"bytes,2,opt,name=server,proto3" json:"server,omitempty"`
+	UserAgent     string                 `protobuf:"bytes,3,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	Usn           string                 `protobuf:"bytes,4,opt,name=usn,proto3" json:"usn,omitempty"`
+	Location      string                 `protobuf:"bytes,5,opt,name=location,proto3" json:"location,omitempty"`
+	Target        string                 `protobuf:"bytes,6,opt,name=target,proto3" json:"target,omitempty"` // Заголовок NT или ST
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SSDPDetails) Reset() {
+	*x = SSDPDetails{}
+	mi := &file_capture_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SSDPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SSDPDetails) ProtoMessage() {}
+
+func (x *SSDPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SSDPDetails.ProtoReflect.Descriptor instead.
+func (*SSDPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SSDPDetails) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *SSDPDetails) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *SSDPDetails) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *SSDPDetails) GetUsn() string {
+	if x != nil {
+		return x.Usn
+	}
+	return ""
+}
+
+func (x *SSDPDetails) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *SSDPDetails) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+// Управляющие пакеты MQTT одного сегмента; учётные данные не передаются
+type MQTTDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Types         []string               `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"` // CONNECT, PUBLISH, SUBSCRIBE
+	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	ProtocolLevel uint32                 `protobuf:"varint,3,opt,name=protocol_level,json=protocolLevel,proto3" json:"protocol_level,omitempty"`
+	KeepAlive     uint32                 `protobuf:"varint,4,opt,name=keep_alive,json=keepAlive,proto3" json:"keep_alive,omitempty"`
+	HasUsername   bool                   `protobuf:"varint,5,opt,name=has_username,json=hasUsername,proto3" json:"has_username,omitempty"`
+	HasPassword   bool                   `protobuf:"varint,6,opt,name=has_password,json=hasPassword,proto3" json:"has_password,omitempty"`
+	Published     []string               `protobuf:"bytes,7,rep,name=published,proto3" json:"published,omitempty"`
+	Subscribed    []string               `protobuf:"bytes,8,rep,name=subscribed,proto3" json:"subscribed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MQTTDetails) Reset() {
+	*x = MQTTDetails{}
+	mi := &file_capture_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MQTTDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MQTTDetails) ProtoMessage() {}
+
+func (x *MQTTDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MQTTDetails.ProtoReflect.Descriptor instead.
+func (*MQTTDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *MQTTDetails) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *MQTTDetails) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *MQTTDetails) GetProtocolLevel() uint32 {
+	if x != nil {
+		return x.ProtocolLevel
+	}
+	return 0
+}
+
+func (x *MQTTDetails) GetKeepAlive() uint32 {
+	if x != nil {
+		return x.KeepAlive
+	}
+	return 0
+}
+
+func (x *MQTTDetails) GetHasUsername() bool {
+	if x != nil {
+		return x.HasUsername
+	}
+	return false
+}
+
+func (x *MQTTDetails) GetHasPassword() bool {
+	if x != nil {
+		return x.HasPassword
+	}
+	return false
+}
+
+func (x *MQTTDetails) GetPublished() []string {
+	if x != nil {
+		return x.Published
+	}
+	return nil
+}
+
+func (x *MQTTDetails) GetSubscribed() []string {
+	if x != nil {
+		return x.Subscribed
+	}
+	return nil
+}
+
+type CoAPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`     // CON, NON, ACK или RST
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"` // GET, POST, ... или код ответа, например 2.05
+	UriHost       string                 `protobuf:"bytes,3,opt,name=uri_host,json=uriHost,proto3" json:"uri_host,omitempty"`
+	UriPath       string                 `protobuf:"bytes,4,opt,name=uri_path,json=uriPath,proto3" json:"uri_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoAPDetails) Reset() {
+	*x = CoAPDetails{}
+	mi := &file_capture_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoAPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoAPDetails) ProtoMessage() {}
+
+func (x *CoAPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoAPDetails.ProtoReflect.Descriptor instead.
+func (*CoAPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CoAPDetails) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CoAPDetails) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *CoAPDetails) GetUriHost() string {
+	if x != nil {
+		return x.UriHost
+	}
+	return ""
+}
+
+func (x *CoAPDetails) GetUriPath() string {
+	if x != nil {
+		return x.UriPath
+	}
+	return ""
+}
+
+// Команда или ответ управляющего соединения FTP, либо сегмент соединения
+// данных; пароли заменяются на ***
+type FTPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Command       string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Args          string                 `protobuf:"bytes,2,opt,name=args,proto3" json:"args,omitempty"`
+	ResponseCode  uint32                 `protobuf:"varint,3,opt,name=response_code,json=responseCode,proto3" json:"response_code,omitempty"` // Код ответа сервера вместо команды
+	DataEndpoint  string                 `protobuf:"bytes,4,opt,name=data_endpoint,json=dataEndpoint,proto3" json:"data_endpoint,omitempty"`  // Адрес из PORT/EPRT или ответа на PASV/EPSV
+	Data          bool                   `protobuf:"varint,5,opt,name=data,proto3" json:"data,omitempty"`                                     // Сегмент соединения данных
+	Session       string                 `protobuf:"bytes,6,opt,name=session,proto3" json:"session,omitempty"`                                // Управляющее соединение, "client:port>server:port"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FTPDetails) Reset() {
+	*x = FTPDetails{}
+	mi := &file_capture_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FTPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FTPDetails) ProtoMessage() {}
+
+func (x *FTPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FTPDetails.ProtoReflect.Descriptor instead.
+func (*FTPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FTPDetails) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *FTPDetails) GetArgs() string {
+	if x != nil {
+		return x.Args
+	}
+	return ""
+}
+
+func (x *FTPDetails) GetResponseCode() uint32 {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return 0
+}
+
+func (x *FTPDetails) GetDataEndpoint() string {
+	if x != nil {
+		return x.DataEndpoint
+	}
+	return ""
+}
+
+func (x *FTPDetails) GetData() bool {
+	if x != nil {
+		return x.Data
+	}
+	return false
+}
+
+func (x *FTPDetails) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+// Сообщение ICMP или ICMPv6; для обнаружения соседей (NDP) также целевой
+// адрес и MAC из опции канального уровня
+type ICMPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint32                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"` // 4 или 6
+	Type          uint32                 `protobuf:"varint,2,opt,name=type,proto3" json:"type,omitempty"`
+	Code          uint32                 `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"` // echo-request, neighbor-solicitation, ...
+	TargetIp      string                 `protobuf:"bytes,5,opt,name=target_ip,json=targetIp,proto3" json:"target_ip,omitempty"`
+	LinkMac       string                 `protobuf:"bytes,6,opt,name=link_mac,json=linkMac,proto3" json:"link_mac,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ICMPDetails) Reset() {
+	*x = ICMPDetails{}
+	mi := &file_capture_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ICMPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ICMPDetails) ProtoMessage() {}
+
+func (x *ICMPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ICMPDetails.ProtoReflect.Descriptor instead.
+func (*ICMPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ICMPDetails) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ICMPDetails) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *ICMPDetails) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ICMPDetails) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ICMPDetails) GetTargetIp() string {
+	if x != nil {
+		return x.TargetIp
+	}
+	return ""
+}
+
+func (x *ICMPDetails) GetLinkMac() string {
+	if x != nil {
+		return x.LinkMac
+	}
+	return ""
+}
+
+type ARPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operation     string                 `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"` // who-has или is-at
+	SenderMac     string                 `protobuf:"bytes,2,opt,name=sender_mac,json=senderMac,proto3" json:"sender_mac,omitempty"`
+	SenderIp      string                 `protobuf:"bytes,3,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	TargetMac     string                 `protobuf:"bytes,4,opt,name=target_mac,json=targetMac,proto3" json:"target_mac,omitempty"`
+	TargetIp      string                 `protobuf:"bytes,5,opt,name=target_ip,json=targetIp,proto3" json:"target_ip,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ARPDetails) Reset() {
+	*x = ARPDetails{}
+	mi := &file_capture_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ARPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ARPDetails) ProtoMessage() {}
+
+func (x *ARPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ARPDetails.ProtoReflect.Descriptor instead.
+func (*ARPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ARPDetails) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *ARPDetails) GetSenderMac() string {
+	if x != nil {
+		return x.SenderMac
+	}
+	return ""
+}
+
+func (x *ARPDetails) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *ARPDetails) GetTargetMac() string {
+	if x != nil {
+		return x.TargetMac
+	}
+	return ""
+}
+
+func (x *ARPDetails) GetTargetIp() string {
+	if x != nil {
+		return x.TargetIp
+	}
+	return ""
+}
+
+type IPsecDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Protocol      string                 `protobuf:"bytes,1,opt,name=protocol,proto3" json:"protocol,omitempty"` // ESP или AH
+	Spi           uint32                 `protobuf:"varint,2,opt,name=spi,proto3" json:"spi,omitempty"`
+	Seq           uint32                 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IPsecDetails) Reset() {
+	*x = IPsecDetails{}
+	mi := &file_capture_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPsecDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPsecDetails) ProtoMessage() {}
+
+func (x *IPsecDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPsecDetails.ProtoReflect.Descriptor instead.
+func (*IPsecDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *IPsecDetails) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *IPsecDetails) GetSpi() uint32 {
+	if x != nil {
+		return x.Spi
+	}
+	return 0
+}
+
+func (x *IPsecDetails) GetSeq() uint32 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type TCPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TCPDetails) Reset() {
+	*x = TCPDetails{}
+	mi := &file_capture_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TCPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TCPDetails) ProtoMessage() {}
+
+func (x *TCPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TCPDetails.ProtoReflect.Descriptor instead.
+func (*TCPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *TCPDetails) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type UDPDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UDPDetails) Reset() {
+	*x = UDPDetails{}
+	mi := &file_capture_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UDPDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UDPDetails) ProtoMessage() {}
+
+func (x *UDPDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UDPDetails.ProtoReflect.Descriptor instead.
+func (*UDPDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UDPDetails) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type PacketDetails struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Http          *HTTPDetails           `protobuf:"bytes,1,opt,name=http,proto3" json:"http,omitempty"`
+	Tls           *TLSDetails            `protobuf:"bytes,2,opt,name=tls,proto3" json:"tls,omitempty"`
+	Dns           *DNSDetails            `protobuf:"bytes,3,opt,name=dns,proto3" json:"dns,omitempty"`
+	Ftp           *FTPDetails            `protobuf:"bytes,4,opt,name=ftp,proto3" json:"ftp,omitempty"`
+	Tcp           *TCPDetails            `protobuf:"bytes,5,opt,name=tcp,proto3" json:"tcp,omitempty"`
+	Udp           *UDPDetails            `protobuf:"bytes,6,opt,name=udp,proto3" json:"udp,omitempty"`
+	Type          PacketType             `protobuf:"varint,7,opt,name=type,proto3,enum=capture_receiver.PacketType" json:"type,omitempty"`
+	Quic          *QUICDetails           `protobuf:"bytes,8,opt,name=quic,proto3" json:"quic,omitempty"`
+	TlsServer     *TLSServerDetails      `protobuf:"bytes,9,opt,name=tls_server,json=tlsServer,proto3" json:"tls_server,omitempty"`
+	Dhcp          *DHCPDetails           `protobuf:"bytes,10,opt,name=dhcp,proto3" json:"dhcp,omitempty"`
+	Mdns          *MDNSDetails           `protobuf:"bytes,11,opt,name=mdns,proto3" json:"mdns,omitempty"`
+	Ssdp          *SSDPDetails           `protobuf:"bytes,12,opt,name=ssdp,proto3" json:"ssdp,omitempty"`
+	Mqtt          *MQTTDetails           `protobuf:"bytes,13,opt,name=mqtt,proto3" json:"mqtt,omitempty"`
+	Coap          *CoAPDetails           `protobuf:"bytes,14,opt,name=coap,proto3" json:"coap,omitempty"`
+	Icmp          *ICMPDetails           `protobuf:"bytes,15,opt,name=icmp,proto3" json:"icmp,omitempty"`
+	Arp           *ARPDetails            `protobuf:"bytes,16,opt,name=arp,proto3" json:"arp,omitempty"`
+	Ipsec         *IPsecDetails          `protobuf:"bytes,17,opt,name=ipsec,proto3" json:"ipsec,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PacketDetails) Reset() {
+	*x = PacketDetails{}
+	mi := &file_capture_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PacketDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PacketDetails) ProtoMessage() {}
+
+func (x *PacketDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PacketDetails.ProtoReflect.Descriptor instead.
+func (*PacketDetails) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PacketDetails) GetHttp() *HTTPDetails {
+	if x != nil {
+		return x.Http
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetTls() *TLSDetails {
+	if x != nil {
+		return x.Tls
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetDns() *DNSDetails {
+	if x != nil {
+		return x.Dns
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetFtp() *FTPDetails {
+	if x != nil {
+		return x.Ftp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetTcp() *TCPDetails {
+	if x != nil {
+		return x.Tcp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetUdp() *UDPDetails {
+	if x != nil {
+		return x.Udp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetType() PacketType {
+	if x != nil {
+		return x.Type
+	}
+	return PacketType_PACKET_TYPE_HTTP
+}
+
+func (x *PacketDetails) GetQuic() *QUICDetails {
+	if x != nil {
+		return x.Quic
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetTlsServer() *TLSServerDetails {
+	if x != nil {
+		return x.TlsServer
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetDhcp() *DHCPDetails {
+	if x != nil {
+		return x.Dhcp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetMdns() *MDNSDetails {
+	if x != nil {
+		return x.Mdns
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetSsdp() *SSDPDetails {
+	if x != nil {
+		return x.Ssdp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetMqtt() *MQTTDetails {
+	if x != nil {
+		return x.Mqtt
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetCoap() *CoAPDetails {
+	if x != nil {
+		return x.Coap
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetIcmp() *ICMPDetails {
+	if x != nil {
+		return x.Icmp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetArp() *ARPDetails {
+	if x != nil {
+		return x.Arp
+	}
+	return nil
+}
+
+func (x *PacketDetails) GetIpsec() *IPsecDetails {
+	if x != nil {
+		return x.Ipsec
+	}
+	return nil
+}
+
+type Flow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int64                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int64                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	UpBytes       uint64                 `protobuf:"varint,3,opt,name=up_bytes,json=upBytes,proto3" json:"up_bytes,omitempty"`
+	DownBytes     uint64                 `protobuf:"varint,4,opt,name=down_bytes,json=downBytes,proto3" json:"down_bytes,omitempty"`
+	UpPackets     uint64                 `protobuf:"varint,5,opt,name=up_packets,json=upPackets,proto3" json:"up_packets,omitempty"`
+	DownPackets   uint64                 `protobuf:"varint,6,opt,name=down_packets,json=downPackets,proto3" json:"down_packets,omitempty"`
+	EndReason     string                 `protobuf:"bytes,7,opt,name=end_reason,json=endReason,proto3" json:"end_reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Flow) Reset() {
+	*x = Flow{}
+	mi := &file_capture_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Flow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Flow) ProtoMessage() {}
+
+func (x *Flow) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Flow.ProtoReflect.Descriptor instead.
+func (*Flow) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Flow) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Flow) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Flow) GetUpBytes() uint64 {
+	if x != nil {
+		return x.UpBytes
+	}
+	return 0
+}
+
+func (x *Flow) GetDownBytes() uint64 {
+	if x != nil {
+		return x.DownBytes
+	}
+	return 0
+}
+
+func (x *Flow) GetUpPackets() uint64 {
+	if x != nil {
+		return x.UpPackets
+	}
+	return 0
+}
+
+func (x *Flow) GetDownPackets() uint64 {
+	if x != nil {
+		return x.DownPackets
+	}
+	return 0
+}
+
+func (x *Flow) GetEndReason() string {
+	if x != nil {
+		return x.EndReason
+	}
+	return ""
+}
+
+type CapturedPacket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SrcIp         string                 `protobuf:"bytes,1,opt,name=src_ip,json=srcIp,proto3" json:"src_ip,omitempty"`
+	DstIp         string                 `protobuf:"bytes,2,opt,name=dst_ip,json=dstIp,proto3" json:"dst_ip,omitempty"`
+	SrcMac        string                 `protobuf:"bytes,3,opt,name=src_mac,json=srcMac,proto3" json:"src_mac,omitempty"`
+	DstMac        string                 `protobuf:"bytes,4,opt,name=dst_mac,json=dstMac,proto3" json:"dst_mac,omitempty"`
+	SrcPort       string                 `protobuf:"bytes,5,opt,name=src_port,json=srcPort,proto3" json:"src_port,omitempty"`
+	DstPort       string                 `protobuf:"bytes,6,opt,name=dst_port,json=dstPort,proto3" json:"dst_port,omitempty"`
+	Size          int64                  `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"`
+	Protocol      string                 `protobuf:"bytes,8,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Details       *PacketDetails         `protobuf:"bytes,9,opt,name=details,proto3" json:"details,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,10,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Direction     PacketDirection        `protobuf:"varint,11,opt,name=direction,proto3,enum=capture_receiver.PacketDirection" json:"direction,omitempty"`
+	TcpFlags      uint32                 `protobuf:"varint,12,opt,name=tcp_flags,json=tcpFlags,proto3" json:"tcp_flags,omitempty"`
+	Flow          *Flow                  `protobuf:"bytes,13,opt,name=flow,proto3" json:"flow,omitempty"`                             // Заполнено, если это запись потока
+	Interface     string                 `protobuf:"bytes,14,opt,name=interface,proto3" json:"interface,omitempty"`                   // Интерфейс, на котором пойман пакет
+	Vlan          uint32                 `protobuf:"varint,15,opt,name=vlan,proto3" json:"vlan,omitempty"`                            // VLAN ID (внутренний тег при QinQ), 0 без тега
+	OuterVlan     uint32                 `protobuf:"varint,16,opt,name=outer_vlan,json=outerVlan,proto3" json:"outer_vlan,omitempty"` // Внешний тег QinQ
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapturedPacket) Reset() {
+	*x = CapturedPacket{}
+	mi := &file_capture_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturedPacket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturedPacket) ProtoMessage() {}
+
+func (x *CapturedPacket) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturedPacket.ProtoReflect.Descriptor instead.
+func (*CapturedPacket) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CapturedPacket) GetSrcIp() string {
+	if x != nil {
+		return x.SrcIp
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetDstIp() string {
+	if x != nil {
+		return x.DstIp
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetSrcMac() string {
+	if x != nil {
+		return x.SrcMac
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetDstMac() string {
+	if x != nil {
+		return x.DstMac
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetSrcPort() string {
+	if x != nil {
+		return x.SrcPort
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetDstPort() string {
+	if x != nil {
+		return x.DstPort
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *CapturedPacket) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetDetails() *PacketDetails {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *CapturedPacket) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *CapturedPacket) GetDirection() PacketDirection {
+	if x != nil {
+		return x.Direction
+	}
+	return PacketDirection_PACKET_DIRECTION_UNSPECIFIED
+}
+
+func (x *CapturedPacket) GetTcpFlags() uint32 {
+	if x != nil {
+		return x.TcpFlags
+	}
+	return 0
+}
+
+func (x *CapturedPacket) GetFlow() *Flow {
+	if x != nil {
+		return x.Flow
+	}
+	return nil
+}
+
+func (x *CapturedPacket) GetInterface() string {
+	if x != nil {
+		return x.Interface
+	}
+	return ""
+}
+
+func (x *CapturedPacket) GetVlan() uint32 {
+	if x != nil {
+		return x.Vlan
+	}
+	return 0
+}
+
+func (x *CapturedPacket) GetOuterVlan() uint32 {
+	if x != nil {
+		return x.OuterVlan
+	}
+	return 0
+}
+
+// Сообщение в очереди RabbitMQ между capture_receiver и analyzer
+type QueueMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payload       []byte                 `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"` // Устаревший JSON пакета
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	SenderUuid    string                 `protobuf:"bytes,3,opt,name=sender_uuid,json=senderUuid,proto3" json:"sender_uuid,omitempty"`
+	Packet        *CapturedPacket        `protobuf:"bytes,4,opt,name=packet,proto3" json:"packet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueMessage) Reset() {
+	*x = QueueMessage{}
+	mi := &file_capture_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueMessage) ProtoMessage() {}
+
+func (x *QueueMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueueMessage.ProtoReflect.Descriptor instead.
+func (*QueueMessage) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *QueueMessage) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *QueueMessage) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *QueueMessage) GetSenderUuid() string {
+	if x != nil {
+		return x.SenderUuid
+	}
+	return ""
+}
+
+func (x *QueueMessage) GetPacket() *CapturedPacket {
+	if x != nil {
+		return x.Packet
+	}
+	return nil
+}
+
+// Пачка пакетов одним сообщением в очереди, из StreamBatches
+type QueueBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*QueueMessage        `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueBatch) Reset() {
+	*x = QueueBatch{}
+	mi := &file_capture_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueBatch) ProtoMessage() {}
+
+func (x *QueueBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueueBatch.ProtoReflect.Descriptor instead.
+func (*QueueBatch) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *QueueBatch) GetMessages() []*QueueMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type PublishResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"` // ID сообщения в RabbitMQ (или сгенерированный UUID)
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`                          // Текст ошибки, если success == false
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishResponse) Reset() {
+	*x = PublishResponse{}
+	mi := &file_capture_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishResponse) ProtoMessage() {}
+
+func (x *PublishResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
+func (*PublishResponse) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PublishResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *PublishResponse) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *PublishResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type NegotiateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Compressions  []Compression          `protobuf:"varint,2,rep,packed,name=compressions,proto3,enum=capture_receiver.Compression" json:"compressions,omitempty"` // Поддерживаемые клиентом, в порядке предпочтения
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateRequest) Reset() {
+	*x = NegotiateRequest{}
+	mi := &file_capture_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateRequest) ProtoMessage() {}
+
+func (x *NegotiateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateRequest.ProtoReflect.Descriptor instead.
+func (*NegotiateRequest) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *NegotiateRequest) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *NegotiateRequest) GetCompressions() []Compression {
+	if x != nil {
+		return x.Compressions
+	}
+	return nil
+}
+
+type NegotiateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Compression   Compression            `protobuf:"varint,1,opt,name=compression,proto3,enum=capture_receiver.Compression" json:"compression,omitempty"` // Выбранное сервером сжатие для StreamBatches
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateResponse) Reset() {
+	*x = NegotiateResponse{}
+	mi := &file_capture_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateResponse) ProtoMessage() {}
+
+func (x *NegotiateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateResponse.ProtoReflect.Descriptor instead.
+func (*NegotiateResponse) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *NegotiateResponse) GetCompression() Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return Compression_COMPRESSION_NONE
+}
+
+type PacketList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Packets       []*Packet              `protobuf:"bytes,1,rep,name=packets,proto3" json:"packets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PacketList) Reset() {
+	*x = PacketList{}
+	mi := &file_capture_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PacketList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PacketList) ProtoMessage() {}
+
+func (x *PacketList) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PacketList.ProtoReflect.Descriptor instead.
+func (*PacketList) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *PacketList) GetPackets() []*Packet {
+	if x != nil {
+		return x.Packets
+	}
+	return nil
+}
+
+type PacketBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"` // Порядковый номер пакета в рамках потока
+	Count         uint32                 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`       // Количество пакетов в батче
+	Compression   Compression            `protobuf:"varint,4,opt,name=compression,proto3,enum=capture_receiver.Compression" json:"compression,omitempty"`
+	Packets       []byte                 `protobuf:"bytes,5,opt,name=packets,proto3" json:"packets,omitempty"` // Сериализованный PacketList, сжатый compression
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PacketBatch) Reset() {
+	*x = PacketBatch{}
+	mi := &file_capture_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PacketBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PacketBatch) ProtoMessage() {}
+
+func (x *PacketBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PacketBatch.ProtoReflect.Descriptor instead.
+func (*PacketBatch) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *PacketBatch) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *PacketBatch) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *PacketBatch) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *PacketBatch) GetCompression() Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return Compression_COMPRESSION_NONE
+}
+
+func (x *PacketBatch) GetPackets() []byte {
+	if x != nil {
+		return x.Packets
+	}
+	return nil
+}
+
+type BatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Count         uint32                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Success       bool                   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_capture_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_capture_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_capture_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *BatchResponse) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *BatchResponse) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *BatchResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *BatchResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_capture_proto protoreflect.FileDescriptor
+
+const file_capture_proto_rawDesc = "" +
+	"\n" +
+	"\rcapture.proto\x12\x10capture_receiver\"\x97\x01\n" +
+	"\x06Packet\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x128\n" +
+	"\x06packet\x18\x04 \x01(\v2 .capture_receiver.CapturedPacketR\x06packet\"\xbb\x02\n" +
+	"\vHTTPDetails\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x12\n" +
+	"\x04body\x18\x04 \x01(\tR\x04body\x12\x10\n" +
+	"\x03sni\x18\x05 \x01(\tR\x03sni\x12\x1f\n" +
+	"\vis_response\x18\x06 \x01(\bR\n" +
+	"isResponse\x12\x1f\n" +
+	"\vstatus_code\x18\a \x01(\x05R\n" +
+	"statusCode\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\b \x01(\tR\tuserAgent\x12!\n" +
+	"\fcontent_type\x18\t \x01(\tR\vcontentType\x12%\n" +
+	"\x0econtent_length\x18\n" +
+	" \x01(\x03R\rcontentLength\x12\x1b\n" +
+	"\tbody_size\x18\v \x01(\x03R\bbodySize\"c\n" +
+	"\n" +
+	"TLSDetails\x12\x10\n" +
+	"\x03sni\x18\x01 \x01(\tR\x03sni\x12\x1f\n" +
+	"\vtls_version\x18\x02 \x01(\tR\n" +
+	"tlsVersion\x12\x10\n" +
+	"\x03ja3\x18\x03 \x01(\tR\x03ja3\x12\x10\n" +
+	"\x03ja4\x18\x04 \x01(\tR\x03ja4\"\xe4\x01\n" +
+	"\x0eTLSCertificate\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x16\n" +
+	"\x06issuer\x18\x02 \x01(\tR\x06issuer\x12\x12\n" +
+	"\x04sans\x18\x03 \x03(\tR\x04sans\x12\x1d\n" +
+	"\n" +
+	"not_before\x18\x04 \x01(\x03R\tnotBefore\x12\x1b\n" +
+	"\tnot_after\x18\x05 \x01(\x03R\bnotAfter\x12\x1f\n" +
+	"\vself_signed\x18\x06 \x01(\bR\n" +
+	"selfSigned\x12/\n" +
+	"\x13signature_algorithm\x18\a \x01(\tR\x12signatureAlgorithm\"\xbf\x01\n" +
+	"\x10TLSServerDetails\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12!\n" +
+	"\fcipher_suite\x18\x02 \x01(\rR\vcipherSuite\x12\x16\n" +
+	"\x06cipher\x18\x03 \x01(\tR\x06cipher\x12\x12\n" +
+	"\x04alpn\x18\x04 \x01(\tR\x04alpn\x12B\n" +
+	"\vcertificate\x18\x05 \x01(\v2 .capture_receiver.TLSCertificateR\vcertificate\"5\n" +
+	"\vDNSQuestion\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\"t\n" +
+	"\tDNSAnswer\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x10\n" +
+	"\x03ttl\x18\x03 \x01(\rR\x03ttl\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\tR\x04data\x12\x19\n" +
+	"\bip_hints\x18\x05 \x03(\tR\aipHints\"\xdb\x01\n" +
+	"\n" +
+	"DNSDetails\x12\x18\n" +
+	"\aqueries\x18\x01 \x03(\tR\aqueries\x12\x19\n" +
+	"\bis_query\x18\x02 \x01(\bR\aisQuery\x12\x0e\n" +
+	"\x02id\x18\x03 \x01(\rR\x02id\x12;\n" +
+	"\tquestions\x18\x04 \x03(\v2\x1d.capture_receiver.DNSQuestionR\tquestions\x125\n" +
+	"\aanswers\x18\x05 \x03(\v2\x1b.capture_receiver.DNSAnswerR\aanswers\x12\x14\n" +
+	"\x05rcode\x18\x06 \x01(\tR\x05rcode\"q\n" +
+	"\vQUICDetails\x12\x10\n" +
+	"\x03sni\x18\x01 \x01(\tR\x03sni\x12\x12\n" +
+	"\x04alpn\x18\x02 \x03(\tR\x04alpn\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x10\n" +
+	"\x03ja3\x18\x04 \x01(\tR\x03ja3\x12\x10\n" +
+	"\x03ja4\x18\x05 \x01(\tR\x03ja4\"\x96\x02\n" +
+	"\vDHCPDetails\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\rR\aversion\x12!\n" +
+	"\fmessage_type\x18\x02 \x01(\tR\vmessageType\x12\x1d\n" +
+	"\n" +
+	"client_mac\x18\x03 \x01(\tR\tclientMac\x12\x1b\n" +
+	"\tclient_ip\x18\x04 \x01(\tR\bclientIp\x12!\n" +
+	"\frequested_ip\x18\x05 \x01(\tR\vrequestedIp\x12\x1a\n" +
+	"\bhostname\x18\x06 \x01(\tR\bhostname\x12!\n" +
+	"\fvendor_class\x18\a \x01(\tR\vvendorClass\x12,\n" +
+	"\x12param_request_list\x18\b \x01(\tR\x10paramRequestList\"\xb7\x01\n" +
+	"\vMDNSDetails\x12\x1a\n" +
+	"\bhostname\x18\x01 \x01(\tR\bhostname\x12\x1a\n" +
+	"\bservices\x18\x02 \x03(\tR\bservices\x12\x1c\n" +
+	"\tinstances\x18\x03 \x03(\tR\tinstances\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model\x12\"\n" +
+	"\fmanufacturer\x18\x05 \x01(\tR\fmanufacturer\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\tR\aversion\"\xa2\x01\n" +
+	"\vSSDPDetails\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x16\n" +
+	"\x06server\x18\x02 \x01(\tR\x06server\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\x03 \x01(\tR\tuserAgent\x12\x10\n" +
+	"\x03usn\x18\x04 \x01(\tR\x03usn\x12\x1a\n" +
+	"\blocation\x18\x05 \x01(\tR\blocation\x12\x16\n" +
+	"\x06target\x18\x06 \x01(\tR\x06target\"\x8a\x02\n" +
+	"\vMQTTDetails\x12\x14\n" +
+	"\x05types\x18\x01 \x03(\tR\x05types\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12%\n" +
+	"\x0eprotocol_level\x18\x03 \x01(\rR\rprotocolLevel\x12\x1d\n" +
+	"\n" +
+	"keep_alive\x18\x04 \x01(\rR\tkeepAlive\x12!\n" +
+	"\fhas_username\x18\x05 \x01(\bR\vhasUsername\x12!\n" +
+	"\fhas_password\x18\x06 \x01(\bR\vhasPassword\x12\x1c\n" +
+	"\tpublished\x18\a \x03(\tR\tpublished\x12\x1e\n" +
+	"\n" +
+	"subscribed\x18\b \x03(\tR\n" +
+	"subscribed\"o\n" +
+	"\vCoAPDetails\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x19\n" +
+	"\buri_host\x18\x03 \x01(\tR\auriHost\x12\x19\n" +
+	"\buri_path\x18\x04 \x01(\tR\auriPath\"\xb2\x01\n" +
+	"\n" +
+	"FTPDetails\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\tR\acommand\x12\x12\n" +
+	"\x04args\x18\x02 \x01(\tR\x04args\x12#\n" +
+	"\rresponse_code\x18\x03 \x01(\rR\fresponseCode\x12#\n" +
+	"\rdata_endpoint\x18\x04 \x01(\tR\fdataEndpoint\x12\x12\n" +
+	"\x04data\x18\x05 \x01(\bR\x04data\x12\x18\n" +
+	"\asession\x18\x06 \x01(\tR\asession\"\x9b\x01\n" +
+	"\vICMPDetails\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\rR\aversion\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\rR\x04type\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\rR\x04code\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x1b\n" +
+	"\ttarget_ip\x18\x05 \x01(\tR\btargetIp\x12\x19\n" +
+	"\blink_mac\x18\x06 \x01(\tR\alinkMac\"\xa2\x01\n" +
+	"\n" +
+	"ARPDetails\x12\x1c\n" +
+	"\toperation\x18\x01 \x01(\tR\toperation\x12\x1d\n" +
+	"\n" +
+	"sender_mac\x18\x02 \x01(\tR\tsenderMac\x12\x1b\n" +
+	"\tsender_ip\x18\x03 \x01(\tR\bsenderIp\x12\x1d\n" +
+	"\n" +
+	"target_mac\x18\x04 \x01(\tR\ttargetMac\x12\x1b\n" +
+	"\ttarget_ip\x18\x05 \x01(\tR\btargetIp\"N\n" +
+	"\fIPsecDetails\x12\x1a\n" +
+	"\bprotocol\x18\x01 \x01(\tR\bprotocol\x12\x10\n" +
+	"\x03spi\x18\x02 \x01(\rR\x03spi\x12\x10\n" +
+	"\x03seq\x18\x03 \x01(\rR\x03seq\" \n" +
+	"\n" +
+	"TCPDetails\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\" \n" +
+	"\n" +
+	"UDPDetails\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\xf2\x06\n" +
+	"\rPacketDetails\x121\n" +
+	"\x04http\x18\x01 \x01(\v2\x1d.capture_receiver.HTTPDetailsR\x04http\x12.\n" +
+	"\x03tls\x18\x02 \x01(\v2\x1c.capture_receiver.TLSDetailsR\x03tls\x12.\n" +
+	"\x03dns\x18\x03 \x01(\v2\x1c.capture_receiver.DNSDetailsR\x03dns\x12.\n" +
+	"\x03ftp\x18\x04 \x01(\v2\x1c.capture_receiver.FTPDetailsR\x03ftp\x12.\n" +
+	"\x03tcp\x18\x05 \x01(\v2\x1c.capture_receiver.TCPDetailsR\x03tcp\x12.\n" +
+	"\x03udp\x18\x06 \x01(\v2\x1c.capture_receiver.UDPDetailsR\x03udp\x120\n" +
+	"\x04type\x18\a \x01(\x0e2\x1c.capture_receiver.PacketTypeR\x04type\x121\n" +
+	"\x04quic\x18\b \x01(\v2\x1d.capture_receiver.QUICDetailsR\x04quic\x12A\n" +
+	"\n" +
+	"tls_server\x18\t \x01(\v2\".capture_receiver.TLSServerDetailsR\ttlsServer\x121\n" +
+	"\x04dhcp\x18\n" +
+	" \x01(\v2\x1d.capture_receiver.DHCPDetailsR\x04dhcp\x121\n" +
+	"\x04mdns\x18\v \x01(\v2\x1d.capture_receiver.MDNSDetailsR\x04mdns\x121\n" +
+	"\x04ssdp\x18\f \x01(\v2\x1d.capture_receiver.SSDPDetailsR\x04ssdp\x121\n" +
+	"\x04mqtt\x18\r \x01(\v2\x1d.capture_receiver.MQTTDetailsR\x04mqtt\x121\n" +
+	"\x04coap\x18\x0e \x01(\v2\x1d.capture_receiver.CoAPDetailsR\x04coap\x121\n" +
+	"\x04icmp\x18\x0f \x01(\v2\x1d.capture_receiver.ICMPDetailsR\x04icmp\x12.\n" +
+	"\x03arp\x18\x10 \x01(\v2\x1c.capture_receiver.ARPDetailsR\x03arp\x124\n" +
+	"\x05ipsec\x18\x11 \x01(\v2\x1e.capture_receiver.IPsecDetailsR\x05ipsec\"\xc9\x01\n" +
+	"\x04Flow\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x03R\x03end\x12\x19\n" +
+	"\bup_bytes\x18\x03 \x01(\x04R\aupBytes\x12\x1d\n" +
+	"\n" +
+	"down_bytes\x18\x04 \x01(\x04R\tdownBytes\x12\x1d\n" +
+	"\n" +
+	"up_packets\x18\x05 \x01(\x04R\tupPackets\x12!\n" +
+	"\fdown_packets\x18\x06 \x01(\x04R\vdownPackets\x12\x1d\n" +
+	"\n" +
+	"end_reason\x18\a \x01(\tR\tendReason\"\x8a\x04\n" +
+	"\x0eCapturedPacket\x12\x15\n" +
+	"\x06src_ip\x18\x01 \x01(\tR\x05srcIp\x12\x15\n" +
+	"\x06dst_ip\x18\x02 \x01(\tR\x05dstIp\x12\x17\n" +
+	"\asrc_mac\x18\x03 \x01(\tR\x06srcMac\x12\x17\n" +
+	"\adst_mac\x18\x04 \x01(\tR\x06dstMac\x12\x19\n" +
+	"\bsrc_port\x18\x05 \x01(\tR\asrcPort\x12\x19\n" +
+	"\bdst_port\x18\x06 \x01(\tR\adstPort\x12\x12\n" +
+	"\x04size\x18\a \x01(\x03R\x04size\x12\x1a\n" +
+	"\bprotocol\x18\b \x01(\tR\bprotocol\x129\n" +
+	"\adetails\x18\t \x01(\v2\x1f.capture_receiver.PacketDetailsR\adetails\x12\x1c\n" +
+	"\ttimestamp\x18\n" +
+	" \x01(\x03R\ttimestamp\x12?\n" +
+	"\tdirection\x18\v \x01(\x0e2!.capture_receiver.PacketDirectionR\tdirection\x12\x1b\n" +
+	"\ttcp_flags\x18\f \x01(\rR\btcpFlags\x12*\n" +
+	"\x04flow\x18\r \x01(\v2\x16.capture_receiver.FlowR\x04flow\x12\x1c\n" +
+	"\tinterface\x18\x0e \x01(\tR\tinterface\x12\x12\n" +
+	"\x04vlan\x18\x0f \x01(\rR\x04vlan\x12\x1d\n" +
+	"\n" +
+	"outer_vlan\x18\x10 \x01(\rR\touterVlan\"\xa1\x01\n" +
+	"\fQueueMessage\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x1f\n" +
+	"\vsender_uuid\x18\x03 \x01(\tR\n" +
+	"senderUuid\x128\n" +
+	"\x06packet\x18\x04 \x01(\v2 .capture_receiver.CapturedPacketR\x06packet\"H\n" +
+	"\n" +
+	"QueueBatch\x12:\n" +
+	"\bmessages\x18\x01 \x03(\v2\x1e.capture_receiver.QueueMessageR\bmessages\"`\n" +
+	"\x0fPublishResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"r\n" +
+	"\x10NegotiateRequest\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12A\n" +
+	"\fcompressions\x18\x02 \x03(\x0e2\x1d.capture_receiver.CompressionR\fcompressions\"T\n" +
+	"\x11NegotiateResponse\x12?\n" +
+	"\vcompression\x18\x01 \x01(\x0e2\x1d.capture_receiver.CompressionR\vcompression\"@\n" +
+	"\n" +
+	"PacketList\x122\n" +
+	"\apackets\x18\x01 \x03(\v2\x18.capture_receiver.PacketR\apackets\"\xb7\x01\n" +
+	"\vPacketBatch\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x1a\n" +
+	"\bsequence\x18\x02 \x01(\x04R\bsequence\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\rR\x05count\x12?\n" +
+	"\vcompression\x18\x04 \x01(\x0e2\x1d.capture_receiver.CompressionR\vcompression\x12\x18\n" +
+	"\apackets\x18\x05 \x01(\fR\apackets\"q\n" +
+	"\rBatchResponse\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\rR\x05count\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error*\xe5\x02\n" +
+	"\n" +
+	"PacketType\x12\x14\n" +
+	"\x10PACKET_TYPE_HTTP\x10\x00\x12\x13\n" +
+	"\x0fPACKET_TYPE_TLS\x10\x01\x12\x13\n" +
+	"\x0fPACKET_TYPE_DNS\x10\x02\x12\x13\n" +
+	"\x0fPACKET_TYPE_FTP\x10\x03\x12\x13\n" +
+	"\x0fPACKET_TYPE_TCP\x10\x04\x12\x13\n" +
+	"\x0fPACKET_TYPE_UDP\x10\x05\x12\x14\n" +
+	"\x10PACKET_TYPE_QUIC\x10\x06\x12\x14\n" +
+	"\x10PACKET_TYPE_DHCP\x10\a\x12\x14\n" +
+	"\x10PACKET_TYPE_MDNS\x10\b\x12\x14\n" +
+	"\x10PACKET_TYPE_SSDP\x10\t\x12\x14\n" +
+	"\x10PACKET_TYPE_MQTT\x10\n" +
+	"\x12\x14\n" +
+	"\x10PACKET_TYPE_COAP\x10\v\x12\x14\n" +
+	"\x10PACKET_TYPE_ICMP\x10\f\x12\x13\n" +
+	"\x0fPACKET_TYPE_ARP\x10\r\x12\x15\n" +
+	"\x11PACKET_TYPE_IPSEC\x10\x0e\x12\x12\n" +
+	"\x0ePACKET_TYPE_IP\x10\x0f*\x81\x01\n" +
+	"\x0fPacketDirection\x12 \n" +
+	"\x1cPACKET_DIRECTION_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13PACKET_DIRECTION_UP\x10\x01\x12\x19\n" +
+	"\x15PACKET_DIRECTION_DOWN\x10\x02\x12\x18\n" +
+	"\x14PACKET_DIRECTION_LAN\x10\x03*O\n" +
+	"\vCompression\x12\x14\n" +
+	"\x10COMPRESSION_NONE\x10\x00\x12\x14\n" +
+	"\x10COMPRESSION_GZIP\x10\x01\x12\x14\n" +
+	"\x10COMPRESSION_ZSTD\x10\x022\xda\x02\n" +
 	"\rPacketGateway\x12L\n" +
 	"\rPublishPacket\x12\x18.capture_receiver.Packet\x1a!.capture_receiver.PublishResponse\x12P\n" +
-	"\rStreamPackets\x12\x18.capture_receiver.Packet\x1a!.capture_receiver.PublishResponse(\x010\x01B:Z8github.com/nrf24l01/sniffly/capture_receiver/proto;protob\x06proto3"
+	"\rStreamPackets\x12\x18.capture_receiver.Packet\x1a!.capture_receiver.PublishResponse(\x010\x01\x12T\n" +
+	"\tNegotiate\x12\".capture_receiver.NegotiateRequest\x1a#.capture_receiver.NegotiateResponse\x12S\n" +
+	"\rStreamBatches\x12\x1d.capture_receiver.PacketBatch\x1a\x1f.capture_receiver.BatchResponse(\x010\x01B:Z8github.com/nrf24l01/sniffly/capture_receiver/proto;protob\x06proto3"
 
 var (
 	file_capture_proto_rawDescOnce sync.Once
@@ -171,21 +2925,88 @@ func file_capture_proto_rawDescGZIP() []byte {
 	return file_capture_proto_rawDescData
 }
 
-var file_capture_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_capture_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_capture_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
 var file_capture_proto_goTypes = []any{
-	(*Packet)(nil),          // 0: capture_receiver.Packet
-	(*PublishResponse)(nil), // 1: capture_receiver.PublishResponse
+	(PacketType)(0),           // 0: capture_receiver.PacketType
+	(PacketDirection)(0),      // 1: capture_receiver.PacketDirection
+	(Compression)(0),          // 2: capture_receiver.Compression
+	(*Packet)(nil),            // 3: capture_receiver.Packet
+	(*HTTPDetails)(nil),       // 4: capture_receiver.HTTPDetails
+	(*TLSDetails)(nil),        // 5: capture_receiver.TLSDetails
+	(*TLSCertificate)(nil),    // 6: capture_receiver.TLSCertificate
+	(*TLSServerDetails)(nil),  // 7: capture_receiver.TLSServerDetails
+	(*DNSQuestion)(nil),       // 8: capture_receiver.DNSQuestion
+	(*DNSAnswer)(nil),         // 9: capture_receiver.DNSAnswer
+	(*DNSDetails)(nil),        // 10: capture_receiver.DNSDetails
+	(*QUICDetails)(nil),       // 11: capture_receiver.QUICDetails
+	(*DHCPDetails)(nil),       // 12: capture_receiver.DHCPDetails
+	(*MDNSDetails)(nil),       // 13: capture_receiver.MDNSDetails
+	(*SSDPDetails)(nil),       // 14: capture_receiver.SSDPDetails
+	(*MQTTDetails)(nil),       // 15: capture_receiver.MQTTDetails
+	(*CoAPDetails)(nil),       // 16: capture_receiver.CoAPDetails
+	(*FTPDetails)(nil),        // 17: capture_receiver.FTPDetails
+	(*ICMPDetails)(nil),       // 18: capture_receiver.ICMPDetails
+	(*ARPDetails)(nil),        // 19: capture_receiver.ARPDetails
+	(*IPsecDetails)(nil),      // 20: capture_receiver.IPsecDetails
+	(*TCPDetails)(nil),        // 21: capture_receiver.TCPDetails
+	(*UDPDetails)(nil),        // 22: capture_receiver.UDPDetails
+	(*PacketDetails)(nil),     // 23: capture_receiver.PacketDetails
+	(*Flow)(nil),              // 24: capture_receiver.Flow
+	(*CapturedPacket)(nil),    // 25: capture_receiver.CapturedPacket
+	(*QueueMessage)(nil),      // 26: capture_receiver.QueueMessage
+	(*QueueBatch)(nil),        // 27: capture_receiver.QueueBatch
+	(*PublishResponse)(nil),   // 28: capture_receiver.PublishResponse
+	(*NegotiateRequest)(nil),  // 29: capture_receiver.NegotiateRequest
+	(*NegotiateResponse)(nil), // 30: capture_receiver.NegotiateResponse
+	(*PacketList)(nil),        // 31: capture_receiver.PacketList
+	(*PacketBatch)(nil),       // 32: capture_receiver.PacketBatch
+	(*BatchResponse)(nil),     // 33: capture_receiver.BatchResponse
 }
 var file_capture_proto_depIdxs = []int32{
-	0, // 0: capture_receiver.PacketGateway.PublishPacket:input_type -> capture_receiver.Packet
-	0, // 1: capture_receiver.PacketGateway.StreamPackets:input_type -> capture_receiver.Packet
-	1, // 2: capture_receiver.PacketGateway.PublishPacket:output_type -> capture_receiver.PublishResponse
-	1, // 3: capture_receiver.PacketGateway.StreamPackets:output_type -> capture_receiver.PublishResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	25, // 0: capture_receiver.Packet.packet:type_name -> capture_receiver.CapturedPacket
+	6,  // 1: capture_receiver.TLSServerDetails.certificate:type_name -> capture_receiver.TLSCertificate
+	8,  // 2: capture_receiver.DNSDetails.questions:type_name -> capture_receiver.DNSQuestion
+	9,  // 3: capture_receiver.DNSDetails.answers:type_name -> capture_receiver.DNSAnswer
+	4,  // 4: capture_receiver.PacketDetails.http:type_name -> capture_receiver.HTTPDetails
+	5,  // 5: capture_receiver.PacketDetails.tls:type_name -> capture_receiver.TLSDetails
+	10, // 6: capture_receiver.PacketDetails.dns:type_name -> capture_receiver.DNSDetails
+	17, // 7: capture_receiver.PacketDetails.ftp:type_name -> capture_receiver.FTPDetails
+	21, // 8: capture_receiver.PacketDetails.tcp:type_name -> capture_receiver.TCPDetails
+	22, // 9: capture_receiver.PacketDetails.udp:type_name -> capture_receiver.UDPDetails
+	0,  // 10: capture_receiver.PacketDetails.type:type_name -> capture_receiver.PacketType
+	11, // 11: capture_receiver.PacketDetails.quic:type_name -> capture_receiver.QUICDetails
+	7,  // 12: capture_receiver.PacketDetails.tls_server:type_name -> capture_receiver.TLSServerDetails
+	12, // 13: capture_receiver.PacketDetails.dhcp:type_name -> capture_receiver.DHCPDetails
+	13, // 14: capture_receiver.PacketDetails.mdns:type_name -> capture_receiver.MDNSDetails
+	14, // 15: capture_receiver.PacketDetails.ssdp:type_name -> capture_receiver.SSDPDetails
+	15, // 16: capture_receiver.PacketDetails.mqtt:type_name -> capture_receiver.MQTTDetails
+	16, // 17: capture_receiver.PacketDetails.coap:type_name -> capture_receiver.CoAPDetails
+	18, // 18: capture_receiver.PacketDetails.icmp:type_name -> capture_receiver.ICMPDetails
+	19, // 19: capture_receiver.PacketDetails.arp:type_name -> capture_receiver.ARPDetails
+	20, // 20: capture_receiver.PacketDetails.ipsec:type_name -> capture_receiver.IPsecDetails
+	23, // 21: capture_receiver.CapturedPacket.details:type_name -> capture_receiver.PacketDetails
+	1,  // 22: capture_receiver.CapturedPacket.direction:type_name -> capture_receiver.PacketDirection
+	24, // 23: capture_receiver.CapturedPacket.flow:type_name -> capture_receiver.Flow
+	25, // 24: capture_receiver.QueueMessage.packet:type_name -> capture_receiver.CapturedPacket
+	26, // 25: capture_receiver.QueueBatch.messages:type_name -> capture_receiver.QueueMessage
+	2,  // 26: capture_receiver.NegotiateRequest.compressions:type_name -> capture_receiver.Compression
+	2,  // 27: capture_receiver.NegotiateResponse.compression:type_name -> capture_receiver.Compression
+	3,  // 28: capture_receiver.PacketList.packets:type_name -> capture_receiver.Packet
+	2,  // 29: capture_receiver.PacketBatch.compression:type_name -> capture_receiver.Compression
+	3,  // 30: capture_receiver.PacketGateway.PublishPacket:input_type -> capture_receiver.Packet
+	3,  // 31: capture_receiver.PacketGateway.StreamPackets:input_type -> capture_receiver.Packet
+	29, // 32: capture_receiver.PacketGateway.Negotiate:input_type -> capture_receiver.NegotiateRequest
+	32, // 33: capture_receiver.PacketGateway.StreamBatches:input_type -> capture_receiver.PacketBatch
+	28, // 34: capture_receiver.PacketGateway.PublishPacket:output_type -> capture_receiver.PublishResponse
+	28, // 35: capture_receiver.PacketGateway.StreamPackets:output_type -> capture_receiver.PublishResponse
+	30, // 36: capture_receiver.PacketGateway.Negotiate:output_type -> capture_receiver.NegotiateResponse
+	33, // 37: capture_receiver.PacketGateway.StreamBatches:output_type -> capture_receiver.BatchResponse
+	34, // [34:38] is the sub-list for method output_type
+	30, // [30:34] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
 }
 
 func init() { file_capture_proto_init() }
@@ -198,13 +3019,14 @@ func file_capture_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_capture_proto_rawDesc), len(file_capture_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      3,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_capture_proto_goTypes,
 		DependencyIndexes: file_capture_proto_depIdxs,
+		EnumInfos:         file_capture_proto_enumTypes,
 		MessageInfos:      file_capture_proto_msgTypes,
 	}.Build()
 	File_capture_proto = out.File
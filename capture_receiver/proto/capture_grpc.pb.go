@@ -21,6 +21,8 @@ const _ = grpc.SupportPackageIsVersion7
 const (
 	PacketGateway_PublishPacket_FullMethodName = "/capture_receiver.PacketGateway/PublishPacket"
 	PacketGateway_StreamPackets_FullMethodName = "/capture_receiver.PacketGateway/StreamPackets"
+	PacketGateway_Negotiate_FullMethodName     = "/capture_receiver.PacketGateway/Negotiate"
+	PacketGateway_StreamBatches_FullMethodName = "/capture_receiver.PacketGateway/StreamBatches"
 )
 
 // PacketGatewayClient is the client API for PacketGateway service.
@@ -29,6 +31,8 @@ const (
 type PacketGatewayClient interface {
 	PublishPacket(ctx context.Context, in *Packet, opts ...grpc.CallOption) (*PublishResponse, error)
 	StreamPackets(ctx context.Context, opts ...grpc.CallOption) (PacketGateway_StreamPacketsClient, error)
+	Negotiate(ctx context.Context, in *NegotiateRequest, opts ...grpc.CallOption) (*NegotiateResponse, error)
+	StreamBatches(ctx context.Context, opts ...grpc.CallOption) (PacketGateway_StreamBatchesClient, error)
 }
 
 type packetGatewayClient struct {
@@ -79,12 +83,54 @@ func (x *packetGatewayStreamPacketsClient) Recv() (*PublishResponse, error) {
 	return m, nil
 }
 
+func (c *packetGatewayClient) Negotiate(ctx context.Context, in *NegotiateRequest, opts ...grpc.CallOption) (*NegotiateResponse, error) {
+	out := new(NegotiateResponse)
+	err := c.cc.Invoke(ctx, PacketGateway_Negotiate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packetGatewayClient) StreamBatches(ctx context.Context, opts ...grpc.CallOption) (PacketGateway_StreamBatchesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PacketGateway_ServiceDesc.Streams[1], PacketGateway_StreamBatches_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &packetGatewayStreamBatchesClient{stream}
+	return x, nil
+}
+
+type PacketGateway_StreamBatchesClient interface {
+	Send(*PacketBatch) error
+	Recv() (*BatchResponse, error)
+	grpc.ClientStream
+}
+
+type packetGatewayStreamBatchesClient struct {
+	grpc.ClientStream
+}
+
+func (x *packetGatewayStreamBatchesClient) Send(m *PacketBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *packetGatewayStreamBatchesClient) Recv() (*BatchResponse, error) {
+	m := new(BatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // PacketGatewayServer is the server API for PacketGateway service.
 // All implementations must embed UnimplementedPacketGatewayServer
 // for forward compatibility
 type PacketGatewayServer interface {
 	PublishPacket(context.Context, *Packet) (*PublishResponse, error)
 	StreamPackets(PacketGateway_StreamPacketsServer) error
+	Negotiate(context.Context, *NegotiateRequest) (*NegotiateResponse, error)
+	StreamBatches(PacketGateway_StreamBatchesServer) error
 	mustEmbedUnimplementedPacketGatewayServer()
 }
 
@@ -98,6 +144,12 @@ func (UnimplementedPacketGatewayServer) PublishPacket(context.Context, *Packet)
 func (UnimplementedPacketGatewayServer) StreamPackets(PacketGateway_StreamPacketsServer) error {
 	return status.Errorf(codes.Unimplemented, "method StreamPackets not implemented")
 }
+func (UnimplementedPacketGatewayServer) Negotiate(context.Context, *NegotiateRequest) (*NegotiateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Negotiate not implemented")
+}
+func (UnimplementedPacketGatewayServer) StreamBatches(PacketGateway_StreamBatchesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamBatches not implemented")
+}
 func (UnimplementedPacketGatewayServer) mustEmbedUnimplementedPacketGatewayServer() {}
 
 // UnsafePacketGatewayServer may be embedded to opt out of forward compatibility for this service.
@@ -155,6 +207,50 @@ func (x *packetGatewayStreamPacketsServer) Recv() (*Packet, error) {
 	return m, nil
 }
 
+func _PacketGateway_Negotiate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NegotiateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PacketGatewayServer).Negotiate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PacketGateway_Negotiate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PacketGatewayServer).Negotiate(ctx, req.(*NegotiateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PacketGateway_StreamBatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PacketGatewayServer).StreamBatches(&packetGatewayStreamBatchesServer{stream})
+}
+
+type PacketGateway_StreamBatchesServer interface {
+	Send(*BatchResponse) error
+	Recv() (*PacketBatch, error)
+	grpc.ServerStream
+}
+
+type packetGatewayStreamBatchesServer struct {
+	grpc.ServerStream
+}
+
+func (x *packetGatewayStreamBatchesServer) Send(m *BatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *packetGatewayStreamBatchesServer) Recv() (*PacketBatch, error) {
+	m := new(PacketBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // PacketGateway_ServiceDesc is the grpc.ServiceDesc for PacketGateway service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -166,6 +262,10 @@ var PacketGateway_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PublishPacket",
 			Handler:    _PacketGateway_PublishPacket_Handler,
 		},
+		{
+			MethodName: "Negotiate",
+			Handler:    _PacketGateway_Negotiate_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -174,6 +274,12 @@ var PacketGateway_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "StreamBatches",
+			Handler:       _PacketGateway_StreamBatches_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "capture.proto",
 }
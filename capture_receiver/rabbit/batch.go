@@ -0,0 +1,45 @@
+package rabbit
+
+import (
+	"context"
+
+	"github.com/nrf24l01/go-web-utils/rabbitMQ"
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// BatchMessage publishes the packets of a capturer batch as one message,
+// instead of one publish per packet.
+type BatchMessage struct {
+	Messages []*Message
+	topic    *Topic
+}
+
+func NewBatchMessage(pkts []*pb.Packet, topic *Topic) *BatchMessage {
+	msgs := make([]*Message, 0, len(pkts))
+	for _, pkt := range pkts {
+		msgs = append(msgs, NewPacketMessage(pkt, topic))
+	}
+	return &BatchMessage{Messages: msgs, topic: topic}
+}
+
+func (m *BatchMessage) ToRabbitMQMessage(rmq *rabbitMQ.RabbitMQ, ctx context.Context, check_topic bool) error {
+	if check_topic {
+		if err := m.topic.CreateIfNotExists(rmq); err != nil {
+			return err
+		}
+	}
+
+	queueBatch := &pb.QueueBatch{Messages: make([]*pb.QueueMessage, 0, len(m.Messages))}
+	for _, msg := range m.Messages {
+		queueBatch.Messages = append(queueBatch.Messages, msg.queueMessage())
+	}
+	data, err := proto.Marshal(queueBatch)
+	if err != nil {
+		return err
+	}
+
+	pub := amqp.Publishing{ContentType: ContentTypeProtobufBatch, Body: data}
+	return rmq.Channel.PublishWithContext(ctx, "", m.topic.Name, false, false, pub)
+}
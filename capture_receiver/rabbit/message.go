@@ -5,13 +5,25 @@ import (
 	"encoding/json"
 
 	"github.com/nrf24l01/go-web-utils/rabbitMQ"
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	// ContentTypeProtobufBatch is a QueueBatch of several packets.
+	ContentTypeProtobufBatch = "application/x-protobuf; message=QueueBatch"
 )
 
 type Message struct {
 	Payload []byte     `json:"payload"`
 	Timestamp int64    `json:"timestamp"`
 	SenderUUID string  `json:"sender_uuid"`
+	// Packet is set for capturers that send typed packets. Such messages are
+	// published as protobuf, legacy JSON payloads keep the JSON encoding.
+	Packet *pb.CapturedPacket `json:"-"`
 	topic *Topic       `json:"-"`
 }
 
@@ -24,6 +36,12 @@ func NewMessage(payload []byte, timestamp int64, senderUUID string, topic *Topic
 	}
 }
 
+func NewPacketMessage(pkt *pb.Packet, topic *Topic) *Message {
+	msg := NewMessage(pkt.Payload, pkt.Timestamp, pkt.SourceId, topic)
+	msg.Packet = pkt.Packet
+	return msg
+}
+
 func (m *Message) ToRabbitMQMessage(rmq *rabbitMQ.RabbitMQ, ctx context.Context, check_topic bool) error {
 	
 	if check_topic {
@@ -32,19 +50,39 @@ func (m *Message) ToRabbitMQMessage(rmq *rabbitMQ.RabbitMQ, ctx context.Context,
 		}
 	}
 
-	data, err := json.Marshal(m)
+	pub, err := m.encode()
 	if err != nil {
 		return err
 	}
 
-	pub := amqp.Publishing{
-		ContentType: "application/json",
-		Body:        data,
-	}
-
 	if err = rmq.Channel.PublishWithContext(ctx, "", m.topic.Name, false, false, pub); err != nil {
 		return err
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+func (m *Message) encode() (amqp.Publishing, error) {
+	if m.Packet == nil {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return amqp.Publishing{}, err
+		}
+		return amqp.Publishing{ContentType: ContentTypeJSON, Body: data}, nil
+	}
+
+	data, err := proto.Marshal(m.queueMessage())
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	return amqp.Publishing{ContentType: ContentTypeProtobuf, Body: data}, nil
+}
+
+func (m *Message) queueMessage() *pb.QueueMessage {
+	return &pb.QueueMessage{
+		Payload:    m.Payload,
+		Timestamp:  m.Timestamp,
+		SenderUuid: m.SenderUUID,
+		Packet:     m.Packet,
+	}
+}
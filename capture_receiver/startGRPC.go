@@ -41,7 +41,7 @@ func StartGRPCServer(cfg *core.AppConfig, packetGatewayServer *handler.PacketGat
 		return streamInt(srv, ss, info, handler)
 	}
 
-	server := grpc.NewServer(
+	opts := []grpc.ServerOption{
 		grpc.UnaryInterceptor(wrappedUnary),
 		grpc.StreamInterceptor(wrappedStream),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
@@ -51,7 +51,17 @@ func StartGRPCServer(cfg *core.AppConfig, packetGatewayServer *handler.PacketGat
 			Time:                  15 * time.Second,
 			Timeout:               30 * time.Second,
 		}),
-	)
+	}
+
+	creds, err := loadServerCredentials(cfg.CaptureConfig)
+	if err != nil {
+		log.Fatalf("failed to load TLS credentials: %v", err)
+	}
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(opts...)
 	pb.RegisterPacketGatewayServer(server, packetGatewayServer)
 
 	if cfg.CaptureConfig.PingEnabled {
@@ -60,7 +70,7 @@ func StartGRPCServer(cfg *core.AppConfig, packetGatewayServer *handler.PacketGat
 		healthpb.RegisterHealthServer(server, healthServer)
 	}
 
-	log.Printf("gRPC server listening on %s, reflection enabled: %v", cfg.CaptureConfig.AppHost, cfg.CaptureConfig.ReflectionEnabled)
+	log.Printf("gRPC server listening on %s, reflection enabled: %v, tls: %v", cfg.CaptureConfig.AppHost, cfg.CaptureConfig.ReflectionEnabled, creds != nil)
 	if cfg.CaptureConfig.ReflectionEnabled {
 		reflection.Register(server)
 	}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/nrf24l01/sniffly/capture_receiver/core"
+	"google.golang.org/grpc/credentials"
+)
+
+// loadServerCredentials builds the gRPC TLS credentials, or returns nil when
+// no certificate is configured and the server should stay plaintext.
+func loadServerCredentials(cfg *core.CaptureConfig) (credentials.TransportCredentials, error) {
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		if cfg.TLSClientCAFile != "" {
+			return nil, fmt.Errorf("CAPTURE_TLS_CLIENT_CA_FILE requires CAPTURE_TLS_CERT_FILE and CAPTURE_TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.TLSRequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if cfg.TLSRequireClientCert {
+		return nil, fmt.Errorf("CAPTURE_TLS_REQUIRE_CLIENT_CERT requires CAPTURE_TLS_CLIENT_CA_FILE")
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
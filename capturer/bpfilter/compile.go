@@ -0,0 +1,550 @@
+// Package bpfilter compiles tcpdump-style filter expressions into classic
+// BPF programs for Ethernet, Linux cooked and raw IP frames, without
+// libpcap.
+package bpfilter
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/bpf"
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeARP  = 0x0806
+
+	ipProtoICMP   = 1
+	ipProtoTCP    = 6
+	ipProtoUDP    = 17
+	ipProtoICMPv6 = 58
+
+	// Offsets in the IP header
+	ipv4SrcOffset = 12
+	ipv4DstOffset = 16
+	ipv6SrcOffset = 8
+	ipv6DstOffset = 24
+
+	// Snap length returned for accepted packets
+	acceptLen = 262144
+	// Kernel limit for classic BPF programs
+	maxInstructions = 4096
+)
+
+// Link is the link layer the packets of a program start with.
+type Link int
+
+const (
+	// LinkEthernet frames come from AF_PACKET sockets, which move 802.1Q
+	// tags to the socket metadata where `vlan` finds them.
+	LinkEthernet Link = iota
+	// LinkLinuxSLL is the Linux cooked header (DLT_LINUX_SLL), which only
+	// keeps the source MAC.
+	LinkLinuxSLL
+	// LinkRaw packets start at the IP header (DLT_RAW), as read from
+	// SOCK_DGRAM sockets.
+	LinkRaw
+	// LinkAny is for kernel filters on sockets bound to all interfaces,
+	// whose link-layer headers differ. It loads relative to the network
+	// header and takes the protocol from the socket buffer, so it doesn't
+	// run in user space.
+	LinkAny
+	// LinkEthernetTagged frames keep their 802.1Q tags, as in capture files
+	// and tunnelled frames. `vlan` matches the tag in the frame and, as in
+	// tcpdump, moves the primitives after it past the tag.
+	LinkEthernetTagged
+)
+
+// skfNetOff is SKF_NET_OFF, the base of loads relative to the network
+// header.
+const skfNetOff = 0xfff00000
+
+func (l Link) String() string {
+	switch l {
+	case LinkEthernet:
+		return "Ethernet"
+	case LinkEthernetTagged:
+		return "Ethernet with VLAN tags"
+	case LinkLinuxSLL:
+		return "Linux cooked"
+	case LinkRaw:
+		return "raw IP"
+	case LinkAny:
+		return "from all interfaces"
+	}
+	return fmt.Sprintf("Link(%d)", int(l))
+}
+
+// headerLen is the length of the link-layer header, the offset of the IP
+// header.
+func (l Link) headerLen() uint32 {
+	switch l {
+	case LinkEthernet, LinkEthernetTagged:
+		return 14
+	case LinkLinuxSLL:
+		return 16
+	case LinkAny:
+		return skfNetOff
+	}
+	return 0
+}
+
+// Compile compiles expr into a classic BPF program for Ethernet frames. An
+// empty expression accepts every packet.
+func Compile(expr string) ([]bpf.Instruction, error) {
+	return CompileLink(expr, LinkEthernet)
+}
+
+// CompileLink compiles expr into a classic BPF program for packets of the
+// given link layer. Primitives the link layer has no field for, such as
+// ether addresses on raw IP, are an error.
+func CompileLink(expr string, link Link) ([]bpf.Instruction, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return []bpf.Instruction{bpf.RetConstant{Val: acceptLen}}, nil
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+
+	g := generator{link: link}
+	accept, reject := g.newLabel(), g.newLabel()
+	g.gen(root, accept, reject)
+	if g.err != nil {
+		return nil, g.err
+	}
+	g.mark(accept)
+	g.emit(bpf.RetConstant{Val: acceptLen})
+	g.mark(reject)
+	g.emit(bpf.RetConstant{Val: 0})
+
+	prog := g.resolve()
+	if len(prog) > maxInstructions {
+		return nil, fmt.Errorf("filter needs %d instructions, the limit is %d", len(prog), maxInstructions)
+	}
+	return prog, nil
+}
+
+// CompileRaw compiles and assembles expr for Ethernet frames, ready for
+// SetBPF.
+func CompileRaw(expr string) ([]bpf.RawInstruction, error) {
+	return CompileRawLink(expr, LinkEthernet)
+}
+
+// CompileRawLink compiles and assembles expr for packets of the given link
+// layer.
+func CompileRawLink(expr string, link Link) ([]bpf.RawInstruction, error) {
+	prog, err := CompileLink(expr, link)
+	if err != nil {
+		return nil, fmt.Errorf("compile filter %q: %w", expr, err)
+	}
+	return bpf.Assemble(prog)
+}
+
+// Filter tree
+
+type node interface{}
+
+type andNode struct{ a, b node }
+type orNode struct{ a, b node }
+type notNode struct{ a node }
+
+type loadKind int
+
+const (
+	loadAbs loadKind = iota
+	// Relative to the IP header, after the link-layer header
+	loadNet
+	// Relative to the IPv4 payload, using the IHL of the header
+	loadIPv4Payload
+	loadExt
+)
+
+type testNode struct {
+	load  loadKind
+	off   uint32
+	size  int
+	ext   bpf.Extension
+	mask  uint32
+	cond  bpf.JumpTest
+	value uint32
+}
+
+func and(a, b node) node { return andNode{a, b} }
+func or(a, b node) node  { return orNode{a, b} }
+func not(a node) node    { return notNode{a} }
+
+// etherTypeNode matches the network protocol, which each link layer keeps
+// in a different place.
+type etherTypeNode struct{ etherType uint32 }
+
+// etherHostNode matches a MAC address in the link-layer header.
+type etherHostNode struct {
+	dir direction
+	mac net.HardwareAddr
+}
+
+func absTest(off uint32, size int, cond bpf.JumpTest, value uint32) testNode {
+	return testNode{load: loadAbs, off: off, size: size, cond: cond, value: value}
+}
+
+func netTest(off uint32, size int, cond bpf.JumpTest, value uint32) testNode {
+	return testNode{load: loadNet, off: off, size: size, cond: cond, value: value}
+}
+
+func etherType(t uint32) node {
+	return etherTypeNode{t}
+}
+
+func ip4Proto(proto uint32) node {
+	return and(etherType(etherTypeIPv4), netTest(9, 1, bpf.JumpEqual, proto))
+}
+
+func ip6Proto(proto uint32) node {
+	return and(etherType(etherTypeIPv6), netTest(6, 1, bpf.JumpEqual, proto))
+}
+
+func protoOnly(proto string) node {
+	switch proto {
+	case "ip":
+		return etherType(etherTypeIPv4)
+	case "ip6":
+		return etherType(etherTypeIPv6)
+	case "arp":
+		return etherType(etherTypeARP)
+	case "tcp":
+		return or(ip4Proto(ipProtoTCP), ip6Proto(ipProtoTCP))
+	case "udp":
+		return or(ip4Proto(ipProtoUDP), ip6Proto(ipProtoUDP))
+	case "icmp":
+		return ip4Proto(ipProtoICMP)
+	case "icmp6":
+		return ip6Proto(ipProtoICMPv6)
+	}
+	// ether on its own matches every frame
+	return lengthAtLeast(0)
+}
+
+func ip4Addr(off uint32, ip net.IP, mask net.IPMask) node {
+	t := netTest(off, 4, bpf.JumpEqual, be32(ip))
+	if mask != nil {
+		t.mask = be32(mask)
+		if t.mask == 0 {
+			// 0.0.0.0/0
+			return etherType(etherTypeIPv4)
+		}
+		t.value &= t.mask
+	}
+	return t
+}
+
+func ip6Addr(off uint32, ip net.IP, mask net.IPMask) node {
+	var result node
+	for i := 0; i < 4; i++ {
+		t := netTest(off+uint32(4*i), 4, bpf.JumpEqual, be32(ip[4*i:]))
+		if mask != nil {
+			t.mask = be32(mask[4*i:])
+			if t.mask == 0 {
+				continue
+			}
+			t.value &= t.mask
+		}
+		if result == nil {
+			result = t
+		} else {
+			result = and(result, t)
+		}
+	}
+	if result == nil {
+		// ::/0
+		return etherType(etherTypeIPv6)
+	}
+	return result
+}
+
+func etherHost(dir direction, mac net.HardwareAddr) node {
+	return etherHostNode{dir, mac}
+}
+
+func macAt(off uint32, mac net.HardwareAddr) node {
+	return and(
+		absTest(off+2, 4, bpf.JumpEqual, be32(mac[2:])),
+		absTest(off, 2, bpf.JumpEqual, uint32(mac[0])<<8|uint32(mac[1])),
+	)
+}
+
+func portMatch(family string, transports []uint32, dir direction, lo, hi uint32) node {
+	var proto node
+	for _, t := range transports {
+		var n node
+		if family == "ip" {
+			n = ip4Proto(t)
+		} else {
+			n = ip6Proto(t)
+		}
+		if proto == nil {
+			proto = n
+		} else {
+			proto = or(proto, n)
+		}
+	}
+
+	port := func(off uint32) node {
+		t := netTest(40+off, 2, 0, 0)
+		if family == "ip" {
+			t = testNode{load: loadIPv4Payload, off: off, size: 2}
+		}
+		if lo == hi {
+			t.cond, t.value = bpf.JumpEqual, lo
+			return t
+		}
+		upper := t
+		t.cond, t.value = bpf.JumpGreaterOrEqual, lo
+		upper.cond, upper.value = bpf.JumpGreaterThan, hi
+		return and(t, not(upper))
+	}
+
+	match := withDirection(dir, port(0), port(2))
+	if family == "ip" {
+		// Only the first fragment carries the transport header
+		notFragment := not(netTest(6, 2, bpf.JumpBitsSet, 0x1fff))
+		return and(proto, and(notFragment, match))
+	}
+	return and(proto, match)
+}
+
+// vlanNode matches an 802.1Q or 802.1ad tag, with the given ID if hasID.
+type vlanNode struct {
+	id    uint32
+	hasID bool
+}
+
+func vlanPresent() node {
+	return vlanNode{}
+}
+
+func vlanID(id uint32) node {
+	return vlanNode{id: id, hasID: true}
+}
+
+func lengthAtLeast(n uint32) node {
+	return testNode{load: loadExt, ext: bpf.ExtLen, cond: bpf.JumpGreaterOrEqual, value: n}
+}
+
+func be32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// Code generation
+
+type label int
+
+type item struct {
+	ins bpf.Instruction
+
+	// Conditional jump to labels
+	cond        bool
+	test        bpf.JumpTest
+	value       uint32
+	onTrue      label
+	onFalse     label
+	jump        bool
+	jumpTo      label
+	labelMarker bool
+	markedLabel label
+}
+
+type generator struct {
+	items  []item
+	labels int
+	link   Link
+	// shift is the length of the VLAN tags matched so far in the frame,
+	// which move the network header
+	shift uint32
+	err   error
+}
+
+func (g *generator) newLabel() label {
+	g.labels++
+	return label(g.labels)
+}
+
+func (g *generator) mark(l label) {
+	g.items = append(g.items, item{labelMarker: true, markedLabel: l})
+}
+
+func (g *generator) emit(ins bpf.Instruction) {
+	g.items = append(g.items, item{ins: ins})
+}
+
+func (g *generator) gen(n node, onTrue, onFalse label) {
+	switch n := n.(type) {
+	case andNode:
+		next := g.newLabel()
+		g.gen(n.a, next, onFalse)
+		g.mark(next)
+		g.gen(n.b, onTrue, onFalse)
+	case orNode:
+		next := g.newLabel()
+		g.gen(n.a, onTrue, next)
+		g.mark(next)
+		g.gen(n.b, onTrue, onFalse)
+	case notNode:
+		g.gen(n.a, onFalse, onTrue)
+	case etherTypeNode:
+		g.gen(g.etherType(n.etherType), onTrue, onFalse)
+	case etherHostNode:
+		g.gen(g.etherHost(n), onTrue, onFalse)
+	case vlanNode:
+		g.gen(g.vlan(n), onTrue, onFalse)
+		if g.link == LinkEthernetTagged {
+			g.shift += 4
+		}
+	case testNode:
+		switch n.load {
+		case loadAbs:
+			g.emit(bpf.LoadAbsolute{Off: n.off, Size: n.size})
+		case loadNet:
+			g.emit(bpf.LoadAbsolute{Off: g.link.headerLen() + g.shift + n.off, Size: n.size})
+		case loadIPv4Payload:
+			g.emit(bpf.LoadMemShift{Off: g.link.headerLen() + g.shift})
+			g.emit(bpf.LoadIndirect{Off: g.link.headerLen() + g.shift + n.off, Size: n.size})
+		case loadExt:
+			g.emit(bpf.LoadExtension{Num: n.ext})
+		}
+		if n.mask != 0 {
+			g.emit(bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: n.mask})
+		}
+		g.items = append(g.items, item{cond: true, test: n.cond, value: n.value, onTrue: onTrue, onFalse: onFalse})
+	default:
+		panic(fmt.Sprintf("bpfilter: unknown node %T", n))
+	}
+}
+
+// etherType tests the network protocol: the EtherType of Ethernet, the
+// protocol of Linux cooked headers or of the socket buffer, or the IP
+// version of raw IP.
+func (g *generator) etherType(t uint32) node {
+	switch g.link {
+	case LinkEthernet, LinkEthernetTagged:
+		return absTest(12+g.shift, 2, bpf.JumpEqual, t)
+	case LinkLinuxSLL:
+		return absTest(14, 2, bpf.JumpEqual, t)
+	case LinkAny:
+		return testNode{load: loadExt, ext: bpf.ExtProto, cond: bpf.JumpEqual, value: t}
+	}
+	switch t {
+	case etherTypeIPv4:
+		return testNode{load: loadNet, size: 1, mask: 0xf0, cond: bpf.JumpEqual, value: 0x40}
+	case etherTypeIPv6:
+		return testNode{load: loadNet, size: 1, mask: 0xf0, cond: bpf.JumpEqual, value: 0x60}
+	}
+	// Raw IP carries nothing else, e.g. no ARP
+	return not(lengthAtLeast(0))
+}
+
+// etherHost tests a MAC address. Linux cooked headers keep only the
+// source, raw IP none, and for all interfaces it isn't at a fixed offset.
+func (g *generator) etherHost(n etherHostNode) node {
+	switch {
+	case g.link == LinkEthernet || g.link == LinkEthernetTagged:
+		return withDirection(n.dir, macAt(6, n.mac), macAt(0, n.mac))
+	case g.link == LinkLinuxSLL && n.dir == dirSrc:
+		return and(absTest(4, 2, bpf.JumpEqual, 6), macAt(6, n.mac))
+	}
+	if g.err == nil {
+		g.err = fmt.Errorf("ether host %s needs an Ethernet header, packets are %s", n.mac, g.link)
+	}
+	return lengthAtLeast(0)
+}
+
+// vlan tests for a VLAN tag in the frame, after the tags matched before,
+// or in the socket metadata.
+func (g *generator) vlan(n vlanNode) node {
+	if g.link != LinkEthernetTagged {
+		present := testNode{load: loadExt, ext: bpf.ExtVLANTagPresent, cond: bpf.JumpEqual, value: 1}
+		if !n.hasID {
+			return present
+		}
+		return and(present, testNode{load: loadExt, ext: bpf.ExtVLANTag, mask: 0x0fff, cond: bpf.JumpEqual, value: n.id})
+	}
+	tagged := or(absTest(12+g.shift, 2, bpf.JumpEqual, 0x8100), absTest(12+g.shift, 2, bpf.JumpEqual, 0x88a8))
+	if !n.hasID {
+		return tagged
+	}
+	id := absTest(14+g.shift, 2, bpf.JumpEqual, n.id)
+	id.mask = 0x0fff
+	return and(tagged, id)
+}
+
+// resolve lays out the program and turns labels into jump offsets. Classic
+// BPF conditional jumps reach at most 255 instructions forward, so farther
+// targets go through an unconditional jump inserted after the condition.
+func (g *generator) resolve() []bpf.Instruction {
+	for {
+		pos := make(map[label]int)
+		index := make([]int, len(g.items))
+		n := 0
+		for i, it := range g.items {
+			index[i] = n
+			if it.labelMarker {
+				pos[it.markedLabel] = n
+				continue
+			}
+			n++
+		}
+
+		far := -1
+		for i, it := range g.items {
+			if !it.cond {
+				continue
+			}
+			if pos[it.onTrue]-index[i]-1 > 255 || pos[it.onFalse]-index[i]-1 > 255 {
+				far = i
+				break
+			}
+		}
+
+		if far < 0 {
+			prog := make([]bpf.Instruction, 0, n)
+			for i, it := range g.items {
+				switch {
+				case it.labelMarker:
+				case it.cond:
+					prog = append(prog, bpf.JumpIf{
+						Cond:      it.test,
+						Val:       it.value,
+						SkipTrue:  uint8(pos[it.onTrue] - index[i] - 1),
+						SkipFalse: uint8(pos[it.onFalse] - index[i] - 1),
+					})
+				case it.jump:
+					prog = append(prog, bpf.Jump{Skip: uint32(pos[it.jumpTo] - index[i] - 1)})
+				default:
+					prog = append(prog, it.ins)
+				}
+			}
+			return prog
+		}
+
+		it := g.items[far]
+		trueHop, falseHop := g.newLabel(), g.newLabel()
+		trampoline := []item{
+			{labelMarker: true, markedLabel: trueHop},
+			{jump: true, jumpTo: it.onTrue},
+			{labelMarker: true, markedLabel: falseHop},
+			{jump: true, jumpTo: it.onFalse},
+		}
+		g.items[far].onTrue, g.items[far].onFalse = trueHop, falseHop
+		g.items = append(g.items[:far+1], append(trampoline, g.items[far+1:]...)...)
+	}
+}
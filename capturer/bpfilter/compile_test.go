@@ -0,0 +1,261 @@
+package bpfilter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/bpf"
+)
+
+var (
+	testDevMAC    = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x0a}
+	testRouterMAC = net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}
+)
+
+func testIPv4(src, dst string, proto byte, payload []byte) []byte {
+	h := make([]byte, 20)
+	h[0] = 0x45
+	binary.BigEndian.PutUint16(h[2:], uint16(20+len(payload)))
+	h[8] = 64
+	h[9] = proto
+	copy(h[12:], net.ParseIP(src).To4())
+	copy(h[16:], net.ParseIP(dst).To4())
+	return append(h, payload...)
+}
+
+func testIPv6(src, dst string, next byte, payload []byte) []byte {
+	h := make([]byte, 40)
+	h[0] = 0x60
+	binary.BigEndian.PutUint16(h[4:], uint16(len(payload)))
+	h[6] = next
+	h[7] = 64
+	copy(h[8:], net.ParseIP(src).To16())
+	copy(h[24:], net.ParseIP(dst).To16())
+	return append(h, payload...)
+}
+
+// testPorts is the start of a TCP or UDP header.
+func testPorts(src, dst uint16, n int) []byte {
+	h := make([]byte, n)
+	binary.BigEndian.PutUint16(h, src)
+	binary.BigEndian.PutUint16(h[2:], dst)
+	return h
+}
+
+func testTCP(src, dst uint16) []byte { return testPorts(src, dst, 20) }
+func testUDP(src, dst uint16) []byte { return testPorts(src, dst, 8) }
+
+func testEthernet(dst, src net.HardwareAddr, etherType uint16, payload []byte) []byte {
+	f := append(append([]byte{}, dst...), src...)
+	f = binary.BigEndian.AppendUint16(f, etherType)
+	return append(f, payload...)
+}
+
+// testTagged inserts an 802.1Q tag with id into an Ethernet frame.
+func testTagged(frame []byte, id uint16) []byte {
+	f := append([]byte{}, frame[:12]...)
+	f = binary.BigEndian.AppendUint16(f, 0x8100)
+	f = binary.BigEndian.AppendUint16(f, id)
+	return append(f, frame[12:]...)
+}
+
+// testCooked is a Linux cooked header carrying src as source address.
+func testCooked(src net.HardwareAddr, protocol uint16, payload []byte) []byte {
+	h := make([]byte, 16)
+	binary.BigEndian.PutUint16(h[2:], 1) // ARPHRD_ETHER
+	binary.BigEndian.PutUint16(h[4:], uint16(len(src)))
+	copy(h[6:], src)
+	binary.BigEndian.PutUint16(h[14:], protocol)
+	return append(h, payload...)
+}
+
+func runFilter(t *testing.T, expr string, link Link, frame []byte) bool {
+	t.Helper()
+	prog, err := CompileLink(expr, link)
+	if err != nil {
+		t.Fatalf("compile %q for %s: %v", expr, link, err)
+	}
+	if _, err := bpf.Assemble(prog); err != nil {
+		t.Fatalf("assemble %q for %s: %v", expr, link, err)
+	}
+	vm, err := bpf.NewVM(prog)
+	if err != nil {
+		t.Fatalf("load %q for %s: %v", expr, link, err)
+	}
+	n, err := vm.Run(frame)
+	if err != nil {
+		t.Fatalf("run %q for %s: %v", expr, link, err)
+	}
+	return n > 0
+}
+
+func TestCompileMatches(t *testing.T) {
+	https := testIPv4("192.168.1.10", "93.184.216.34", ipProtoTCP, testTCP(50000, 443))
+	dns := testIPv4("192.168.1.10", "192.168.1.1", ipProtoUDP, testUDP(53000, 53))
+	https6 := testIPv6("fd00::10", "2001:db8::1", ipProtoTCP, testTCP(50000, 443))
+	ssh := testIPv4("192.168.1.20", "192.168.1.10", ipProtoTCP, testTCP(22, 41000))
+
+	// The header length comes from IHL, not a fixed 20 bytes
+	options := testIPv4("192.168.1.10", "93.184.216.34", ipProtoTCP, append(make([]byte, 4), testTCP(50000, 8443)...))
+	options[0] = 0x46
+	// A later fragment has no transport header, its bytes aren't ports
+	fragment := testIPv4("192.168.1.10", "93.184.216.34", ipProtoTCP, testTCP(50000, 443))
+	binary.BigEndian.PutUint16(fragment[6:], 0x00b9)
+
+	frames := map[string]struct {
+		link  Link
+		frame []byte
+	}{
+		"eth https":    {LinkEthernet, testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, https)},
+		"eth dns":      {LinkEthernet, testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, dns)},
+		"eth https6":   {LinkEthernet, testEthernet(testRouterMAC, testDevMAC, etherTypeIPv6, https6)},
+		"eth ssh":      {LinkEthernet, testEthernet(testDevMAC, testRouterMAC, etherTypeIPv4, ssh)},
+		"eth options":  {LinkEthernet, testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, options)},
+		"eth fragment": {LinkEthernet, testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, fragment)},
+		"eth arp":      {LinkEthernet, testEthernet(testRouterMAC, testDevMAC, etherTypeARP, make([]byte, 28))},
+		"vlan https":   {LinkEthernetTagged, testTagged(testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, https), 10)},
+		"vlan dns":     {LinkEthernetTagged, testTagged(testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, dns), 20)},
+		"untagged":     {LinkEthernetTagged, testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, https)},
+		"raw https":    {LinkRaw, https},
+		"raw https6":   {LinkRaw, https6},
+		"raw dns":      {LinkRaw, dns},
+		"sll https":    {LinkLinuxSLL, testCooked(testDevMAC, etherTypeIPv4, https)},
+		"sll https6":   {LinkLinuxSLL, testCooked(testDevMAC, etherTypeIPv6, https6)},
+		"sll arp":      {LinkLinuxSLL, testCooked(testRouterMAC, etherTypeARP, make([]byte, 28))},
+	}
+
+	tagged := []Link{LinkEthernetTagged}
+	tests := []struct {
+		expr  string
+		match []string
+		// links limits the frames to those of these links, nil runs all
+		links []Link
+	}{
+		{"", []string{"eth https", "eth dns", "eth https6", "eth ssh", "eth options", "eth fragment", "eth arp", "vlan https", "vlan dns", "untagged", "raw https", "raw https6", "raw dns", "sll https", "sll https6", "sll arp"}, nil},
+		{"ip", []string{"eth https", "eth dns", "eth ssh", "eth options", "eth fragment", "untagged", "raw https", "raw dns", "sll https"}, nil},
+		{"ip6", []string{"eth https6", "raw https6", "sll https6"}, nil},
+		{"arp", []string{"eth arp", "sll arp"}, nil},
+		{"tcp", []string{"eth https", "eth https6", "eth ssh", "eth options", "eth fragment", "untagged", "raw https", "raw https6", "sll https", "sll https6"}, nil},
+		{"udp", []string{"eth dns", "raw dns"}, nil},
+		{"port 443", []string{"eth https", "eth https6", "untagged", "raw https", "raw https6", "sll https", "sll https6"}, nil},
+		{"tcp dst port 8443", []string{"eth options"}, nil},
+		{"udp port 53", []string{"eth dns", "raw dns"}, nil},
+		{"src port 22", []string{"eth ssh"}, nil},
+		{"portrange 440-450", []string{"eth https", "eth https6", "untagged", "raw https", "raw https6", "sll https", "sll https6"}, nil},
+		{"host 93.184.216.34", []string{"eth https", "eth options", "eth fragment", "untagged", "raw https", "sll https"}, nil},
+		{"dst host 192.168.1.10", []string{"eth ssh"}, nil},
+		{"src net 192.168.1.0/24 and dst net 192.168.1.0/24", []string{"eth dns", "eth ssh", "raw dns"}, nil},
+		{"net 2001:db8::/32", []string{"eth https6", "raw https6", "sll https6"}, nil},
+		{"ether src 02:00:00:00:00:0a", []string{"eth https", "eth dns", "eth https6", "eth options", "eth fragment", "eth arp", "vlan https", "vlan dns", "untagged", "sll https", "sll https6"},
+			[]Link{LinkEthernet, LinkEthernetTagged, LinkLinuxSLL}},
+		{"ether host 02:00:00:00:00:01", []string{"eth https", "eth dns", "eth https6", "eth ssh", "eth options", "eth fragment", "eth arp", "vlan https", "vlan dns", "untagged"},
+			[]Link{LinkEthernet, LinkEthernetTagged}},
+		{"greater 56", []string{"eth https6", "eth options", "vlan https", "raw https6", "sll https", "sll https6"}, nil},
+		{"less 42", []string{"eth dns", "eth arp", "raw https", "raw dns"}, nil},
+
+		// Combinations
+		{"not port 22", []string{"eth https", "eth dns", "eth https6", "eth options", "eth fragment", "eth arp", "vlan https", "vlan dns", "untagged", "raw https", "raw https6", "raw dns", "sll https", "sll https6", "sll arp"}, nil},
+		{"not (port 443 or arp)", []string{"eth dns", "eth ssh", "eth options", "eth fragment", "vlan https", "vlan dns", "raw dns"}, nil},
+		{"tcp and not ip6", []string{"eth https", "eth ssh", "eth options", "eth fragment", "untagged", "raw https", "sll https"}, nil},
+		{"udp or host 93.184.216.34", []string{"eth https", "eth dns", "eth options", "eth fragment", "untagged", "raw https", "raw dns", "sll https"}, nil},
+		{"! (tcp && port 443) && ip", []string{"eth dns", "eth ssh", "eth options", "eth fragment", "raw dns"}, nil},
+
+		// Tags in the frame move the primitives after "vlan"
+		{"vlan", []string{"vlan https", "vlan dns"}, tagged},
+		{"vlan 10", []string{"vlan https"}, tagged},
+		{"vlan and tcp port 443", []string{"vlan https"}, tagged},
+		{"vlan 20 and udp and src host 192.168.1.10", []string{"vlan dns"}, tagged},
+		{"port 443 or (vlan and port 443)", []string{"vlan https", "untagged"}, tagged},
+	}
+
+	for _, tt := range tests {
+		want := make(map[string]bool)
+		for _, name := range tt.match {
+			if _, ok := frames[name]; !ok {
+				t.Fatalf("%q: unknown frame %q", tt.expr, name)
+			}
+			want[name] = true
+		}
+		for name, f := range frames {
+			if tt.links != nil && !slices.Contains(tt.links, f.link) {
+				delete(want, name)
+				continue
+			}
+			if got := runFilter(t, tt.expr, f.link, f.frame); got != want[name] {
+				t.Errorf("%q on %s: got %v, want %v", tt.expr, name, got, want[name])
+			}
+		}
+	}
+}
+
+func TestCompileLongJumps(t *testing.T) {
+	// Enough hosts that the jumps to accept and reject exceed 255
+	// instructions and go through trampolines
+	var hosts []string
+	for i := 1; i <= 100; i++ {
+		hosts = append(hosts, fmt.Sprintf("host 10.0.%d.%d", i/50, i))
+	}
+	expr := "tcp and (" + strings.Join(hosts, " or ") + ")"
+
+	prog, err := Compile(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jumps := 0
+	for _, ins := range prog {
+		if _, ok := ins.(bpf.Jump); ok {
+			jumps++
+		}
+	}
+	if len(prog) <= 256 || jumps == 0 {
+		t.Fatalf("%d instructions, %d long jumps; want a program needing trampolines", len(prog), jumps)
+	}
+
+	for _, tt := range []struct {
+		src, dst string
+		proto    byte
+		want     bool
+	}{
+		{"10.0.0.1", "192.168.1.10", ipProtoTCP, true},
+		{"192.168.1.10", "10.0.2.100", ipProtoTCP, true},
+		{"192.168.1.10", "10.0.1.77", ipProtoTCP, true},
+		{"192.168.1.10", "10.0.2.101", ipProtoTCP, false},
+		{"10.0.0.1", "192.168.1.10", ipProtoUDP, false},
+	} {
+		payload := testTCP(50000, 443)
+		frame := testEthernet(testRouterMAC, testDevMAC, etherTypeIPv4, testIPv4(tt.src, tt.dst, tt.proto, payload))
+		if got := runFilter(t, expr, LinkEthernet, frame); got != tt.want {
+			t.Errorf("%s > %s proto %d: got %v, want %v", tt.src, tt.dst, tt.proto, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		link Link
+	}{
+		{"ether host 02:00:00:00:00:0a", LinkRaw},
+		{"ether dst 02:00:00:00:00:0a", LinkLinuxSLL},
+		{"ether src 02:00:00:00:00:0a", LinkAny},
+		{"port 70000", LinkEthernet},
+		{"vlan 5000", LinkEthernet},
+		{"icmp port 1", LinkEthernet},
+		{"tcp and", LinkEthernet},
+		{"(tcp", LinkEthernet},
+		{"bogus", LinkEthernet},
+	} {
+		if _, err := CompileLink(tt.expr, tt.link); err == nil {
+			t.Errorf("%q for %s: compiled, want an error", tt.expr, tt.link)
+		}
+	}
+
+	// Cooked headers keep the source MAC
+	if _, err := CompileLink("ether src 02:00:00:00:00:0a", LinkLinuxSLL); err != nil {
+		t.Errorf("ether src on cooked: %v", err)
+	}
+}
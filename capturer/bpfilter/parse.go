@@ -0,0 +1,420 @@
+package bpfilter
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Expression grammar, a subset of pcap-filter(7):
+//
+//	expr      = term { ("or" | "||") term }
+//	term      = factor { ("and" | "&&") factor }
+//	factor    = ("not" | "!") factor | "(" expr ")" | primitive
+//	primitive = [proto] [dir] [kind] value | proto | "vlan" [id]
+//	          | "greater" len | "less" len
+//
+// proto is one of ether, ip, ip6, arp, tcp, udp, icmp, icmp6; dir is src,
+// dst, "src or dst" or "src and dst"; kind is host, net, port or portrange.
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) peekAt(n int) string {
+	if p.pos+n < len(p.tokens) {
+		return p.tokens[p.pos+n]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf(format, args...)
+}
+
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '!':
+			tokens = append(tokens, "not")
+			i++
+		case strings.HasPrefix(s[i:], "&&"):
+			tokens = append(tokens, "and")
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, "or")
+			i += 2
+		case isWordChar(c):
+			j := i
+			for j < len(s) && isWordChar(s[j]) {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(s[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '.' || c == ':' || c == '/' || c == '-' || c == '_'
+}
+
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = or(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = and(left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (node, error) {
+	switch p.peek() {
+	case "not":
+		p.next()
+		n, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return not(n), nil
+	case "(":
+		p.next()
+		n, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, p.errorf("missing )")
+		}
+		return n, nil
+	case "":
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return p.parsePrimitive()
+}
+
+type direction int
+
+const (
+	dirSrcOrDst direction = iota
+	dirSrc
+	dirDst
+	dirSrcAndDst
+)
+
+var protoNames = map[string]bool{
+	"ether": true, "ip": true, "ip6": true, "arp": true,
+	"tcp": true, "udp": true, "icmp": true, "icmp6": true,
+}
+
+func (p *parser) parsePrimitive() (node, error) {
+	switch p.peek() {
+	case "vlan":
+		p.next()
+		if id, ok := p.peekNumber(); ok {
+			p.next()
+			if id > 4095 {
+				return nil, p.errorf("vlan id %d out of range", id)
+			}
+			return vlanID(uint32(id)), nil
+		}
+		return vlanPresent(), nil
+	case "greater", "less":
+		op := p.next()
+		n, ok := p.peekNumber()
+		if !ok {
+			return nil, p.errorf("%s needs a length", op)
+		}
+		p.next()
+		if op == "greater" {
+			return lengthAtLeast(uint32(n)), nil
+		}
+		return not(lengthAtLeast(uint32(n) + 1)), nil
+	}
+
+	proto := ""
+	if protoNames[p.peek()] {
+		proto = p.next()
+	}
+
+	dir, hasDir := p.parseDirection()
+
+	kind := ""
+	switch p.peek() {
+	case "host", "net", "port", "portrange":
+		kind = p.next()
+	}
+
+	if kind == "" && !hasDir {
+		if proto == "" {
+			return nil, p.errorf("unknown primitive %q", p.peek())
+		}
+		return protoOnly(proto), nil
+	}
+	if kind == "" {
+		kind = "host"
+	}
+
+	value := p.next()
+	if value == "" || value == "(" || value == ")" || value == "and" || value == "or" || value == "not" {
+		return nil, p.errorf("%s needs a value", kind)
+	}
+
+	switch kind {
+	case "host":
+		return p.hostPrimitive(proto, dir, value)
+	case "net":
+		return p.netPrimitive(proto, dir, value)
+	case "port", "portrange":
+		return p.portPrimitive(proto, dir, kind, value)
+	}
+	return nil, p.errorf("unknown primitive %q", kind)
+}
+
+func (p *parser) peekNumber() (uint64, bool) {
+	n, err := strconv.ParseUint(p.peek(), 10, 32)
+	return n, err == nil
+}
+
+func (p *parser) parseDirection() (direction, bool) {
+	switch p.peek() {
+	case "src", "dst":
+		first := p.next()
+		// "src or dst" and "src and dst" qualify a single primitive
+		if (p.peek() == "or" || p.peek() == "and") && (p.peekAt(1) == "src" || p.peekAt(1) == "dst") && p.peekAt(1) != first {
+			op := p.next()
+			p.next()
+			if op == "and" {
+				return dirSrcAndDst, true
+			}
+			return dirSrcOrDst, true
+		}
+		if first == "src" {
+			return dirSrc, true
+		}
+		return dirDst, true
+	}
+	return dirSrcOrDst, false
+}
+
+func (p *parser) hostPrimitive(proto string, dir direction, value string) (node, error) {
+	if proto == "ether" {
+		mac, err := net.ParseMAC(value)
+		if err != nil || len(mac) != 6 {
+			return nil, p.errorf("invalid ethernet address %q", value)
+		}
+		return etherHost(dir, mac), nil
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(value); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		resolved, err := net.LookupIP(value)
+		if err != nil {
+			return nil, p.errorf("unknown host %q: %v", value, err)
+		}
+		ips = resolved
+	}
+
+	var result node
+	for _, ip := range ips {
+		n, err := p.addrPrimitive(proto, dir, ip, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			continue
+		}
+		if result == nil {
+			result = n
+		} else {
+			result = or(result, n)
+		}
+	}
+	if result == nil {
+		return nil, p.errorf("host %q has no %s address", value, proto)
+	}
+	return result, nil
+}
+
+func (p *parser) netPrimitive(proto string, dir direction, value string) (node, error) {
+	_, ipnet, err := net.ParseCIDR(value)
+	if err != nil {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, p.errorf("invalid network %q", value)
+		}
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		ipnet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	n, err := p.addrPrimitive(proto, dir, ipnet.IP, ipnet.Mask)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, p.errorf("network %q does not match %s", value, proto)
+	}
+	return n, nil
+}
+
+// addrPrimitive matches an address or network. It returns nil when the
+// address family contradicts proto.
+func (p *parser) addrPrimitive(proto string, dir direction, ip net.IP, mask net.IPMask) (node, error) {
+	var n node
+	if ip4 := ip.To4(); ip4 != nil {
+		if proto != "" && proto != "ip" && proto != "tcp" && proto != "udp" && proto != "icmp" {
+			return nil, nil
+		}
+		if mask != nil && len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+		n = withDirection(dir, ip4Addr(ipv4SrcOffset, ip4, mask), ip4Addr(ipv4DstOffset, ip4, mask))
+		n = and(etherType(etherTypeIPv4), n)
+	} else {
+		if proto != "" && proto != "ip6" && proto != "tcp" && proto != "udp" && proto != "icmp6" {
+			return nil, nil
+		}
+		n = withDirection(dir, ip6Addr(ipv6SrcOffset, ip.To16(), mask), ip6Addr(ipv6DstOffset, ip.To16(), mask))
+		n = and(etherType(etherTypeIPv6), n)
+	}
+	if proto != "" && proto != "ip" && proto != "ip6" {
+		n = and(protoOnly(proto), n)
+	}
+	return n, nil
+}
+
+func (p *parser) portPrimitive(proto string, dir direction, kind, value string) (node, error) {
+	if proto != "" && proto != "tcp" && proto != "udp" && proto != "ip" && proto != "ip6" {
+		return nil, p.errorf("%s does not have ports", proto)
+	}
+
+	lo, hi, err := parsePortRange(kind, value)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+
+	var families []string
+	switch proto {
+	case "ip", "ip6":
+		families = []string{proto}
+	default:
+		families = []string{"ip", "ip6"}
+	}
+	var transports []uint32
+	switch proto {
+	case "tcp":
+		transports = []uint32{ipProtoTCP}
+	case "udp":
+		transports = []uint32{ipProtoUDP}
+	default:
+		transports = []uint32{ipProtoTCP, ipProtoUDP}
+	}
+
+	var result node
+	for _, family := range families {
+		n := portMatch(family, transports, dir, lo, hi)
+		if result == nil {
+			result = n
+		} else {
+			result = or(result, n)
+		}
+	}
+	return result, nil
+}
+
+func parsePortRange(kind, value string) (uint32, uint32, error) {
+	parse := func(s string) (uint32, error) {
+		n, err := strconv.ParseUint(s, 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port %q", s)
+		}
+		return uint32(n), nil
+	}
+
+	if kind == "port" {
+		n, err := parse(value)
+		return n, n, err
+	}
+
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid port range %q", value)
+	}
+	lo, err := parse(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, err := parse(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi, nil
+}
+
+func withDirection(dir direction, src, dst node) node {
+	switch dir {
+	case dirSrc:
+		return src
+	case dirDst:
+		return dst
+	case dirSrcAndDst:
+		return and(src, dst)
+	}
+	return or(src, dst)
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"log"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env"
 )
@@ -10,6 +12,106 @@ type Config struct {
 	ServerAddress string `env:"SERVER_ADDRESS" envDefault:"localhost:50051"`
 	ApiToken 	  string `env:"API_TOKEN" envDefault:""`
 	Interface     string `env:"INTERFACE" envDefault:"eth0"`
+	// Interfaces captures on several interfaces at once and overrides Interface
+	Interfaces    []string `env:"INTERFACES" envSeparator:","`
+
+	// TLS to the receiver. TLSCAFile replaces the system roots, the client
+	// certificate enables mutual TLS and TLSServerName pins the name the
+	// server certificate must carry.
+	TLSEnabled    bool   `env:"TLS_ENABLED" envDefault:"false"`
+	TLSCAFile     string `env:"TLS_CA_FILE" envDefault:""`
+	TLSCertFile   string `env:"TLS_CERT_FILE" envDefault:""`
+	TLSKeyFile    string `env:"TLS_KEY_FILE" envDefault:""`
+	TLSServerName string `env:"TLS_SERVER_NAME" envDefault:""`
+
+	// PacketEncoding is "proto" for typed packets or "json" for the legacy
+	// JSON payload understood by older receivers and analyzers.
+	PacketEncoding string `env:"PACKET_ENCODING" envDefault:"proto"`
+
+	// BatchEnabled sends packets in compressed batches when the receiver
+	// supports it. A batch is flushed by count, size or age.
+	BatchEnabled     bool          `env:"BATCH_ENABLED" envDefault:"false"`
+	BatchMaxPackets  int           `env:"BATCH_MAX_PACKETS" envDefault:"500"`
+	BatchMaxBytes    int           `env:"BATCH_MAX_BYTES" envDefault:"262144"`
+	BatchMaxDelay    time.Duration `env:"BATCH_MAX_DELAY" envDefault:"1s"`
+	BatchCompression string        `env:"BATCH_COMPRESSION" envDefault:"zstd,gzip"`
+
+	// HomeNets lists the internal networks (CIDR), replacing the interface
+	// addresses. Packets from or to ExcludeNets are dropped. Entries written
+	// as iface=cidr apply to that interface only, the others to interfaces
+	// without their own. TrafficMode is one of outbound, bidirectional, lan
+	// or all.
+	HomeNets    []string `env:"HOME_NETS" envSeparator:","`
+	ExcludeNets []string `env:"EXCLUDE_NETS" envSeparator:","`
+	TrafficMode string   `env:"TRAFFIC_MODE" envDefault:"bidirectional"`
+
+	// ReassemblyEnabled rebuilds the first ReassemblyMaxBytes of HTTP and
+	// TLS client streams so requests split across segments are parsed.
+	ReassemblyEnabled    bool          `env:"REASSEMBLY_ENABLED" envDefault:"true"`
+	ReassemblyMaxBytes   int           `env:"REASSEMBLY_MAX_BYTES" envDefault:"8192"`
+	ReassemblyMaxStreams int           `env:"REASSEMBLY_MAX_STREAMS" envDefault:"4096"`
+	ReassemblyTimeout    time.Duration `env:"REASSEMBLY_TIMEOUT" envDefault:"30s"`
+
+	// HTTPBodySizes reports the body size of HTTP messages. Bodies are
+	// never captured.
+	HTTPBodySizes bool `env:"HTTP_BODY_SIZES" envDefault:"false"`
+
+	// Dissectors limits protocol parsing to the named dissectors, all run
+	// when it is empty. DissectorPorts maps extra ports as name:port, or
+	// drops a default one with name:-port.
+	Dissectors         []string `env:"DISSECTORS" envSeparator:","`
+	DissectorsDisabled []string `env:"DISSECTORS_DISABLED" envSeparator:","`
+	DissectorPorts     []string `env:"DISSECTOR_PORTS" envSeparator:","`
+
+	// BPFFilter is a tcpdump-style expression compiled to classic BPF and
+	// attached to the capture socket. BPFPresets adds built-in filters:
+	// exclude-self (own gRPC traffic) and exclude-lan (LAN-to-LAN).
+	BPFFilter  string   `env:"BPF_FILTER" envDefault:""`
+	BPFPresets []string `env:"BPF_PRESETS" envSeparator:","`
+
+	// Mode selects the packet source: "live" captures from Interface,
+	// "replay" reads frames from ReplayFile (pcap or pcapng), "tunnel"
+	// decapsulates traffic mirrored over GRE/ERSPAN, VXLAN or TZSP.
+	Mode                 string  `env:"MODE" envDefault:"live"`
+	ReplayFile           string  `env:"REPLAY_FILE" envDefault:""`
+	ReplayPacing         string  `env:"REPLAY_PACING" envDefault:"realtime"`
+	ReplaySpeed          float64 `env:"REPLAY_SPEED" envDefault:"1"`
+	ReplayKeepTimestamps bool    `env:"REPLAY_KEEP_TIMESTAMPS" envDefault:"true"`
+
+	// TunnelListen opens sockets as encapsulation:address (gre, vxlan or
+	// tzsp), TunnelInterface takes the tunnels from frames captured on an
+	// interface instead. TunnelLabels names tunnels as ip=label or
+	// ip/id=label, id being the VXLAN VNI or ERSPAN session.
+	TunnelListen    []string `env:"TUNNEL_LISTEN" envSeparator:","`
+	TunnelInterface string   `env:"TUNNEL_INTERFACE" envDefault:""`
+	TunnelLabels    []string `env:"TUNNEL_LABELS" envSeparator:","`
+
+	// SpoolDir enables the on-disk queue between capture and the gRPC sender.
+	SpoolDir          string        `env:"SPOOL_DIR" envDefault:""`
+	SpoolSegmentBytes int64         `env:"SPOOL_SEGMENT_BYTES" envDefault:"8388608"`
+	SpoolSegmentAge   time.Duration `env:"SPOOL_SEGMENT_AGE" envDefault:"1m"`
+	SpoolMaxBytes     int64         `env:"SPOOL_MAX_BYTES" envDefault:"268435456"`
+	SpoolMaxAge       time.Duration `env:"SPOOL_MAX_AGE" envDefault:"72h"`
+
+	// FlowsEnabled sends one record per 5-tuple flow instead of per packet.
+	FlowsEnabled      bool          `env:"FLOWS_ENABLED" envDefault:"false"`
+	FlowIdleTimeout   time.Duration `env:"FLOW_IDLE_TIMEOUT" envDefault:"15s"`
+	FlowActiveTimeout time.Duration `env:"FLOW_ACTIVE_TIMEOUT" envDefault:"60s"`
+	FlowMaxFlows      int           `env:"FLOW_MAX_FLOWS" envDefault:"65536"`
+}
+
+// CaptureInterfaces returns the interfaces to capture on.
+func (c *Config) CaptureInterfaces() []string {
+	var ifaces []string
+	for _, iface := range c.Interfaces {
+		if iface = strings.TrimSpace(iface); iface != "" {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	if len(ifaces) == 0 {
+		return []string{c.Interface}
+	}
+	return ifaces
 }
 
 func LoadConfigFromEnv() *Config {
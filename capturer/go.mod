@@ -9,14 +9,17 @@ require (
 	github.com/gopacket/gopacket v1.4.0
 	github.com/joho/godotenv v1.5.1
 	github.com/nrf24l01/sniffly/capture_receiver v0.0.0-20251114154504-5c88f47c540f
+	golang.org/x/net v0.45.0
 	google.golang.org/grpc v1.76.0
+	google.golang.org/protobuf v1.36.10
 )
 
 require (
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
-	golang.org/x/net v0.45.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
-	google.golang.org/protobuf v1.36.10 // indirect
 )
+
+replace github.com/nrf24l01/sniffly/capture_receiver => ../capture_receiver
@@ -0,0 +1,249 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/nrf24l01/sniffly/capture_receiver/batch"
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
+	"github.com/nrf24l01/sniffly/capturer/core"
+	"github.com/nrf24l01/sniffly/capturer/snifpacket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var errBatchUnsupported = errors.New("receiver does not support batched streams")
+
+// negotiateBatches agrees on the batch compression with the receiver.
+func negotiateBatches(ctx context.Context, client pb.PacketGatewayClient, cfg *core.Config) (pb.Compression, error) {
+	offered, err := batch.ParseCompressions(cfg.BatchCompression)
+	if err != nil {
+		log.Printf("invalid BATCH_COMPRESSION: %v; sending batches uncompressed", err)
+		offered = nil
+	}
+
+	resp, err := client.Negotiate(ctx, &pb.NegotiateRequest{SourceId: snifpacket.ProtoSourceID, Compressions: offered})
+	if status.Code(err) == codes.Unimplemented {
+		return pb.Compression_COMPRESSION_NONE, errBatchUnsupported
+	}
+	if err != nil {
+		return pb.Compression_COMPRESSION_NONE, err
+	}
+	return resp.Compression, nil
+}
+
+// packetBatch collects packets until one of the batch limits is reached.
+type packetBatch struct {
+	packets []*pb.Packet
+	size    int
+	// rejections counts how often the receiver failed to decode the batch.
+	rejections int
+}
+
+func (b *packetBatch) add(p *pb.Packet) {
+	b.packets = append(b.packets, p)
+	b.size += proto.Size(p)
+}
+
+func (b *packetBatch) full(cfg *core.Config) bool {
+	return len(b.packets) >= cfg.BatchMaxPackets || b.size >= cfg.BatchMaxBytes
+}
+
+func (b *packetBatch) reset() {
+	b.packets = nil
+	b.size = 0
+	b.rejections = 0
+}
+
+// Number of times a batch is resent after the receiver rejected it before
+// its packets go over the per-packet stream instead.
+const batchMaxRejections = 3
+
+var errBatchRejected = errors.New("receiver keeps rejecting a batch")
+
+// Maximum number of batches sent but not yet acknowledged by the receiver.
+const batchesInFlight = 16
+
+// undelivered flattens the batches, in order, for the per-packet stream.
+func undelivered(batches ...packetBatch) []*pb.Packet {
+	var packets []*pb.Packet
+	for _, b := range batches {
+		packets = append(packets, b.packets...)
+	}
+	return packets
+}
+
+// streamPacketBatches is the batched variant of StreamPackets. A batch is
+// kept until the receiver acknowledged it and resent after a reconnect. It
+// returns errBatchUnsupported when the receiver predates batched streams and
+// errBatchRejected when it can't decode a batch, together with the packets
+// not delivered yet.
+func streamPacketBatches(client pb.PacketGatewayClient, cfg *core.Config, packets chan *snifpacket.SnifPacket) ([]*pb.Packet, error) {
+	var pending packetBatch
+	var unacked []packetBatch
+	var seq uint64
+	closed := false
+
+	backoff := 1 * time.Second
+	for {
+		ctx, cancel := context.WithCancel(withAuth(context.Background(), cfg.ApiToken))
+		compression, err := negotiateBatches(ctx, client, cfg)
+		if errors.Is(err, errBatchUnsupported) {
+			cancel()
+			return undelivered(append(unacked, pending)...), err
+		}
+		var stream pb.PacketGateway_StreamBatchesClient
+		if err == nil {
+			stream, err = client.StreamBatches(ctx)
+		}
+		if err != nil {
+			cancel()
+			log.Printf("failed to start batch stream: %v; retrying in %s", err, backoff)
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+
+		backoff = 1 * time.Second
+		log.Printf("grpc: batch stream started, compression %v", compression)
+
+		inflight := make(chan packetBatch, batchesInFlight)
+		var rejected *packetBatch
+		acksDone := make(chan struct{})
+		go func() {
+			defer close(acksDone)
+			defer cancel()
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					log.Printf("grpc: server closed response stream")
+					return
+				}
+				if err != nil {
+					log.Printf("grpc: error receiving ack: %v", err)
+					return
+				}
+				var b packetBatch
+				select {
+				case b = <-inflight:
+				case <-ctx.Done():
+					return
+				}
+				if !resp.Success {
+					// Renegotiating may help, the batch goes first after the reconnect
+					log.Printf("grpc: batch %d rejected: %s; will reconnect", resp.Sequence, resp.Error)
+					b.rejections++
+					rejected = &b
+					return
+				}
+			}
+		}()
+
+		timer := time.NewTimer(cfg.BatchMaxDelay)
+		timer.Stop()
+
+		// send hands b to the stream; on error the caller still owns it
+		send := func(b packetBatch) error {
+			seq++
+			encoded, err := batch.Encode(snifpacket.ProtoSourceID, seq, b.packets, compression)
+			if err != nil {
+				log.Printf("failed to encode batch: %v", err)
+				return nil
+			}
+			if err := stream.Send(encoded); err != nil {
+				return err
+			}
+			select {
+			case inflight <- b:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		flush := func() error {
+			timer.Stop()
+			if len(pending.packets) == 0 {
+				return nil
+			}
+			if err := send(pending); err != nil {
+				return err
+			}
+			pending.reset()
+			return nil
+		}
+
+		// Batches left unacknowledged by the previous stream go first
+		for len(unacked) > 0 && err == nil {
+			if err = send(unacked[0]); err == nil {
+				unacked = unacked[1:]
+			}
+		}
+		if err == nil {
+			err = flush()
+		}
+		for err == nil {
+			if closed {
+				if err = flush(); err != nil {
+					break
+				}
+				_ = stream.CloseSend()
+				<-acksDone
+				if rejected == nil && len(inflight) == 0 {
+					log.Printf("StreamPackets: packets channel closed, exiting batch stream")
+					return nil, nil
+				}
+				err = errors.New("stream closed with batches unacknowledged")
+				break
+			}
+
+			select {
+			case pkt, ok := <-packets:
+				if !ok {
+					closed = true
+					continue
+				}
+
+				protoPacket, perr := encodePacket(pkt, cfg.PacketEncoding)
+				if perr != nil {
+					log.Printf("failed to convert packet to proto: %v", perr)
+					continue
+				}
+				if len(pending.packets) == 0 {
+					timer.Reset(cfg.BatchMaxDelay)
+				}
+				pending.add(protoPacket)
+				if pending.full(cfg) {
+					err = flush()
+				}
+			case <-timer.C:
+				err = flush()
+			}
+		}
+
+		log.Printf("failed to send batch to grpc stream: %v; will reconnect", err)
+		timer.Stop()
+		cancel()
+		<-acksDone
+
+		// Everything sent but not acknowledged is resent, in order
+		var requeue []packetBatch
+		if rejected != nil {
+			requeue = append(requeue, *rejected)
+		}
+		for len(inflight) > 0 {
+			requeue = append(requeue, <-inflight)
+		}
+		unacked = append(requeue, unacked...)
+		if rejected != nil && rejected.rejections >= batchMaxRejections {
+			return undelivered(append(unacked, pending)...), errBatchRejected
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
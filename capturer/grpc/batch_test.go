@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nrf24l01/sniffly/capture_receiver/batch"
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
+	"github.com/nrf24l01/sniffly/capturer/core"
+	"github.com/nrf24l01/sniffly/capturer/snifpacket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeGateway answers the n-th batch of the c-th stream with answer.
+type fakeGateway struct {
+	pb.PacketGatewayClient
+	unsupported bool
+	answer      func(c, n int) (*pb.BatchResponse, error)
+
+	mu        sync.Mutex
+	streams   int
+	delivered []int64
+}
+
+func (g *fakeGateway) Negotiate(ctx context.Context, in *pb.NegotiateRequest, opts ...grpc.CallOption) (*pb.NegotiateResponse, error) {
+	if g.unsupported {
+		return nil, status.Error(codes.Unimplemented, "unknown method Negotiate")
+	}
+	return &pb.NegotiateResponse{Compression: pb.Compression_COMPRESSION_NONE}, nil
+}
+
+func (g *fakeGateway) StreamBatches(ctx context.Context, opts ...grpc.CallOption) (pb.PacketGateway_StreamBatchesClient, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.streams++
+	return &fakeBatchStream{ctx: ctx, gateway: g, conn: g.streams, sent: make(chan *pb.PacketBatch, 64)}, nil
+}
+
+type fakeBatchStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	gateway *fakeGateway
+	conn    int
+	n       int
+	sent    chan *pb.PacketBatch
+}
+
+func (s *fakeBatchStream) Send(b *pb.PacketBatch) error {
+	select {
+	case s.sent <- b:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *fakeBatchStream) CloseSend() error {
+	close(s.sent)
+	return nil
+}
+
+func (s *fakeBatchStream) Recv() (*pb.BatchResponse, error) {
+	var b *pb.PacketBatch
+	var ok bool
+	select {
+	case b, ok = <-s.sent:
+		if !ok {
+			return nil, io.EOF
+		}
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+	s.n++
+	resp, err := s.gateway.answer(s.conn, s.n)
+	if err != nil || !resp.Success {
+		return resp, err
+	}
+	packets, err := batch.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	s.gateway.mu.Lock()
+	for _, p := range packets {
+		s.gateway.delivered = append(s.gateway.delivered, p.Timestamp)
+	}
+	s.gateway.mu.Unlock()
+	return resp, nil
+}
+
+func testBatchConfig() *core.Config {
+	return &core.Config{BatchMaxPackets: 2, BatchMaxBytes: 1 << 20, BatchMaxDelay: time.Hour}
+}
+
+func testPackets(n int) chan *snifpacket.SnifPacket {
+	packets := make(chan *snifpacket.SnifPacket, n)
+	for i := 0; i < n; i++ {
+		packets <- &snifpacket.SnifPacket{SrcIP: "192.168.1.2", DstIP: "1.1.1.1", Timestamp: int64(i)}
+	}
+	close(packets)
+	return packets
+}
+
+func TestStreamPacketBatchesResendsUnacknowledged(t *testing.T) {
+	g := &fakeGateway{answer: func(c, n int) (*pb.BatchResponse, error) {
+		switch {
+		case c == 1 && n == 2:
+			// The connection drops before the second batch is answered
+			return nil, errors.New("connection reset")
+		case c == 2 && n == 1:
+			return &pb.BatchResponse{Success: false, Error: "corrupt"}, nil
+		}
+		return &pb.BatchResponse{Success: true}, nil
+	}}
+
+	rest, err := streamPacketBatches(g, testBatchConfig(), testPackets(6))
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("streamPacketBatches = %d packets, %v; want 0, nil", len(rest), err)
+	}
+	if len(g.delivered) != 6 {
+		t.Fatalf("delivered %v, want 6 packets", g.delivered)
+	}
+	for i, ts := range g.delivered {
+		if ts != int64(i) {
+			t.Fatalf("delivered %v, want 0-5 in order", g.delivered)
+		}
+	}
+}
+
+func TestStreamPacketBatchesFallback(t *testing.T) {
+	g := &fakeGateway{answer: func(c, n int) (*pb.BatchResponse, error) {
+		return &pb.BatchResponse{Success: false, Error: "corrupt"}, nil
+	}}
+	packets := testPackets(5)
+	rest, err := streamPacketBatches(g, testBatchConfig(), packets)
+	// Packets not read yet stay in the channel for the per-packet stream
+	if !errors.Is(err, errBatchRejected) || len(rest)+len(packets) != 5 || rest[0].Timestamp != 0 {
+		t.Fatalf("rejected batches: %d packets, %v; want 5, %v", len(rest)+len(packets), err, errBatchRejected)
+	}
+
+	g = &fakeGateway{unsupported: true}
+	rest, err = streamPacketBatches(g, testBatchConfig(), testPackets(3))
+	if !errors.Is(err, errBatchUnsupported) || len(rest) != 0 {
+		t.Fatalf("old receiver: %d packets, %v; want 0, %v", len(rest), err, errBatchUnsupported)
+	}
+}
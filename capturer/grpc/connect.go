@@ -1,11 +1,16 @@
 package grpc
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
 	"time"
 
 	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
 	"github.com/nrf24l01/sniffly/capturer/core"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 )
@@ -19,8 +24,13 @@ func ConnectPacketGatewayClient(cfg *core.Config) (pb.PacketGatewayClient, error
 		PermitWithoutStream: false,
 	}
 
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	conn, err := grpc.NewClient(cfg.ServerAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(kp),
 	)
 	if err != nil {
@@ -29,4 +39,37 @@ func ConnectPacketGatewayClient(cfg *core.Config) (pb.PacketGatewayClient, error
 
 	client := pb.NewPacketGatewayClient(conn)
 	return client, nil
-}
\ No newline at end of file
+}
+
+func transportCredentials(cfg *core.Config) (credentials.TransportCredentials, error) {
+	if !cfg.TLSEnabled {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: cfg.TLSServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -0,0 +1,285 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nrf24l01/sniffly/capture_receiver/batch"
+	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
+	"github.com/nrf24l01/sniffly/capturer/core"
+	"github.com/nrf24l01/sniffly/capturer/snifpacket"
+	"github.com/nrf24l01/sniffly/capturer/spool"
+	"google.golang.org/protobuf/proto"
+)
+
+// Maximum number of packets sent but not yet acknowledged by the receiver.
+const spoolInFlight = 1024
+
+// Maximum number of batches sent but not yet acknowledged by the receiver.
+const spoolBatchesInFlight = 16
+
+// SpoolPackets moves captured packets from the channel into the disk spool.
+func SpoolPackets(packets chan *snifpacket.SnifPacket, cfg *core.Config, sp *spool.Spool, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer sp.CloseWrite()
+
+	for pkt := range packets {
+		protoPacket, err := encodePacket(pkt, cfg.PacketEncoding)
+		if err != nil {
+			log.Printf("failed to convert packet to proto: %v", err)
+			continue
+		}
+		data, err := proto.Marshal(protoPacket)
+		if err != nil {
+			log.Printf("failed to marshal packet for spool: %v", err)
+			continue
+		}
+		if err := sp.Append(data); err != nil {
+			log.Printf("failed to append packet to spool: %v", err)
+		}
+	}
+	log.Printf("SpoolPackets: packets channel closed, spool sealed")
+}
+
+// StreamSpooledPackets drains the spool into the gRPC stream. A record is
+// acknowledged in the spool only after the receiver answered for it, and
+// everything unacknowledged is resent after a reconnect.
+func StreamSpooledPackets(client pb.PacketGatewayClient, cfg *core.Config, sp *spool.Spool, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	if cfg.BatchEnabled {
+		err := streamSpooledBatches(client, cfg, sp)
+		if !errors.Is(err, errBatchUnsupported) {
+			return err
+		}
+		log.Printf("StreamSpooledPackets: %v, falling back to per-packet stream", err)
+	}
+
+	backoff := 1 * time.Second
+	for {
+		ctx, cancel := context.WithCancel(withAuth(context.Background(), cfg.ApiToken))
+		stream, err := client.StreamPackets(ctx)
+		if err != nil {
+			cancel()
+			log.Printf("failed to start packet stream: %v; retrying in %s", err, backoff)
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+
+		backoff = 1 * time.Second
+		sp.Rewind()
+
+		inflight := make(chan spool.Position, spoolInFlight)
+		acksDone := make(chan struct{})
+		go func() {
+			defer close(acksDone)
+			for {
+				_, err := stream.Recv()
+				if err == io.EOF {
+					log.Printf("grpc: server closed response stream")
+					return
+				}
+				if err != nil {
+					log.Printf("grpc: error receiving ack: %v", err)
+					cancel()
+					return
+				}
+				select {
+				case pos := <-inflight:
+					sp.Ack(pos)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		for {
+			rec, err := sp.Next(ctx)
+			if err == io.EOF {
+				_ = stream.CloseSend()
+				<-acksDone
+				cancel()
+				log.Printf("StreamSpooledPackets: spool drained, exiting stream")
+				return nil
+			}
+			if errors.Is(err, spool.ErrClosed) {
+				cancel()
+				return nil
+			}
+			if err != nil {
+				break
+			}
+
+			var protoPacket pb.Packet
+			if err := proto.Unmarshal(rec.Data, &protoPacket); err != nil {
+				log.Printf("failed to decode spooled packet: %v", err)
+				continue
+			}
+
+			if err := stream.Send(&protoPacket); err != nil {
+				log.Printf("failed to send packet to grpc stream: %v; will reconnect", err)
+				break
+			}
+
+			select {
+			case inflight <- rec.Pos:
+				continue
+			case <-ctx.Done():
+			}
+			break
+		}
+
+		cancel()
+		<-acksDone
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// streamSpooledBatches is the batched variant of StreamSpooledPackets. The
+// spool is acknowledged up to the last record of each answered batch.
+func streamSpooledBatches(client pb.PacketGatewayClient, cfg *core.Config, sp *spool.Spool) error {
+	backoff := 1 * time.Second
+	for {
+		ctx, cancel := context.WithCancel(withAuth(context.Background(), cfg.ApiToken))
+		compression, err := negotiateBatches(ctx, client, cfg)
+		if errors.Is(err, errBatchUnsupported) {
+			cancel()
+			return err
+		}
+		var stream pb.PacketGateway_StreamBatchesClient
+		if err == nil {
+			stream, err = client.StreamBatches(ctx)
+		}
+		if err != nil {
+			cancel()
+			log.Printf("failed to start batch stream: %v; retrying in %s", err, backoff)
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+
+		backoff = 1 * time.Second
+		sp.Rewind()
+		log.Printf("grpc: spooled batch stream started, compression %v", compression)
+
+		inflight := make(chan spool.Position, spoolBatchesInFlight)
+		acksDone := make(chan struct{})
+		go func() {
+			defer close(acksDone)
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					log.Printf("grpc: server closed response stream")
+					return
+				}
+				if err != nil {
+					log.Printf("grpc: error receiving ack: %v", err)
+					cancel()
+					return
+				}
+				if !resp.Success {
+					// Resending won't fix a batch the receiver can't decode
+					log.Printf("grpc: batch %d rejected: %s", resp.Sequence, resp.Error)
+				}
+				select {
+				case pos := <-inflight:
+					sp.Ack(pos)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		var pending packetBatch
+		var last spool.Position
+		var seq uint64
+		var deadline time.Time
+
+		flush := func() error {
+			if len(pending.packets) == 0 {
+				return nil
+			}
+			seq++
+			b, err := batch.Encode(snifpacket.ProtoSourceID, seq, pending.packets, compression)
+			pending.reset()
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(b); err != nil {
+				return err
+			}
+			select {
+			case inflight <- last:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		for {
+			readCtx, readCancel := ctx, context.CancelFunc(func() {})
+			if len(pending.packets) > 0 {
+				readCtx, readCancel = context.WithDeadline(ctx, deadline)
+			}
+			rec, err := sp.Next(readCtx)
+			readCancel()
+
+			if err == io.EOF {
+				if err := flush(); err != nil {
+					log.Printf("failed to send batch to grpc stream: %v; will reconnect", err)
+					break
+				}
+				_ = stream.CloseSend()
+				<-acksDone
+				cancel()
+				log.Printf("StreamSpooledPackets: spool drained, exiting batch stream")
+				return nil
+			}
+			if errors.Is(err, spool.ErrClosed) {
+				cancel()
+				return nil
+			}
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				if err := flush(); err != nil {
+					log.Printf("failed to send batch to grpc stream: %v; will reconnect", err)
+					break
+				}
+				continue
+			}
+			if err != nil {
+				break
+			}
+
+			var protoPacket pb.Packet
+			if err := proto.Unmarshal(rec.Data, &protoPacket); err != nil {
+				log.Printf("failed to decode spooled packet: %v", err)
+				continue
+			}
+			if len(pending.packets) == 0 {
+				deadline = time.Now().Add(cfg.BatchMaxDelay)
+			}
+			pending.add(&protoPacket)
+			last = rec.Pos
+
+			if pending.full(cfg) {
+				if err := flush(); err != nil {
+					log.Printf("failed to send batch to grpc stream: %v; will reconnect", err)
+					break
+				}
+			}
+		}
+
+		cancel()
+		<-acksDone
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log"
 	"sync"
@@ -15,6 +16,17 @@ import (
 func StreamPackets(client pb.PacketGatewayClient, cfg *core.Config, packets chan *snifpacket.SnifPacket, wg *sync.WaitGroup) error {
 	defer wg.Done()
 
+	// Packets the batched stream didn't deliver go first
+	var backlog []*pb.Packet
+	if cfg.BatchEnabled {
+		var err error
+		backlog, err = streamPacketBatches(client, cfg, packets)
+		if !errors.Is(err, errBatchUnsupported) && !errors.Is(err, errBatchRejected) {
+			return err
+		}
+		log.Printf("StreamPackets: %v, falling back to per-packet stream", err)
+	}
+
 	backoff := 1 * time.Second
 	for {
 		stream, err := client.StreamPackets(withAuth(context.Background(), cfg.ApiToken))
@@ -29,7 +41,9 @@ func StreamPackets(client pb.PacketGatewayClient, cfg *core.Config, packets chan
 
 		backoff = 1 * time.Second
 
+		acksDone := make(chan struct{})
 		go func() {
+			defer close(acksDone)
 			for {
 				_, err := stream.Recv()
 				if err == io.EOF {
@@ -44,14 +58,26 @@ func StreamPackets(client pb.PacketGatewayClient, cfg *core.Config, packets chan
 		}()
 
 		for {
+			if len(backlog) > 0 {
+				if err := stream.Send(backlog[0]); err != nil {
+					log.Printf("failed to send packet to grpc stream: %v; will reconnect", err)
+					_ = stream.CloseSend()
+					break
+				}
+				backlog = backlog[1:]
+				continue
+			}
+
 			pkt, ok := <-packets
 			if !ok {
 				_ = stream.CloseSend()
+				// Wait for the server to drain the stream so a finished replay is fully delivered
+				<-acksDone
 				log.Printf("StreamPackets: packets channel closed, exiting stream")
 				return nil
 			}
 
-			protoPacket, err := pkt.ToProto()
+			protoPacket, err := encodePacket(pkt, cfg.PacketEncoding)
 			if err != nil {
 				log.Printf("failed to convert packet to proto: %v", err)
 				continue
@@ -66,4 +92,11 @@ func StreamPackets(client pb.PacketGatewayClient, cfg *core.Config, packets chan
 
 		time.Sleep(500 * time.Millisecond)
 	}
-}
\ No newline at end of file
+}
+// encodePacket converts the packet using the configured wire encoding.
+func encodePacket(pkt *snifpacket.SnifPacket, encoding string) (*pb.Packet, error) {
+	if encoding == "json" {
+		return pkt.ToJSONProto()
+	}
+	return pkt.ToProto()
+}
@@ -4,16 +4,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gopacket/gopacket"
 	"github.com/gopacket/gopacket/afpacket"
-	"github.com/gopacket/gopacket/layers"
 	"github.com/joho/godotenv"
 	"github.com/nrf24l01/sniffly/capturer/core"
 	"github.com/nrf24l01/sniffly/capturer/grpc"
 	"github.com/nrf24l01/sniffly/capturer/snifpacket"
+	"github.com/nrf24l01/sniffly/capturer/spool"
 )
 
 func main() {
@@ -37,40 +38,281 @@ func main() {
         log.Fatalf("Failed to connect to gRPC server: %v", err)
     }
 
-    // Open device for packet capturing via AF_PACKET (Linux)
-    tp, err := afpacket.NewTPacket(
-        afpacket.OptInterface(config.Interface),
-        afpacket.OptFrameSize(65536),
-        afpacket.OptBlockSize(1024*1024),
-        afpacket.OptNumBlocks(32),
-        afpacket.OptPollTimeout(500*time.Millisecond),
-    )
+    // Goroutines
+    var wg sync.WaitGroup
+
+    homeNets, err := snifpacket.ParseInterfaceCIDRs(config.HomeNets)
     if err != nil {
-        log.Fatalf("failed to open AF_PACKET on %s: %v", config.Interface, err)
+        log.Fatalf("invalid HOME_NETS: %v", err)
+    }
+    excludeNets, err := snifpacket.ParseInterfaceCIDRs(config.ExcludeNets)
+    if err != nil {
+        log.Fatalf("invalid EXCLUDE_NETS: %v", err)
+    }
+    // Networks configured for iface, else the ones for every interface
+    scopeFor := func(iface string) snifpacket.ScopeOptions {
+        return snifpacket.ScopeOptions{
+            Mode:        config.TrafficMode,
+            HomeNets:    homeNets.For(iface),
+            ExcludeNets: excludeNets.For(iface),
+        }
+    }
+    bpfOptionsFor := func(iface string) snifpacket.BPFOptions {
+        return snifpacket.BPFOptions{
+            Expression:    config.BPFFilter,
+            Presets:       config.BPFPresets,
+            ServerAddress: config.ServerAddress,
+            HomeNets:      homeNets.For(iface),
+        }
     }
-    defer tp.Close()
 
-    packetSource := gopacket.NewPacketSource(tp, layers.LinkTypeEthernet)
-    packetSource.NoCopy = true
+    dissectors, err := snifpacket.ParseDissectorOptions(config.Dissectors, config.DissectorsDisabled, config.DissectorPorts)
+    if err != nil {
+        log.Fatalf("invalid dissector configuration: %v", err)
+    }
 
-    // Goroutines
-    var wg sync.WaitGroup
-    fmt.Printf("Starting packet capture on interface: %s to target %s\n", config.Interface, config.ServerAddress)
+    // Each capture goroutine owns its processor and stream state
+    newProcessor := func() *snifpacket.Processor {
+        opts := snifpacket.ProcessorOptions{
+            HTTPBodySizes: config.HTTPBodySizes,
+            Dissectors:    dissectors,
+        }
+        if !config.ReassemblyEnabled {
+            return snifpacket.NewProcessor(nil, opts)
+        }
+        return snifpacket.NewProcessor(snifpacket.NewReassembler(snifpacket.ReassemblyOptions{
+            MaxBytes:   config.ReassemblyMaxBytes,
+            MaxStreams: config.ReassemblyMaxStreams,
+            Timeout:    config.ReassemblyTimeout,
+        }), opts)
+    }
+
+    switch config.Mode {
+    case "replay":
+        iface := config.CaptureInterfaces()[0]
+        // The file was likely recorded elsewhere, the capture host's
+        // addresses say nothing about its home networks
+        expr, err := snifpacket.BuildBPFExpression("", bpfOptionsFor(iface))
+        if err != nil {
+            log.Fatalf("failed to build BPF filter: %v", err)
+        }
+        if expr != "" {
+            log.Printf("Replay filter: %s", expr)
+        }
+
+        // Read frames from a capture file instead of the interface
+        packetSource, closer, err := snifpacket.OpenReplaySource(config.ReplayFile, snifpacket.ReplayOptions{
+            Pacing:         config.ReplayPacing,
+            Speed:          config.ReplaySpeed,
+            KeepTimestamps: config.ReplayKeepTimestamps,
+            Filter:         expr,
+        })
+        if err != nil {
+            log.Fatalf("failed to open replay file %s: %v", config.ReplayFile, err)
+        }
+        defer closer.Close()
 
-    // Start packet processing
-    wg.Add(1)
-    go snifpacket.ReceivePackets(packetSource, config.Interface, packets, &wg)
+        fmt.Printf("Replaying %s (pacing %s) to target %s\n", config.ReplayFile, config.ReplayPacing, config.ServerAddress)
 
-    wg.Add(1)
-    go func() {
-        if err := grpc.StreamPackets(client, config, packets, &wg); err != nil {
-            log.Printf("StreamPackets exited with error: %v", err)
-        } else {
-            log.Printf("StreamPackets exited normally")
+        keep, err := snifpacket.NewScopeFilter("", scopeFor(iface))
+        if err != nil {
+            log.Fatalf("failed to set up traffic scope: %v", err)
         }
-    }()
+
+        wg.Add(1)
+        go snifpacket.ReplayPackets(packetSource, iface, newProcessor(), keep, packets, &wg)
+    case "live":
+        // One receive goroutine per interface, all feeding the shared channel
+        var captureWg sync.WaitGroup
+        for _, iface := range config.CaptureInterfaces() {
+            tp, link := openCapture(iface)
+            defer tp.Close()
+
+            // Drop unwanted frames in the kernel before they reach user space
+            filter, expr, err := snifpacket.CompileBPFFilter(iface, link.LinkType, bpfOptionsFor(iface))
+            if err != nil {
+                log.Fatalf("failed to build BPF filter for %s: %v", iface, err)
+            }
+            if filter != nil {
+                if err := tp.SetBPF(filter); err != nil {
+                    log.Fatalf("failed to attach BPF filter on %s: %v", iface, err)
+                }
+                log.Printf("BPF filter on %s: %s", iface, expr)
+            }
+
+            packetSource := gopacket.NewPacketSource(link.Source(tp), link.LinkType)
+            packetSource.NoCopy = true
+
+            fmt.Printf("Starting packet capture on interface: %s (%s) to target %s\n", iface, link.LinkType, config.ServerAddress)
+
+            // Start packet processing
+            keep, err := snifpacket.NewScopeFilter(iface, scopeFor(iface))
+            if err != nil {
+                log.Fatalf("failed to set up traffic scope for %s: %v", iface, err)
+            }
+
+            captureWg.Add(1)
+            go snifpacket.ReceivePackets(packetSource, iface, newProcessor(), keep, packets, &captureWg)
+        }
+
+        // Close the shared channel once every interface stopped
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            captureWg.Wait()
+            close(packets)
+        }()
+    case "tunnel":
+        labels, err := snifpacket.ParseTunnelLabels(config.TunnelLabels)
+        if err != nil {
+            log.Fatalf("invalid TUNNEL_LABELS: %v", err)
+        }
+        tunnelOptions := snifpacket.TunnelOptions{Labels: labels}
+
+        // The filter applies to the mirrored frames, in user space
+        expr, err := snifpacket.BuildBPFExpression("", bpfOptionsFor(""))
+        if err != nil {
+            log.Fatalf("failed to build BPF filter: %v", err)
+        }
+        if expr != "" {
+            if tunnelOptions.Match, err = snifpacket.NewTunnelMatcher(expr); err != nil {
+                log.Fatalf("failed to build BPF filter: %v", err)
+            }
+            log.Printf("Tunnel filter: %s", expr)
+        }
+
+        // Addresses of the capture host say nothing about mirrored segments
+        keep, err := snifpacket.NewScopeFilter("", scopeFor(""))
+        if err != nil {
+            log.Fatalf("failed to set up traffic scope: %v", err)
+        }
+
+        if len(config.TunnelListen) == 0 && config.TunnelInterface == "" {
+            log.Fatalf("tunnel mode needs TUNNEL_LISTEN or TUNNEL_INTERFACE")
+        }
+
+        var captureWg sync.WaitGroup
+        for _, spec := range config.TunnelListen {
+            if strings.TrimSpace(spec) == "" {
+                continue
+            }
+            conn, encapsulation, err := snifpacket.ListenTunnel(spec)
+            if err != nil {
+                log.Fatalf("failed to listen for tunnel %s: %v", spec, err)
+            }
+            defer conn.Close()
+
+            fmt.Printf("Receiving %s tunnels on %s to target %s\n", encapsulation, conn.LocalAddr(), config.ServerAddress)
+
+            captureWg.Add(1)
+            go snifpacket.ReceiveTunnelSocket(conn, encapsulation, newProcessor(), keep, tunnelOptions, packets, &captureWg)
+        }
+        if iface := config.TunnelInterface; iface != "" {
+            tp, link := openCapture(iface)
+            defer tp.Close()
+
+            packetSource := gopacket.NewPacketSource(link.Source(tp), link.LinkType)
+            packetSource.NoCopy = true
+
+            fmt.Printf("Receiving tunnels on interface: %s (%s) to target %s\n", iface, link.LinkType, config.ServerAddress)
+
+            captureWg.Add(1)
+            go snifpacket.ReceiveTunnelCapture(packetSource, iface, newProcessor(), keep, tunnelOptions, packets, &captureWg)
+        }
+
+        // Close the shared channel once every tunnel source stopped
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            captureWg.Wait()
+            close(packets)
+        }()
+    default:
+        log.Fatalf("unknown capture mode %q", config.Mode)
+    }
+
+    if config.FlowsEnabled {
+        // Collapse packets into flow records before they reach the sender
+        flows := make(chan *snifpacket.SnifPacket, 1000)
+        wg.Add(1)
+        go snifpacket.AggregateFlows(packets, flows, snifpacket.FlowOptions{
+            IdleTimeout:   config.FlowIdleTimeout,
+            ActiveTimeout: config.FlowActiveTimeout,
+            MaxFlows:      config.FlowMaxFlows,
+        }, &wg)
+        packets = flows
+    }
+
+    if config.SpoolDir != "" {
+        // Buffer packets on disk so receiver outages and restarts lose nothing
+        sp, err := spool.Open(config.SpoolDir, spool.Options{
+            SegmentBytes: config.SpoolSegmentBytes,
+            SegmentAge:   config.SpoolSegmentAge,
+            MaxBytes:     config.SpoolMaxBytes,
+            MaxAge:       config.SpoolMaxAge,
+        })
+        if err != nil {
+            log.Fatalf("failed to open spool in %s: %v", config.SpoolDir, err)
+        }
+        defer sp.Close()
+
+        wg.Add(1)
+        go grpc.SpoolPackets(packets, config, sp, &wg)
+
+        wg.Add(1)
+        go func() {
+            if err := grpc.StreamSpooledPackets(client, config, sp, &wg); err != nil {
+                log.Printf("StreamSpooledPackets exited with error: %v", err)
+            } else {
+                log.Printf("StreamSpooledPackets exited normally")
+            }
+        }()
+    } else {
+        wg.Add(1)
+        go func() {
+            if err := grpc.StreamPackets(client, config, packets, &wg); err != nil {
+                log.Printf("StreamPackets exited with error: %v", err)
+            } else {
+                log.Printf("StreamPackets exited normally")
+            }
+        }()
+    }
 
     // On exit
     wg.Wait()
     fmt.Printf("Exiting...")
 }
+
+// openCapture opens an AF_PACKET socket on iface, cooked for interfaces
+// without an Ethernet header.
+func openCapture(iface string) (*afpacket.TPacket, snifpacket.CaptureLink) {
+    // PPP, WireGuard and tun have no Ethernet header
+    link, err := snifpacket.DetectLink(iface)
+    if err != nil {
+        log.Fatalf("failed to detect link type of %s: %v", iface, err)
+    }
+    socketType := afpacket.SocketRaw
+    if link.Cooked {
+        socketType = afpacket.SocketDgram
+    }
+    bindIface := iface
+    if iface == snifpacket.AnyInterface {
+        bindIface = ""
+    }
+
+    // Open device for packet capturing via AF_PACKET (Linux)
+    tp, err := afpacket.NewTPacket(
+        afpacket.OptInterface(bindIface),
+        afpacket.OptSocketType(socketType),
+        afpacket.OptFrameSize(65536),
+        afpacket.OptBlockSize(1024*1024),
+        afpacket.OptNumBlocks(32),
+        afpacket.OptPollTimeout(500*time.Millisecond),
+        // Put back the 802.1Q tags the NIC strips, to report VLAN IDs
+        afpacket.OptAddVLANHeader(true),
+    )
+    if err != nil {
+        log.Fatalf("failed to open AF_PACKET on %s: %v", iface, err)
+    }
+    return tp, link
+}
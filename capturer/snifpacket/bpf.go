@@ -0,0 +1,153 @@
+package snifpacket
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gopacket/gopacket/layers"
+	"github.com/nrf24l01/sniffly/capturer/bpfilter"
+	"golang.org/x/net/bpf"
+)
+
+// Built-in filter presets, combined with the user expression.
+const (
+	// BPFPresetExcludeSelf drops the capturer's own gRPC traffic to the receiver.
+	BPFPresetExcludeSelf = "exclude-self"
+	// BPFPresetExcludeLAN drops traffic between two addresses of the local networks.
+	BPFPresetExcludeLAN = "exclude-lan"
+)
+
+type BPFOptions struct {
+	// Expression is a tcpdump-style filter, e.g. "not port 22".
+	Expression string
+	Presets    []string
+	// ServerAddress is the receiver address used by BPFPresetExcludeSelf.
+	ServerAddress string
+	// HomeNets overrides the interface networks for BPFPresetExcludeLAN.
+	HomeNets []*net.IPNet
+}
+
+// BuildBPFExpression combines the user expression with the enabled presets
+// for iface. An empty result means no filter.
+func BuildBPFExpression(iface string, opts BPFOptions) (string, error) {
+	var parts []string
+	if expr := strings.TrimSpace(opts.Expression); expr != "" {
+		parts = append(parts, "("+expr+")")
+	}
+
+	for _, preset := range opts.Presets {
+		var expr string
+		var err error
+		switch strings.TrimSpace(preset) {
+		case "":
+			continue
+		case BPFPresetExcludeSelf:
+			expr, err = excludeSelfExpression(opts.ServerAddress)
+		case BPFPresetExcludeLAN:
+			expr, err = excludeLANExpression(ResolveHomeNets(iface, opts.HomeNets))
+		default:
+			err = fmt.Errorf("unknown BPF preset %q", preset)
+		}
+		if err != nil {
+			return "", err
+		}
+		if expr != "" {
+			parts = append(parts, expr)
+		}
+	}
+
+	return strings.Join(parts, " and "), nil
+}
+
+// CompileBPFFilter builds and compiles the filter for iface, whose packets
+// start with linkType. It returns a nil program when no filter is
+// configured.
+func CompileBPFFilter(iface string, linkType layers.LinkType, opts BPFOptions) ([]bpf.RawInstruction, string, error) {
+	expr, err := BuildBPFExpression(iface, opts)
+	if err != nil || expr == "" {
+		return nil, "", err
+	}
+	link, err := bpfLink(linkType)
+	if err != nil {
+		return nil, "", err
+	}
+	if iface == AnyInterface {
+		// The kernel sees each interface's own header, not the cooked one
+		link = bpfilter.LinkAny
+	}
+	raw, err := bpfilter.CompileRawLink(expr, link)
+	if err != nil {
+		return nil, "", err
+	}
+	return raw, expr, nil
+}
+
+// NewBPFMatcher compiles expr for linkType and returns a predicate that
+// runs it in user space, for packet sources that can't attach it to a
+// socket.
+func NewBPFMatcher(expr string, linkType layers.LinkType) (func(data []byte) bool, error) {
+	link, err := bpfLink(linkType)
+	if err != nil {
+		return nil, err
+	}
+	if link == bpfilter.LinkEthernet {
+		// Capture files and tunnels keep the VLAN tags in the frame
+		link = bpfilter.LinkEthernetTagged
+	}
+	instructions, err := bpfilter.CompileLink(expr, link)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := bpf.NewVM(instructions)
+	if err != nil {
+		return nil, err
+	}
+	return func(data []byte) bool {
+		n, err := vm.Run(data)
+		return err == nil && n > 0
+	}, nil
+}
+
+func bpfLink(linkType layers.LinkType) (bpfilter.Link, error) {
+	switch linkType {
+	case layers.LinkTypeEthernet:
+		return bpfilter.LinkEthernet, nil
+	case layers.LinkTypeLinuxSLL:
+		return bpfilter.LinkLinuxSLL, nil
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4, layers.LinkTypeIPv6:
+		return bpfilter.LinkRaw, nil
+	}
+	return 0, fmt.Errorf("BPF filters are not supported on link type %s", linkType)
+}
+
+func excludeSelfExpression(serverAddress string) (string, error) {
+	host, port, err := net.SplitHostPort(serverAddress)
+	if err != nil {
+		return "", fmt.Errorf("%s preset: %w", BPFPresetExcludeSelf, err)
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil {
+		return "", fmt.Errorf("%s preset: %w", BPFPresetExcludeSelf, err)
+	}
+
+	hosts := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		hosts = append(hosts, "host "+ip)
+	}
+	return fmt.Sprintf("not (tcp port %s and (%s))", port, strings.Join(hosts, " or ")), nil
+}
+
+func excludeLANExpression(homeNets []*net.IPNet) (string, error) {
+	var src, dst []string
+	for _, n := range homeNets {
+		ones, _ := n.Mask.Size()
+		cidr := fmt.Sprintf("%s/%d", n.IP.Mask(n.Mask), ones)
+		src = append(src, "src net "+cidr)
+		dst = append(dst, "dst net "+cidr)
+	}
+	if len(src) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("not ((%s) and (%s))", strings.Join(src, " or "), strings.Join(dst, " or ")), nil
+}
@@ -0,0 +1,129 @@
+package snifpacket
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// testBPFFrame builds a TCP or UDP packet between src and dst, starting
+// with an Ethernet header (optionally VLAN-tagged) or, for raw, the IP
+// header.
+func testBPFFrame(t *testing.T, src, dst string, tcp bool, srcPort, dstPort uint16, vlan uint16, raw bool) []byte {
+	t.Helper()
+	ip := &layers.IPv4{Version: 4, TTL: 64, SrcIP: net.ParseIP(src).To4(), DstIP: net.ParseIP(dst).To4()}
+	var transport gopacket.SerializableLayer
+	if tcp {
+		ip.Protocol = layers.IPProtocolTCP
+		l := &layers.TCP{SrcPort: layers.TCPPort(srcPort), DstPort: layers.TCPPort(dstPort), Window: 1024}
+		l.SetNetworkLayerForChecksum(ip)
+		transport = l
+	} else {
+		ip.Protocol = layers.IPProtocolUDP
+		l := &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: layers.UDPPort(dstPort)}
+		l.SetNetworkLayerForChecksum(ip)
+		transport = l
+	}
+
+	stack := []gopacket.SerializableLayer{ip, transport}
+	if !raw {
+		eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{2, 0, 0, 0, 0, 0x0a}, DstMAC: net.HardwareAddr{2, 0, 0, 0, 0, 1}, EthernetType: layers.EthernetTypeIPv4}
+		if vlan != 0 {
+			eth.EthernetType = layers.EthernetTypeDot1Q
+			stack = append([]gopacket.SerializableLayer{eth, &layers.Dot1Q{VLANIdentifier: vlan, Type: layers.EthernetTypeIPv4}}, stack...)
+		} else {
+			stack = append([]gopacket.SerializableLayer{eth}, stack...)
+		}
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, stack...); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBPFPresets(t *testing.T) {
+	_, home, _ := net.ParseCIDR("192.168.1.0/24")
+	opts := BPFOptions{
+		ServerAddress: "10.0.0.5:50051",
+		HomeNets:      []*net.IPNet{home},
+	}
+
+	type frame struct {
+		src, dst         string
+		tcp              bool
+		srcPort, dstPort uint16
+	}
+	toReceiver := frame{"192.168.1.10", "10.0.0.5", true, 41000, 50051}
+	fromReceiver := frame{"10.0.0.5", "192.168.1.10", true, 50051, 41000}
+	otherReceiverPort := frame{"192.168.1.10", "10.0.0.5", true, 41000, 443}
+	lan := frame{"192.168.1.10", "192.168.1.1", false, 53000, 53}
+	outbound := frame{"192.168.1.10", "93.184.216.34", true, 50000, 443}
+	ssh := frame{"192.168.1.10", "93.184.216.34", true, 50001, 22}
+
+	tests := []struct {
+		expr    string
+		presets []string
+		keep    []frame
+		drop    []frame
+	}{
+		{"", []string{BPFPresetExcludeSelf}, []frame{otherReceiverPort, lan, outbound, ssh}, []frame{toReceiver, fromReceiver}},
+		{"", []string{BPFPresetExcludeLAN}, []frame{toReceiver, fromReceiver, otherReceiverPort, outbound, ssh}, []frame{lan}},
+		{"", []string{BPFPresetExcludeSelf, BPFPresetExcludeLAN}, []frame{otherReceiverPort, outbound, ssh}, []frame{toReceiver, fromReceiver, lan}},
+		{"not port 22", []string{BPFPresetExcludeSelf, BPFPresetExcludeLAN}, []frame{otherReceiverPort, outbound}, []frame{toReceiver, fromReceiver, lan, ssh}},
+		{"tcp or udp port 53", []string{BPFPresetExcludeSelf}, []frame{otherReceiverPort, lan, outbound, ssh}, []frame{toReceiver, fromReceiver}},
+	}
+
+	for _, tt := range tests {
+		opts.Expression, opts.Presets = tt.expr, tt.presets
+		expr, err := BuildBPFExpression("", opts)
+		if err != nil {
+			t.Fatalf("%q %v: %v", tt.expr, tt.presets, err)
+		}
+
+		for _, link := range []struct {
+			name     string
+			linkType layers.LinkType
+			raw      bool
+		}{
+			{"ethernet", layers.LinkTypeEthernet, false},
+			{"raw", layers.LinkTypeRaw, true},
+		} {
+			match, err := NewBPFMatcher(expr, link.linkType)
+			if err != nil {
+				t.Fatalf("%q on %s: %v", expr, link.name, err)
+			}
+			check := func(f frame, want bool) {
+				data := testBPFFrame(t, f.src, f.dst, f.tcp, f.srcPort, f.dstPort, 0, link.raw)
+				if got := match(data); got != want {
+					t.Errorf("%q on %s %+v: kept %v, want %v", expr, link.name, f, got, want)
+				}
+			}
+			for _, f := range tt.keep {
+				check(f, true)
+			}
+			for _, f := range tt.drop {
+				check(f, false)
+			}
+		}
+	}
+}
+
+func TestBPFMatcherVLAN(t *testing.T) {
+	// Capture files keep the tags in the frame
+	match, err := NewBPFMatcher("vlan 10 and tcp port 443", layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match(testBPFFrame(t, "192.168.1.10", "93.184.216.34", true, 50000, 443, 10, false)) {
+		t.Errorf("tagged frame in VLAN 10 not matched")
+	}
+	if match(testBPFFrame(t, "192.168.1.10", "93.184.216.34", true, 50000, 443, 20, false)) {
+		t.Errorf("tagged frame in VLAN 20 matched")
+	}
+	if match(testBPFFrame(t, "192.168.1.10", "93.184.216.34", true, 50000, 443, 0, false)) {
+		t.Errorf("untagged frame matched")
+	}
+}
@@ -0,0 +1,102 @@
+package snifpacket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cleartext CoAP; 5684 is DTLS
+const coapPort = 5683
+
+// CoAP options naming the target resource (RFC 7252)
+const (
+	coapOptionURIHost = 3
+	coapOptionURIPath = 11
+)
+
+var coapTypes = [4]string{"CON", "NON", "ACK", "RST"}
+
+var coapMethods = map[byte]string{
+	1: "GET", 2: "POST", 3: "PUT", 4: "DELETE", 5: "FETCH", 6: "PATCH", 7: "iPATCH",
+}
+
+// parseCoAP reads the header and target resource of a CoAP message.
+// Responses get their code ("2.05") as method and no URI.
+func parseCoAP(payload []byte) *SnifPacketDetailsCoAP {
+	if len(payload) < 4 || payload[0]>>6 != 1 {
+		return nil
+	}
+	tokenLen := int(payload[0] & 0x0f)
+	if tokenLen > 8 || len(payload) < 4+tokenLen {
+		return nil
+	}
+	code := payload[1]
+	// An empty message is a ping or a bare acknowledgement
+	if code == 0 {
+		return nil
+	}
+
+	details := &SnifPacketDetailsCoAP{Type: coapTypes[(payload[0]>>4)&0x03]}
+	class := code >> 5
+	switch {
+	case class == 0:
+		method, ok := coapMethods[code&0x1f]
+		if !ok {
+			return nil
+		}
+		details.Method = method
+	case class >= 2 && class <= 5:
+		details.Method = fmt.Sprintf("%d.%02d", class, code&0x1f)
+	default:
+		return nil
+	}
+
+	var path []string
+	option := 0
+	for data := payload[4+tokenLen:]; len(data) > 0 && data[0] != 0xff; {
+		delta, length := int(data[0]>>4), int(data[0]&0x0f)
+		data = data[1:]
+		var ok bool
+		if delta, data, ok = coapOptionNibble(delta, data); !ok {
+			return nil
+		}
+		if length, data, ok = coapOptionNibble(length, data); !ok {
+			return nil
+		}
+		if len(data) < length {
+			return nil
+		}
+		option += delta
+		switch option {
+		case coapOptionURIHost:
+			details.URIHost = string(data[:length])
+		case coapOptionURIPath:
+			path = append(path, string(data[:length]))
+		}
+		data = data[length:]
+	}
+	if class == 0 {
+		details.URIPath = "/" + strings.Join(path, "/")
+	}
+	return details
+}
+
+// coapOptionNibble resolves the extended forms of an option delta or
+// length.
+func coapOptionNibble(v int, data []byte) (int, []byte, bool) {
+	switch v {
+	case 13:
+		if len(data) < 1 {
+			return 0, nil, false
+		}
+		return int(data[0]) + 13, data[1:], true
+	case 14:
+		if len(data) < 2 {
+			return 0, nil, false
+		}
+		return int(data[0])<<8 | int(data[1]) + 269, data[2:], true
+	case 15:
+		return 0, nil, false
+	}
+	return v, data, true
+}
@@ -0,0 +1,148 @@
+package snifpacket
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// DHCP server ports; only client messages are parsed since they describe
+// the device.
+const (
+	dhcpv4ServerPort = 67
+	dhcpv6ServerPort = 547
+)
+
+// parseDHCPv4 reads the identifying options of a client message.
+func parseDHCPv4(payload []byte) *SnifPacketDetailsDHCP {
+	dhcp := &layers.DHCPv4{}
+	if err := dhcp.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	if dhcp.Operation != layers.DHCPOpRequest {
+		return nil
+	}
+
+	// Plain BOOTP requests have no message type option
+	details := &SnifPacketDetailsDHCP{Version: 4, MessageType: "BOOTP"}
+	if len(dhcp.ClientHWAddr) == 6 {
+		details.ClientMAC = dhcp.ClientHWAddr.String()
+	}
+	if ip := dhcp.ClientIP; ip != nil && !ip.IsUnspecified() {
+		details.ClientIP = ip.String()
+	}
+	for _, opt := range dhcp.Options {
+		switch opt.Type {
+		case layers.DHCPOptMessageType:
+			if len(opt.Data) == 1 {
+				details.MessageType = layers.DHCPMsgType(opt.Data[0]).String()
+			}
+		case layers.DHCPOptHostname:
+			details.Hostname = cleanDHCPString(opt.Data)
+		case layers.DHCPOptClassID:
+			details.VendorClass = cleanDHCPString(opt.Data)
+		case layers.DHCPOptParamsRequest:
+			params := make([]string, 0, len(opt.Data))
+			for _, p := range opt.Data {
+				params = append(params, strconv.Itoa(int(p)))
+			}
+			details.ParamRequestList = strings.Join(params, ",")
+		case layers.DHCPOptRequestIP:
+			if len(opt.Data) == 4 {
+				details.RequestedIP = net.IP(opt.Data).String()
+			}
+		}
+	}
+	return details
+}
+
+// parseDHCPv6 reads the identifying options of a client message.
+func parseDHCPv6(payload []byte) *SnifPacketDetailsDHCP {
+	dhcp := &layers.DHCPv6{}
+	if err := dhcp.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	switch dhcp.MsgType {
+	case layers.DHCPv6MsgTypeSolicit, layers.DHCPv6MsgTypeRequest, layers.DHCPv6MsgTypeConfirm,
+		layers.DHCPv6MsgTypeRenew, layers.DHCPv6MsgTypeRebind, layers.DHCPv6MsgTypeInformationRequest:
+	default:
+		return nil
+	}
+
+	details := &SnifPacketDetailsDHCP{Version: 6, MessageType: dhcp.MsgType.String()}
+	for _, opt := range dhcp.Options {
+		switch opt.Code {
+		case layers.DHCPv6OptClientID:
+			duid := &layers.DHCPv6DUID{}
+			if duid.DecodeFromBytes(opt.Data) == nil && (duid.Type == layers.DHCPv6DUIDTypeLLT || duid.Type == layers.DHCPv6DUIDTypeLL) && len(duid.LinkLayerAddress) == 6 {
+				details.ClientMAC = duid.LinkLayerAddress.String()
+			}
+		case dhcpv6OptClientFQDN:
+			// Flags, then the name in DNS wire format
+			if len(opt.Data) > 1 {
+				details.Hostname = dnsWireName(opt.Data[1:])
+			}
+		case layers.DHCPv6OptVendorClass:
+			// Enterprise number, then length-prefixed class data
+			var classes []string
+			for data := opt.Data[min(4, len(opt.Data)):]; len(data) >= 2; {
+				n := int(binary.BigEndian.Uint16(data))
+				if len(data) < 2+n {
+					break
+				}
+				classes = append(classes, cleanDHCPString(data[2:2+n]))
+				data = data[2+n:]
+			}
+			details.VendorClass = strings.Join(classes, " ")
+		case layers.DHCPv6OptOro:
+			var params []string
+			for data := opt.Data; len(data) >= 2; data = data[2:] {
+				params = append(params, strconv.Itoa(int(binary.BigEndian.Uint16(data))))
+			}
+			details.ParamRequestList = strings.Join(params, ",")
+		case layers.DHCPv6OptIANA:
+			// IAID, T1, T2, then IA Address options
+			for data := opt.Data[min(12, len(opt.Data)):]; len(data) >= 4; {
+				code := binary.BigEndian.Uint16(data)
+				n := int(binary.BigEndian.Uint16(data[2:]))
+				if len(data) < 4+n {
+					break
+				}
+				if layers.DHCPv6Opt(code) == layers.DHCPv6OptIAAddr && n >= net.IPv6len {
+					details.RequestedIP = net.IP(data[4 : 4+net.IPv6len]).String()
+				}
+				data = data[4+n:]
+			}
+		}
+	}
+	return details
+}
+
+// Client FQDN option, RFC 4704
+const dhcpv6OptClientFQDN layers.DHCPv6Opt = 39
+
+// dnsWireName decodes a possibly unqualified name in DNS wire format.
+func dnsWireName(data []byte) string {
+	var labels []string
+	for len(data) > 0 && data[0] != 0 {
+		n := int(data[0])
+		if len(data) < 1+n {
+			break
+		}
+		labels = append(labels, string(data[1:1+n]))
+		data = data[1+n:]
+	}
+	return cleanDHCPString([]byte(strings.Join(labels, ".")))
+}
+
+// cleanDHCPString trims the padding and control characters some clients
+// put in string options.
+func cleanDHCPString(data []byte) string {
+	return strings.TrimFunc(string(data), func(r rune) bool {
+		return r < 0x20 || r == 0x7f || r == ' '
+	})
+}
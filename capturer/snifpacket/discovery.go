@@ -0,0 +1,188 @@
+package snifpacket
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// Service discovery ports; announcements describe their sender.
+const (
+	mdnsPort = 5353
+	ssdpPort = 1900
+)
+
+// TXT keys devices use for their model, manufacturer and software version,
+// most specific first (Apple, Google Cast, IPP printers, HomeKit and others)
+var (
+	mdnsModelKeys        = []string{"usb_MDL", "md", "model", "am", "mdl", "ty"}
+	mdnsManufacturerKeys = []string{"usb_MFG", "manufacturer", "mfg", "vendor", "mf"}
+	mdnsVersionKeys      = []string{"fv", "firmware", "fw_ver", "version", "vers", "srcvers", "osxvers", "sw"}
+)
+
+// parseMDNS collects what an mDNS response tells about the responder: its
+// host name, the services it offers with their instance names, and the
+// model and version keys of its TXT records.
+func parseMDNS(payload []byte) *SnifPacketDetailsMDNS {
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	// Queries only say what the sender looks for
+	if !dns.QR {
+		return nil
+	}
+
+	details := &SnifPacketDetailsMDNS{}
+	txt := make(map[string]string)
+	records := append(append([]layers.DNSResourceRecord{}, dns.Answers...), dns.Additionals...)
+	for _, rr := range records {
+		name := strings.TrimSuffix(string(rr.Name), ".")
+		switch rr.Type {
+		case layers.DNSTypeA, layers.DNSTypeAAAA:
+			details.Hostname = mdnsHostname(name)
+		case layers.DNSTypePTR:
+			target := strings.TrimSuffix(string(rr.PTR), ".")
+			switch {
+			case strings.HasSuffix(name, ".arpa"):
+				details.Hostname = mdnsHostname(target)
+			case name == "_services._dns-sd._udp.local":
+				details.Services = appendUnique(details.Services, mdnsServiceName(target))
+			default:
+				if service, instance, ok := mdnsSplitInstance(target); ok && service == name {
+					details.Services = appendUnique(details.Services, mdnsServiceName(service))
+					details.Instances = appendUnique(details.Instances, instance)
+				}
+			}
+		case layers.DNSTypeSRV:
+			if service, instance, ok := mdnsSplitInstance(name); ok {
+				details.Services = appendUnique(details.Services, mdnsServiceName(service))
+				details.Instances = appendUnique(details.Instances, instance)
+			}
+			if target := strings.TrimSuffix(string(rr.SRV.Name), "."); target != "" {
+				details.Hostname = mdnsHostname(target)
+			}
+		case layers.DNSTypeTXT:
+			for _, entry := range rr.TXTs {
+				key, value, ok := bytes.Cut(entry, []byte("="))
+				if ok && len(value) > 0 {
+					if _, seen := txt[string(key)]; !seen {
+						txt[string(key)] = strings.TrimSpace(string(value))
+					}
+				}
+			}
+		}
+	}
+	details.Model = firstTXT(txt, mdnsModelKeys)
+	details.Manufacturer = firstTXT(txt, mdnsManufacturerKeys)
+	details.Version = firstTXT(txt, mdnsVersionKeys)
+
+	if details.Hostname == "" && len(details.Services) == 0 && details.Model == "" && details.Manufacturer == "" {
+		return nil
+	}
+	return details
+}
+
+// mdnsSplitInstance splits "<instance>._<service>._<proto>.local" into the
+// service type and the instance name, which may itself contain dots.
+func mdnsSplitInstance(name string) (service, instance string, ok bool) {
+	labels := strings.Split(name, ".")
+	if len(labels) < 4 || labels[len(labels)-1] != "local" {
+		return "", "", false
+	}
+	proto := labels[len(labels)-2]
+	if proto != "_tcp" && proto != "_udp" || !strings.HasPrefix(labels[len(labels)-3], "_") {
+		return "", "", false
+	}
+	// Subtype browsing names ("<sub>._sub._<service>...") carry no instance
+	if labels[len(labels)-4] == "_sub" {
+		return "", "", false
+	}
+	return strings.Join(labels[len(labels)-3:], "."), strings.Join(labels[:len(labels)-3], "."), true
+}
+
+// mdnsServiceName drops the domain from a service type, "_ipp._tcp.local"
+// becomes "_ipp._tcp".
+func mdnsServiceName(service string) string {
+	return strings.TrimSuffix(service, ".local")
+}
+
+func mdnsHostname(name string) string {
+	return strings.TrimSuffix(name, ".local")
+}
+
+func firstTXT(txt map[string]string, keys []string) string {
+	for _, key := range keys {
+		if v := txt[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func appendUnique(list []string, value string) []string {
+	if value == "" {
+		return list
+	}
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// parseSSDP reads the headers of SSDP announcements (NOTIFY), searches
+// (M-SEARCH) and search responses.
+func parseSSDP(payload []byte) *SnifPacketDetailsSSDP {
+	lineEnd := indexOf(payload, []byte("\r\n"))
+	if lineEnd < 0 {
+		return nil
+	}
+	details := &SnifPacketDetailsSSDP{}
+	switch line := string(payload[:lineEnd]); {
+	case strings.HasPrefix(line, "NOTIFY * HTTP/1."):
+		details.Method = "NOTIFY"
+	case strings.HasPrefix(line, "M-SEARCH * HTTP/1."):
+		details.Method = "M-SEARCH"
+	case isHTTPResponseStart(payload):
+		details.Method = "RESPONSE"
+	default:
+		return nil
+	}
+
+	details.Server = httpHeader(payload, "SERVER")
+	details.UserAgent = httpHeader(payload, "USER-AGENT")
+	details.USN = httpHeader(payload, "USN")
+	details.Location = httpHeader(payload, "LOCATION")
+	// Notification type, or the search target for searches and responses
+	details.Target = httpHeader(payload, "NT")
+	if details.Target == "" {
+		details.Target = httpHeader(payload, "ST")
+	}
+	return details
+}
+
+// merge adds the records of a later response from the same responder.
+func (d *SnifPacketDetailsMDNS) merge(o *SnifPacketDetailsMDNS) {
+	for _, s := range o.Services {
+		d.Services = appendUnique(d.Services, s)
+	}
+	for _, i := range o.Instances {
+		d.Instances = appendUnique(d.Instances, i)
+	}
+	if o.Hostname != "" {
+		d.Hostname = o.Hostname
+	}
+	if o.Model != "" {
+		d.Model = o.Model
+	}
+	if o.Manufacturer != "" {
+		d.Manufacturer = o.Manufacturer
+	}
+	if o.Version != "" {
+		d.Version = o.Version
+	}
+}
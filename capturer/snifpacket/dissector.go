@@ -0,0 +1,256 @@
+package snifpacket
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transports a dissector runs on
+const (
+	TransportTCP = "tcp"
+	TransportUDP = "udp"
+)
+
+// Dissector recognises one application protocol in TCP segments or UDP
+// datagrams and fills in the packet details.
+type Dissector interface {
+	// Name identifies the dissector in the configuration, e.g. "tls". A
+	// protocol running over TCP and UDP has one dissector per transport
+	// sharing the name.
+	Name() string
+	// Transport is TransportTCP or TransportUDP.
+	Transport() string
+	// Ports are the ports the protocol is expected on by default.
+	Ports() []uint16
+	// Priority orders the dissectors of a transport, higher runs first.
+	Priority() int
+	// Heuristic reports whether a payload on ports the dissector is not
+	// mapped to may still be its protocol. It should be cheap, Dissect
+	// makes the final call.
+	Heuristic(ctx *DissectContext) bool
+	// Dissect parses ctx.Payload and fills in ctx.Packet.Details. It
+	// returns false to leave the packet to the next dissector.
+	Dissect(ctx *DissectContext) bool
+}
+
+// StreamDissector is a TCP dissector whose messages may span several
+// segments. With reassembly enabled its streams are buffered and it gets
+// their start instead of single segments.
+type StreamDissector interface {
+	Dissector
+	// DissectStream parses the reassembled data in ctx.Payload. It returns
+	// more while the message is incomplete and full is false; the stream is
+	// dropped from the reassembler otherwise.
+	DissectStream(ctx *DissectContext, full bool) (more bool)
+	// StreamStart reports whether a segment of a connection whose SYN was
+	// not seen begins a message, so that its stream is worth buffering.
+	// It should be cheap like Heuristic.
+	StreamStart(ctx *DissectContext) bool
+}
+
+// DissectContext is the packet a dissector looks at.
+type DissectContext struct {
+	Packet           *SnifPacket
+	Payload          []byte
+	SrcIP, DstIP     net.IP
+	SrcPort, DstPort uint16
+	Timestamp        time.Time
+	// ToPort and FromPort tell whether the destination or source port is
+	// one the dissector is mapped to; both are false for heuristic matches.
+	ToPort, FromPort bool
+
+	proc *Processor
+}
+
+// RegisterDissector adds a dissector, replacing a registered one with the
+// same name and transport. Processors created afterwards use it.
+func RegisterDissector(d Dissector) {
+	for i, r := range dissectorRegistry {
+		if r.Name() == d.Name() && r.Transport() == d.Transport() {
+			dissectorRegistry[i] = d
+			return
+		}
+	}
+	dissectorRegistry = append(dissectorRegistry, d)
+}
+
+func registeredDissector(name string) bool {
+	for _, d := range dissectorRegistry {
+		if d.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+type DissectorOptions struct {
+	// Enabled limits dissection to the named dissectors, all run when it
+	// is empty. Disabled turns single ones off.
+	Enabled  []string
+	Disabled []string
+	// AddPorts and RemovePorts change the ports of a dissector, by name.
+	AddPorts    map[string][]uint16
+	RemovePorts map[string][]uint16
+}
+
+// ParseDissectorOptions checks the dissector names and parses port
+// mappings of the form "name:port", which adds a port, or "name:-port",
+// which removes a default one.
+func ParseDissectorOptions(enabled, disabled, ports []string) (DissectorOptions, error) {
+	opts := DissectorOptions{
+		AddPorts:    make(map[string][]uint16),
+		RemovePorts: make(map[string][]uint16),
+	}
+	names := func(list []string) ([]string, error) {
+		var out []string
+		for _, name := range list {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if !registeredDissector(name) {
+				return nil, fmt.Errorf("unknown dissector %q", name)
+			}
+			out = append(out, name)
+		}
+		return out, nil
+	}
+	var err error
+	if opts.Enabled, err = names(enabled); err != nil {
+		return opts, err
+	}
+	if opts.Disabled, err = names(disabled); err != nil {
+		return opts, err
+	}
+	for _, mapping := range ports {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			continue
+		}
+		name, port, ok := strings.Cut(mapping, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return opts, fmt.Errorf("invalid dissector port %q, expected name:port", mapping)
+		}
+		if !registeredDissector(name) {
+			return opts, fmt.Errorf("unknown dissector %q", name)
+		}
+		port = strings.TrimSpace(port)
+		remove := strings.HasPrefix(port, "-")
+		n, err := strconv.ParseUint(strings.TrimPrefix(port, "-"), 10, 16)
+		if err != nil || n == 0 {
+			return opts, fmt.Errorf("invalid port in %q", mapping)
+		}
+		if remove {
+			opts.RemovePorts[name] = append(opts.RemovePorts[name], uint16(n))
+		} else {
+			opts.AddPorts[name] = append(opts.AddPorts[name], uint16(n))
+		}
+	}
+	return opts, nil
+}
+
+type dissectorEntry struct {
+	d     Dissector
+	ports map[uint16]bool
+}
+
+// dissectorSet holds the enabled dissectors of a processor with their
+// effective ports, in priority order.
+type dissectorSet struct {
+	tcp, udp []dissectorEntry
+}
+
+func newDissectorSet(opts DissectorOptions) *dissectorSet {
+	contains := func(list []string, name string) bool {
+		for _, v := range list {
+			if v == name {
+				return true
+			}
+		}
+		return false
+	}
+	set := &dissectorSet{}
+	for _, d := range dissectorRegistry {
+		name := d.Name()
+		if len(opts.Enabled) > 0 && !contains(opts.Enabled, name) || contains(opts.Disabled, name) {
+			continue
+		}
+		entry := dissectorEntry{d: d, ports: make(map[uint16]bool)}
+		for _, port := range d.Ports() {
+			entry.ports[port] = true
+		}
+		for _, port := range opts.AddPorts[name] {
+			entry.ports[port] = true
+		}
+		for _, port := range opts.RemovePorts[name] {
+			delete(entry.ports, port)
+		}
+		switch d.Transport() {
+		case TransportTCP:
+			set.tcp = append(set.tcp, entry)
+		case TransportUDP:
+			set.udp = append(set.udp, entry)
+		}
+	}
+	for _, list := range [][]dissectorEntry{set.tcp, set.udp} {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].d.Priority() > list[j].d.Priority()
+		})
+	}
+	return set
+}
+
+func (s *dissectorSet) entries(transport string) []dissectorEntry {
+	if transport == TransportTCP {
+		return s.tcp
+	}
+	return s.udp
+}
+
+// match reports whether a dissector should look at the packet, by port or
+// by its heuristic, and sets the port flags of ctx.
+func (e dissectorEntry) match(ctx *DissectContext) bool {
+	ctx.ToPort, ctx.FromPort = e.ports[ctx.DstPort], e.ports[ctx.SrcPort]
+	return ctx.ToPort || ctx.FromPort || e.d.Heuristic(ctx)
+}
+
+// dissect runs the dissectors of a transport until one recognises the
+// packet.
+func (s *dissectorSet) dissect(transport string, ctx *DissectContext) bool {
+	for _, e := range s.entries(transport) {
+		if e.match(ctx) && e.d.Dissect(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// stream returns the stream dissector for a TCP segment or reassembled
+// stream start, if any.
+func (s *dissectorSet) stream(ctx *DissectContext) (StreamDissector, bool) {
+	for _, e := range s.tcp {
+		if d, ok := e.d.(StreamDissector); ok && e.match(ctx) {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// dissector is the common part of the built-in dissectors.
+type dissector struct {
+	name      string
+	transport string
+	ports     []uint16
+	priority  int
+}
+
+func (d dissector) Name() string                   { return d.name }
+func (d dissector) Transport() string              { return d.transport }
+func (d dissector) Ports() []uint16                { return d.ports }
+func (d dissector) Priority() int                  { return d.priority }
+func (d dissector) Heuristic(*DissectContext) bool { return false }
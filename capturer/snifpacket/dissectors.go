@@ -0,0 +1,271 @@
+package snifpacket
+
+import "encoding/binary"
+
+// The built-in dissectors. Priorities keep the order the protocols were
+// always tried in: FTP before HTTP so data connections stay attributed,
+// HTTP before TLS so cleartext HTTP on 443 is still parsed.
+var dissectorRegistry = []Dissector{
+	ftpDissector{dissector{"ftp", TransportTCP, []uint16{ftpControlPort}, 60}},
+	httpDissector{dissector{"http", TransportTCP, []uint16{80}, 50}},
+	tlsDissector{dissector{"tls", TransportTCP, []uint16{443}, 40}},
+	dnsTCPDissector{dissector{"dns", TransportTCP, []uint16{53}, 30}},
+	mqttDissector{dissector{"mqtt", TransportTCP, []uint16{mqttPort}, 20}},
+
+	dnsUDPDissector{dissector{"dns", TransportUDP, []uint16{53}, 60}},
+	dhcpDissector{dissector{"dhcp", TransportUDP, []uint16{dhcpv4ServerPort, dhcpv6ServerPort}, 50}},
+	mdnsDissector{dissector{"mdns", TransportUDP, []uint16{mdnsPort}, 40}},
+	ssdpDissector{dissector{"ssdp", TransportUDP, []uint16{ssdpPort}, 30}},
+	coapDissector{dissector{"coap", TransportUDP, []uint16{coapPort}, 20}},
+	quicDissector{dissector{"quic", TransportUDP, []uint16{443}, 10}},
+	espUDPDissector{dissector{"ipsec", TransportUDP, []uint16{4500}, 5}},
+}
+
+type ftpDissector struct{ dissector }
+
+// Heuristic lets data connections on any port through while control
+// connections have announced some.
+func (ftpDissector) Heuristic(ctx *DissectContext) bool {
+	return ctx.proc.ftp != nil && !ctx.proc.ftp.empty()
+}
+
+func (ftpDissector) Dissect(ctx *DissectContext) bool {
+	return ctx.proc.processFTP(ctx)
+}
+
+type httpDissector struct{ dissector }
+
+// Heuristic finds HTTP on any port by its request or status line.
+func (httpDissector) Heuristic(ctx *DissectContext) bool {
+	return isHTTPRequestStart(ctx.Payload) || isHTTPResponseStart(ctx.Payload)
+}
+
+func (httpDissector) Dissect(ctx *DissectContext) bool {
+	details := ctx.proc.parseHTTPMessage(ctx.Payload, ctx.SrcIP, ctx.DstIP)
+	if details == nil {
+		return false
+	}
+	ctx.Packet.Details.HTTP = details
+	ctx.Packet.Details.Type = SnifPacketTypeHTTP
+	return true
+}
+
+func (h httpDissector) StreamStart(ctx *DissectContext) bool {
+	return h.Heuristic(ctx)
+}
+
+func (h httpDissector) DissectStream(ctx *DissectContext, full bool) bool {
+	complete, ok := httpHeaderComplete(ctx.Payload)
+	if !ok {
+		return false
+	}
+	if !complete && !full {
+		return true
+	}
+	h.Dissect(ctx)
+	return false
+}
+
+// tlsDissector parses the ClientHello sent to and the ServerHello sent from
+// its ports.
+type tlsDissector struct{ dissector }
+
+func (tlsDissector) Dissect(ctx *DissectContext) bool {
+	if ctx.ToPort {
+		if details := parseTLSClientHello(ctx.Payload, ctx.SrcIP, ctx.DstIP, len(ctx.Payload)); details != nil {
+			ctx.Packet.Details.TLS = details
+			ctx.Packet.Details.Type = SnifPacketTypeTLS
+			return true
+		}
+	}
+	if ctx.FromPort {
+		if details, _, _ := parseTLSServerHello(ctx.Payload); details != nil {
+			ctx.Packet.Details.TLSServer = details
+			ctx.Packet.Details.Type = SnifPacketTypeTLS
+			return true
+		}
+	}
+	return false
+}
+
+// StreamStart looks for a handshake record opening with a ClientHello sent
+// to the port or a ServerHello sent from it.
+func (tlsDissector) StreamStart(ctx *DissectContext) bool {
+	data := ctx.Payload
+	if len(data) < 6 || data[0] != 0x16 || data[1] != 0x03 {
+		return false
+	}
+	return ctx.ToPort && data[5] == 0x01 || ctx.FromPort && data[5] == 0x02
+}
+
+func (tlsDissector) DissectStream(ctx *DissectContext, full bool) bool {
+	if ctx.ToPort {
+		record, complete, ok := tlsClientHelloRecord(ctx.Payload)
+		if !ok {
+			return false
+		}
+		if !complete && !full {
+			return true
+		}
+		if details := parseTLSClientHello(record, ctx.SrcIP, ctx.DstIP, len(record)); details != nil {
+			ctx.Packet.Details.TLS = details
+			ctx.Packet.Details.Type = SnifPacketTypeTLS
+		}
+		return false
+	}
+	details, complete, ok := parseTLSServerHello(ctx.Payload)
+	if !ok {
+		return false
+	}
+	if !complete && !full {
+		return true
+	}
+	if details != nil {
+		ctx.Packet.Details.TLSServer = details
+		ctx.Packet.Details.Type = SnifPacketTypeTLS
+	}
+	return false
+}
+
+// dnsTCPDissector parses DNS over TCP; without reassembly only messages
+// that fit in one segment.
+type dnsTCPDissector struct{ dissector }
+
+func (dnsTCPDissector) Dissect(ctx *DissectContext) bool {
+	msg, ok := dnsTCPMessage(ctx.Payload)
+	if !ok {
+		return false
+	}
+	return dissectDNS(ctx, msg)
+}
+
+// StreamStart looks for a length prefix followed by a DNS header with one
+// question.
+func (dnsTCPDissector) StreamStart(ctx *DissectContext) bool {
+	data := ctx.Payload
+	return len(data) >= 2+12 && binary.BigEndian.Uint16(data) >= 12 && binary.BigEndian.Uint16(data[6:]) == 1
+}
+
+func (dnsTCPDissector) DissectStream(ctx *DissectContext, full bool) bool {
+	msg, complete := dnsTCPMessage(ctx.Payload)
+	if !complete {
+		return !full
+	}
+	dissectDNS(ctx, msg)
+	return false
+}
+
+type dnsUDPDissector struct{ dissector }
+
+func (dnsUDPDissector) Dissect(ctx *DissectContext) bool {
+	return dissectDNS(ctx, ctx.Payload)
+}
+
+func dissectDNS(ctx *DissectContext, msg []byte) bool {
+	details := parseDNSMessage(msg, ctx.SrcIP, ctx.DstIP)
+	if details == nil {
+		return false
+	}
+	ctx.Packet.Details.DNS = details
+	ctx.Packet.Details.Type = SnifPacketTypeDNS
+	return true
+}
+
+// mqttDissector parses cleartext MQTT per segment.
+type mqttDissector struct{ dissector }
+
+func (mqttDissector) Dissect(ctx *DissectContext) bool {
+	details := parseMQTT(ctx.Payload)
+	if details == nil {
+		return false
+	}
+	ctx.Packet.Details.MQTT = details
+	ctx.Packet.Details.Type = SnifPacketTypeMQTT
+	return true
+}
+
+// dhcpDissector parses client messages sent to the server port, DHCPv4
+// over IPv4 and DHCPv6 over IPv6.
+type dhcpDissector struct{ dissector }
+
+func (dhcpDissector) Dissect(ctx *DissectContext) bool {
+	if !ctx.ToPort {
+		return false
+	}
+	var dhcp *SnifPacketDetailsDHCP
+	if ctx.SrcIP.To4() != nil {
+		dhcp = parseDHCPv4(ctx.Payload)
+	} else {
+		dhcp = parseDHCPv6(ctx.Payload)
+	}
+	if dhcp == nil {
+		return false
+	}
+	if dhcp.ClientMAC == "" {
+		dhcp.ClientMAC = ctx.Packet.SrcMAC
+	}
+	ctx.Packet.Details.DHCP = dhcp
+	ctx.Packet.Details.Type = SnifPacketTypeDHCP
+	return true
+}
+
+type mdnsDissector struct{ dissector }
+
+func (mdnsDissector) Dissect(ctx *DissectContext) bool {
+	details := parseMDNS(ctx.Payload)
+	if details == nil {
+		return false
+	}
+	ctx.Packet.Details.MDNS = details
+	ctx.Packet.Details.Type = SnifPacketTypeMDNS
+	return true
+}
+
+type ssdpDissector struct{ dissector }
+
+func (ssdpDissector) Dissect(ctx *DissectContext) bool {
+	details := parseSSDP(ctx.Payload)
+	if details == nil {
+		return false
+	}
+	ctx.Packet.Details.SSDP = details
+	ctx.Packet.Details.Type = SnifPacketTypeSSDP
+	return true
+}
+
+type coapDissector struct{ dissector }
+
+func (coapDissector) Dissect(ctx *DissectContext) bool {
+	details := parseCoAP(ctx.Payload)
+	if details == nil {
+		return false
+	}
+	ctx.Packet.Details.CoAP = details
+	ctx.Packet.Details.Type = SnifPacketTypeCoAP
+	return true
+}
+
+// quicDissector parses client Initials sent to its ports.
+type quicDissector struct{ dissector }
+
+func (quicDissector) Dissect(ctx *DissectContext) bool {
+	return ctx.ToPort && ctx.proc.processQUIC(ctx.Packet, ctx.Payload, ctx.SrcPort, ctx.DstPort, ctx.Timestamp)
+}
+
+// espUDPDissector reads the SPI of ESP packets encapsulated in UDP for NAT
+// traversal (RFC 3948). IKE messages on the same port start with four zero
+// bytes.
+type espUDPDissector struct{ dissector }
+
+func (espUDPDissector) Dissect(ctx *DissectContext) bool {
+	if len(ctx.Payload) < 8 {
+		return false
+	}
+	spi := binary.BigEndian.Uint32(ctx.Payload)
+	if spi == 0 {
+		return false
+	}
+	ctx.Packet.Details.IPsec = &SnifPacketDetailsIPsec{Protocol: "ESP", SPI: spi, Seq: binary.BigEndian.Uint32(ctx.Payload[4:])}
+	ctx.Packet.Details.Type = SnifPacketTypeIPsec
+	return true
+}
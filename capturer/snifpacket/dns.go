@@ -0,0 +1,162 @@
+package snifpacket
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+func parseDNS(dns *layers.DNS, src, dst net.IP) *SnifPacketDetailsDNS {
+	if dns.OpCode != layers.DNSOpCodeQuery || (!dns.QR && len(dns.Questions) == 0) {
+		return nil
+	}
+
+	details := &SnifPacketDetailsDNS{
+		ID:      dns.ID,
+		IsQuery: !dns.QR,
+	}
+	for _, q := range dns.Questions {
+		details.Queries = append(details.Queries, string(q.Name))
+		details.Questions = append(details.Questions, SnifPacketDNSQuestion{
+			Name: string(q.Name),
+			Type: dnsTypeName(q.Type),
+		})
+	}
+	if !dns.QR {
+		return details
+	}
+
+	details.RCode = dnsRCodeName(dns.ResponseCode)
+	for _, rr := range dns.Answers {
+		if answer, ok := dnsAnswer(rr); ok {
+			details.Answers = append(details.Answers, answer)
+		}
+	}
+	return details
+}
+
+// parseDNSMessage decodes a DNS message that did not come through the
+// gopacket UDP decoder, e.g. one read from a TCP stream.
+func parseDNSMessage(msg []byte, src, dst net.IP) *SnifPacketDetailsDNS {
+	dns := &layers.DNS{}
+	if err := dns.DecodeFromBytes(msg, gopacket.NilDecodeFeedback); err != nil {
+		return nil
+	}
+	return parseDNS(dns, src, dst)
+}
+
+// dnsTCPMessage returns the first message of a DNS over TCP stream, which
+// is prefixed with its length. ok is false until the message is complete.
+func dnsTCPMessage(data []byte) (msg []byte, ok bool) {
+	if len(data) < 2 {
+		return nil, false
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if n == 0 || len(data) < 2+n {
+		return nil, false
+	}
+	return data[2 : 2+n], true
+}
+
+func dnsAnswer(rr layers.DNSResourceRecord) (SnifPacketDNSAnswer, bool) {
+	answer := SnifPacketDNSAnswer{
+		Name: string(rr.Name),
+		Type: dnsTypeName(rr.Type),
+		TTL:  rr.TTL,
+	}
+	switch rr.Type {
+	case layers.DNSTypeA, layers.DNSTypeAAAA:
+		if rr.IP == nil {
+			return answer, false
+		}
+		answer.Data = rr.IP.String()
+	case layers.DNSTypeCNAME:
+		answer.Data = string(rr.CNAME)
+	case layers.DNSTypeSVCB, layers.DNSTypeHTTPS:
+		answer.Data, answer.IPHints = svcbPresentation(rr.SVCB)
+	default:
+		return answer, false
+	}
+	return answer, true
+}
+
+// svcbPresentation formats an SVCB/HTTPS record like a zone file does,
+// e.g. "1 . alpn=h2,h3 ipv4hint=192.0.2.1", and returns its address hints.
+func svcbPresentation(svcb layers.DNSSVCB) (string, []string) {
+	target := string(svcb.Target)
+	if target == "" {
+		target = "."
+	}
+	parts := []string{strconv.Itoa(int(svcb.Priority)), target}
+
+	var hints []string
+	for _, param := range svcb.Params {
+		var value string
+		switch param.Key {
+		case layers.DNSSvcParamKeyAlpn:
+			var ids []string
+			for v := param.Value; len(v) > 0 && len(v) >= 1+int(v[0]); v = v[1+int(v[0]):] {
+				ids = append(ids, string(v[1:1+int(v[0])]))
+			}
+			value = strings.Join(ids, ",")
+		case layers.DNSSvcParamKeyPort:
+			if len(param.Value) == 2 {
+				value = strconv.Itoa(int(binary.BigEndian.Uint16(param.Value)))
+			}
+		case layers.DNSSvcParamKeyIPv4Hint, layers.DNSSvcParamKeyIPv6Hint:
+			size := net.IPv4len
+			if param.Key == layers.DNSSvcParamKeyIPv6Hint {
+				size = net.IPv6len
+			}
+			var ips []string
+			for v := param.Value; len(v) >= size; v = v[size:] {
+				ips = append(ips, net.IP(v[:size]).String())
+			}
+			hints = append(hints, ips...)
+			value = strings.Join(ips, ",")
+		default:
+			// ECH configs and unknown keys are opaque blobs
+			parts = append(parts, svcParamKeyName(param.Key))
+			continue
+		}
+		parts = append(parts, svcParamKeyName(param.Key)+"="+value)
+	}
+	return strings.Join(parts, " "), hints
+}
+
+func svcParamKeyName(key layers.DNSSvcParamKey) string {
+	if name := key.String(); name != "Unknown" && name != "Invalid key" {
+		return name
+	}
+	return fmt.Sprintf("key%d", uint16(key))
+}
+
+func dnsTypeName(t layers.DNSType) string {
+	if name := t.String(); name != "Unknown" {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", uint16(t))
+}
+
+func dnsRCodeName(rc layers.DNSResponseCode) string {
+	switch rc {
+	case layers.DNSResponseCodeNoErr:
+		return "NOERROR"
+	case layers.DNSResponseCodeFormErr:
+		return "FORMERR"
+	case layers.DNSResponseCodeServFail:
+		return "SERVFAIL"
+	case layers.DNSResponseCodeNXDomain:
+		return "NXDOMAIN"
+	case layers.DNSResponseCodeNotImp:
+		return "NOTIMP"
+	case layers.DNSResponseCodeRefused:
+		return "REFUSED"
+	}
+	return fmt.Sprintf("RCODE%d", uint8(rc))
+}
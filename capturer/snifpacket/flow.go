@@ -0,0 +1,271 @@
+package snifpacket
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	FlowEndIdle    = "idle"
+	FlowEndActive  = "active"
+	FlowEndFIN     = "fin"
+	FlowEndRST     = "rst"
+	FlowEndEvicted = "evicted"
+	FlowEndFlush   = "flush"
+)
+
+// Trailing ACKs after FIN/RST are folded into the closed flow for this long.
+const flowCloseGrace = 2 * time.Second
+
+type FlowOptions struct {
+	IdleTimeout   time.Duration
+	ActiveTimeout time.Duration
+	MaxFlows      int
+}
+
+type flowKey struct {
+	iface      string
+	deviceIP   string
+	devicePort string
+	remoteIP   string
+	remotePort string
+	protocol   string
+	vlan       uint16
+}
+
+type flowState struct {
+	key      flowKey
+	record   *SnifPacket
+	start    time.Time
+	lastSeen time.Time
+	finUp    bool
+	finDown  bool
+	closedAt time.Time
+	reason   string
+	elem     *list.Element
+}
+
+// FlowTracker groups packets into device-oriented 5-tuple flows.
+type FlowTracker struct {
+	opts  FlowOptions
+	flows map[flowKey]*flowState
+	lru   *list.List
+
+	// Packet clock: the latest packet timestamp advanced by wall time since
+	// it was seen, so both live capture and fast replays expire correctly.
+	lastPacketTs time.Time
+	lastPacketAt time.Time
+}
+
+func NewFlowTracker(opts FlowOptions) *FlowTracker {
+	return &FlowTracker{
+		opts:  opts,
+		flows: make(map[flowKey]*flowState),
+		lru:   list.New(),
+	}
+}
+
+func (ft *FlowTracker) now() time.Time {
+	if ft.lastPacketTs.IsZero() {
+		return time.Now()
+	}
+	return ft.lastPacketTs.Add(time.Since(ft.lastPacketAt))
+}
+
+// Add accounts one packet and returns any flow records that were completed.
+func (ft *FlowTracker) Add(sp *SnifPacket) []*SnifPacket {
+	ts := time.Unix(sp.Timestamp, 0)
+	if ts.After(ft.lastPacketTs) {
+		ft.lastPacketTs = ts
+	}
+	ft.lastPacketAt = time.Now()
+
+	down := sp.IsDownload()
+	key := flowKey{sp.Interface, sp.SrcIP, sp.SrcPort, sp.DstIP, sp.DstPort, sp.Protocol, sp.VLAN}
+	if down {
+		key = flowKey{sp.Interface, sp.DstIP, sp.DstPort, sp.SrcIP, sp.SrcPort, sp.Protocol, sp.VLAN}
+	}
+
+	var out []*SnifPacket
+
+	fs, ok := ft.flows[key]
+	if ok && !fs.closedAt.IsZero() && sp.TCPFlags&TCPFlagSYN != 0 {
+		// A new connection reusing the 5-tuple of a closed one
+		out = append(out, ft.finish(fs, fs.reason))
+		ok = false
+	}
+	if !ok {
+		if ft.opts.MaxFlows > 0 && len(ft.flows) >= ft.opts.MaxFlows {
+			oldest := ft.lru.Front().Value.(*flowState)
+			out = append(out, ft.finish(oldest, FlowEndEvicted))
+		}
+		fs = ft.newFlow(key, sp, ts)
+	}
+
+	fs.lastSeen = ts
+	ft.lru.MoveToBack(fs.elem)
+	flow := fs.record.Flow
+	if down {
+		flow.DownBytes += uint64(sp.Size)
+		flow.DownPackets++
+	} else {
+		flow.UpBytes += uint64(sp.Size)
+		flow.UpPackets++
+	}
+	flow.End = sp.Timestamp
+
+	// Keep the first application-level details seen on the flow. A DNS
+	// response replaces its query, it carries the questions and the answers.
+	// The ServerHello is added next to the ClientHello.
+	if !hasAppDetails(fs.record) && hasAppDetails(sp) {
+		fs.record.Details = sp.Details
+	} else if sp.Details.TLSServer != nil && fs.record.Details.TLSServer == nil {
+		fs.record.Details.TLSServer = sp.Details.TLSServer
+	} else if d := fs.record.Details.DNS; d != nil && d.IsQuery && sp.Details.DNS != nil && !sp.Details.DNS.IsQuery {
+		fs.record.Details = sp.Details
+	} else if d := fs.record.Details.FTP; d != nil && d.ResponseCode != 0 && sp.Details.FTP != nil && sp.Details.FTP.Command != "" {
+		// The first FTP command says more than the server banner
+		fs.record.Details = sp.Details
+	} else if d := fs.record.Details.MDNS; d != nil && sp.Details.MDNS != nil {
+		// Devices announce their services in several responses
+		d.merge(sp.Details.MDNS)
+	} else if d := fs.record.Details.MQTT; d != nil && sp.Details.MQTT != nil {
+		// The topics of a connection are spread over its lifetime
+		d.merge(sp.Details.MQTT)
+	} else if sp.Details.DHCP != nil {
+		// A Discover and the following Request share the flow; the
+		// Request names the address the client settled on
+		fs.record.Details = sp.Details
+	}
+
+	if fs.closedAt.IsZero() {
+		if sp.TCPFlags&TCPFlagRST != 0 {
+			fs.closedAt, fs.reason = ts, FlowEndRST
+		} else if sp.TCPFlags&TCPFlagFIN != 0 {
+			if down {
+				fs.finDown = true
+			} else {
+				fs.finUp = true
+			}
+			if fs.finUp && fs.finDown {
+				fs.closedAt, fs.reason = ts, FlowEndFIN
+			}
+		}
+	}
+
+	return out
+}
+
+// Expire returns flows that hit the idle, active or close timeouts.
+func (ft *FlowTracker) Expire() []*SnifPacket {
+	now := ft.now()
+	var out []*SnifPacket
+	for e := ft.lru.Front(); e != nil; {
+		next := e.Next()
+		fs := e.Value.(*flowState)
+		switch {
+		case !fs.closedAt.IsZero() && now.Sub(fs.lastSeen) >= flowCloseGrace:
+			out = append(out, ft.finish(fs, fs.reason))
+		case ft.opts.IdleTimeout > 0 && now.Sub(fs.lastSeen) >= ft.opts.IdleTimeout:
+			out = append(out, ft.finish(fs, FlowEndIdle))
+		case ft.opts.ActiveTimeout > 0 && now.Sub(fs.start) >= ft.opts.ActiveTimeout:
+			out = append(out, ft.finish(fs, FlowEndActive))
+		}
+		e = next
+	}
+	return out
+}
+
+// Flush returns every open flow.
+func (ft *FlowTracker) Flush() []*SnifPacket {
+	var out []*SnifPacket
+	for e := ft.lru.Front(); e != nil; {
+		next := e.Next()
+		out = append(out, ft.finish(e.Value.(*flowState), FlowEndFlush))
+		e = next
+	}
+	return out
+}
+
+func (ft *FlowTracker) newFlow(key flowKey, sp *SnifPacket, ts time.Time) *flowState {
+	record := &SnifPacket{
+		SrcIP:     key.deviceIP,
+		DstIP:     key.remoteIP,
+		SrcMAC:    sp.DeviceMAC(),
+		DstMAC:    sp.SrcMAC,
+		SrcPort:   key.devicePort,
+		DstPort:   key.remotePort,
+		Protocol:  sp.Protocol,
+		Details:   sp.Details,
+		Timestamp: sp.Timestamp,
+		Flow:      &SnifPacketFlow{Start: sp.Timestamp, End: sp.Timestamp},
+		Interface: sp.Interface,
+		VLAN:      sp.VLAN,
+		OuterVLAN: sp.OuterVLAN,
+	}
+	switch sp.Direction {
+	case SnifPacketDirectionUp, SnifPacketDirectionDown:
+		record.Direction = SnifPacketDirectionUp
+	default:
+		record.Direction = sp.Direction
+	}
+	if !sp.IsDownload() {
+		record.DstMAC = sp.DstMAC
+	}
+
+	fs := &flowState{key: key, record: record, start: ts}
+	fs.elem = ft.lru.PushBack(fs)
+	ft.flows[key] = fs
+	return fs
+}
+
+func (ft *FlowTracker) finish(fs *flowState, reason string) *SnifPacket {
+	ft.lru.Remove(fs.elem)
+	delete(ft.flows, fs.key)
+	fs.record.Flow.EndReason = reason
+	fs.record.Size = int(fs.record.Flow.UpBytes + fs.record.Flow.DownBytes)
+	return fs.record
+}
+
+func hasAppDetails(sp *SnifPacket) bool {
+	switch sp.Details.Type {
+	case SnifPacketTypeTCP, SnifPacketTypeUDP, SnifPacketTypeIP:
+		return false
+	}
+	return true
+}
+
+// AggregateFlows turns the packet stream into a stream of flow records.
+func AggregateFlows(packets chan *SnifPacket, flows chan *SnifPacket, opts FlowOptions, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(flows)
+
+	ft := NewFlowTracker(opts)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var seen, emitted uint64
+	emit := func(records []*SnifPacket) {
+		for _, r := range records {
+			flows <- r
+			emitted++
+		}
+	}
+
+	for {
+		select {
+		case sp, ok := <-packets:
+			if !ok {
+				emit(ft.Flush())
+				log.Printf("Flow aggregation finished: packets=%d flows=%d", seen, emitted)
+				return
+			}
+			seen++
+			emit(ft.Add(sp))
+		case <-ticker.C:
+			emit(ft.Expire())
+		}
+	}
+}
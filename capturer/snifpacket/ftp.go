@@ -0,0 +1,268 @@
+package snifpacket
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ftpControlPort = 21
+
+// Commands whose arguments are secrets
+var ftpRedactedCommands = map[string]bool{"PASS": true, "ACCT": true}
+
+const ftpRedacted = "***"
+
+// parseFTPCommand parses the first command line a client sent on the
+// control connection.
+func parseFTPCommand(payload []byte) *SnifPacketDetailsFTP {
+	line, ok := ftpLine(payload)
+	if !ok {
+		return nil
+	}
+	verb, args, _ := strings.Cut(line, " ")
+	if len(verb) < 3 || len(verb) > 4 {
+		return nil
+	}
+	for _, c := range verb {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return nil
+		}
+	}
+	details := &SnifPacketDetailsFTP{Command: strings.ToUpper(verb), Args: args}
+	if ftpRedactedCommands[details.Command] && args != "" {
+		details.Args = ftpRedacted
+	}
+	return details
+}
+
+// parseFTPResponse parses the first line of a server reply.
+func parseFTPResponse(payload []byte) *SnifPacketDetailsFTP {
+	line, ok := ftpLine(payload)
+	if !ok || len(line) < 3 {
+		return nil
+	}
+	code, err := strconv.Atoi(line[:3])
+	if err != nil || code < 100 || code > 599 {
+		return nil
+	}
+	// Single-line replies and the first line of multi-line ones
+	if len(line) > 3 && line[3] != ' ' && line[3] != '-' {
+		return nil
+	}
+	return &SnifPacketDetailsFTP{ResponseCode: code, Args: strings.TrimSpace(line[3:])}
+}
+
+func ftpLine(payload []byte) (string, bool) {
+	end := bytes.Index(payload, []byte("\r\n"))
+	if end < 0 {
+		return "", false
+	}
+	line := payload[:end]
+	for _, c := range line {
+		if c < 0x20 && c != '\t' || c == 0x7f {
+			return "", false
+		}
+	}
+	return string(bytes.TrimRight(line, " ")), true
+}
+
+// ftpDataEndpoint returns the address a data connection is announced on:
+// by the client with PORT/EPRT, or by the server in its 227/229 reply to
+// PASV/EPSV. serverIP fills in the address EPSV leaves out.
+func ftpDataEndpoint(d *SnifPacketDetailsFTP, serverIP string) string {
+	switch {
+	case d.Command == "PORT":
+		return ftpHostPort(d.Args)
+	case d.Command == "EPRT":
+		// |proto|address|port|
+		parts := strings.Split(d.Args, "|")
+		if len(parts) == 5 && net.ParseIP(parts[2]) != nil {
+			if port, err := strconv.Atoi(parts[3]); err == nil && port > 0 && port < 65536 {
+				return net.JoinHostPort(parts[2], parts[3])
+			}
+		}
+	case d.ResponseCode == 227:
+		// Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+		open, close := strings.IndexByte(d.Args, '('), strings.IndexByte(d.Args, ')')
+		if open >= 0 && close > open {
+			return ftpHostPort(d.Args[open+1 : close])
+		}
+		// Some servers leave out the parentheses
+		if i := strings.LastIndexByte(d.Args, ' '); i >= 0 {
+			return ftpHostPort(strings.TrimRight(d.Args[i+1:], "."))
+		}
+	case d.ResponseCode == 229:
+		// Entering Extended Passive Mode (|||port|)
+		open, close := strings.IndexByte(d.Args, '('), strings.IndexByte(d.Args, ')')
+		if open >= 0 && close > open {
+			parts := strings.Split(d.Args[open+1:close], "|")
+			if len(parts) == 5 {
+				if port, err := strconv.Atoi(parts[3]); err == nil && port > 0 && port < 65536 {
+					return net.JoinHostPort(serverIP, parts[3])
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ftpHostPort decodes the h1,h2,h3,h4,p1,p2 form of PORT and PASV.
+func ftpHostPort(s string) string {
+	parts := strings.Split(strings.TrimSpace(s), ",")
+	if len(parts) != 6 {
+		return ""
+	}
+	var n [6]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 0 || v > 255 {
+			return ""
+		}
+		n[i] = v
+	}
+	port := n[4]<<8 | n[5]
+	if port == 0 {
+		return ""
+	}
+	ip := net.IPv4(byte(n[0]), byte(n[1]), byte(n[2]), byte(n[3]))
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
+
+// How long an announced data endpoint waits for its connection, and how
+// long an idle data connection stays attributed to its session
+const (
+	ftpAnnounceTimeout = 30 * time.Second
+	ftpDataIdleTimeout = 2 * time.Minute
+	ftpMaxChannels     = 1024
+)
+
+type ftpChannel struct {
+	session  string
+	lastSeen time.Time
+}
+
+// ftpTracker follows the data connections announced on FTP control
+// connections. It is not safe for concurrent use.
+type ftpTracker struct {
+	announced map[string]*ftpChannel
+	active    map[string]*ftpChannel
+}
+
+func newFTPTracker() *ftpTracker {
+	return &ftpTracker{
+		announced: make(map[string]*ftpChannel),
+		active:    make(map[string]*ftpChannel),
+	}
+}
+
+// announce remembers that a data connection of session will be opened to
+// endpoint.
+func (t *ftpTracker) announce(endpoint, session string, ts time.Time) {
+	t.expire(ts)
+	if len(t.announced) >= ftpMaxChannels {
+		return
+	}
+	t.announced[endpoint] = &ftpChannel{session: session, lastSeen: ts}
+}
+
+// match returns the session a TCP segment's connection belongs to, if it
+// is an FTP data connection. The first segment to an announced endpoint
+// claims it.
+func (t *ftpTracker) match(sp *SnifPacket, srcPort, dstPort uint16, ts time.Time) (string, bool) {
+	src := ftpAddr(sp.SrcIP, srcPort)
+	dst := ftpAddr(sp.DstIP, dstPort)
+	conn := src + ">" + dst
+	if dst < src {
+		conn = dst + ">" + src
+	}
+
+	if c, ok := t.active[conn]; ok {
+		if sp.TCPFlags&TCPFlagRST != 0 {
+			delete(t.active, conn)
+		} else {
+			c.lastSeen = ts
+		}
+		return c.session, true
+	}
+	for _, endpoint := range []string{dst, src} {
+		c, ok := t.announced[endpoint]
+		if !ok || ts.Sub(c.lastSeen) > ftpAnnounceTimeout {
+			continue
+		}
+		delete(t.announced, endpoint)
+		if len(t.active) < ftpMaxChannels {
+			c.lastSeen = ts
+			t.active[conn] = c
+		}
+		return c.session, true
+	}
+	return "", false
+}
+
+// empty reports whether no data connection is announced or active.
+func (t *ftpTracker) empty() bool {
+	return len(t.announced) == 0 && len(t.active) == 0
+}
+
+func (t *ftpTracker) expire(ts time.Time) {
+	for k, c := range t.announced {
+		if ts.Sub(c.lastSeen) > ftpAnnounceTimeout {
+			delete(t.announced, k)
+		}
+	}
+	for k, c := range t.active {
+		if ts.Sub(c.lastSeen) > ftpDataIdleTimeout {
+			delete(t.active, k)
+		}
+	}
+}
+
+// ftpAddr formats an address like the announced data endpoints; the port
+// strings of a packet carry service names.
+func ftpAddr(ip string, port uint16) string {
+	return net.JoinHostPort(ip, strconv.Itoa(int(port)))
+}
+
+// processFTP parses control connections on the ports of the FTP dissector
+// and attributes data connections to their session.
+func (p *Processor) processFTP(ctx *DissectContext) bool {
+	sp := ctx.Packet
+	var details *SnifPacketDetailsFTP
+	var session, serverIP string
+	switch {
+	case ctx.ToPort:
+		details = parseFTPCommand(ctx.Payload)
+		session = ftpAddr(sp.SrcIP, ctx.SrcPort) + ">" + ftpAddr(sp.DstIP, ctx.DstPort)
+		serverIP = sp.DstIP
+	case ctx.FromPort:
+		details = parseFTPResponse(ctx.Payload)
+		session = ftpAddr(sp.DstIP, ctx.DstPort) + ">" + ftpAddr(sp.SrcIP, ctx.SrcPort)
+		serverIP = sp.SrcIP
+	default:
+		if p.ftp == nil {
+			return false
+		}
+		dataSession, ok := p.ftp.match(sp, ctx.SrcPort, ctx.DstPort, ctx.Timestamp)
+		if !ok {
+			return false
+		}
+		sp.Details.FTP = &SnifPacketDetailsFTP{Data: true, Session: dataSession}
+		sp.Details.Type = SnifPacketTypeFTP
+		return true
+	}
+	if details == nil {
+		return false
+	}
+
+	details.Session = session
+	details.DataEndpoint = ftpDataEndpoint(details, serverIP)
+	if details.DataEndpoint != "" && p.ftp != nil {
+		p.ftp.announce(details.DataEndpoint, session, ctx.Timestamp)
+	}
+	sp.Details.FTP = details
+	sp.Details.Type = SnifPacketTypeFTP
+	return true
+}
@@ -0,0 +1,123 @@
+package snifpacket
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+)
+
+// Request methods recognised at the start of a TCP payload on any port
+var httpMethods = [][]byte{
+	[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("DELETE "), []byte("HEAD "),
+	[]byte("OPTIONS "), []byte("PATCH "), []byte("CONNECT "), []byte("TRACE "),
+}
+
+// isHTTPRequestStart reports whether data starts with an HTTP/1.x request
+// line. A line cut off by the segment end only needs a known method.
+func isHTTPRequestStart(data []byte) bool {
+	method := -1
+	for i, m := range httpMethods {
+		if bytes.HasPrefix(data, m) {
+			method = i
+			break
+		}
+	}
+	if method < 0 {
+		return false
+	}
+	lineEnd := indexOf(data, []byte("\r\n"))
+	if lineEnd < 0 {
+		return true
+	}
+	line := data[len(httpMethods[method]):lineEnd]
+	sp := bytes.LastIndexByte(line, ' ')
+	return sp > 0 && bytes.HasPrefix(line[sp+1:], []byte("HTTP/1."))
+}
+
+// isHTTPResponseStart reports whether data starts with an HTTP/1.x status
+// line.
+func isHTTPResponseStart(data []byte) bool {
+	if len(data) < 12 || !bytes.HasPrefix(data, []byte("HTTP/1.")) || data[8] != ' ' {
+		return false
+	}
+	for _, c := range data[9:12] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHTTPResponse parses the status line and headers of a response.
+func parseHTTPResponse(payload []byte, src, dst net.IP, size int) *SnifPacketDetailsHTTP {
+	if !isHTTPResponseStart(payload) {
+		return nil
+	}
+	status, _ := strconv.Atoi(string(payload[9:12]))
+	details := &SnifPacketDetailsHTTP{IsResponse: true, StatusCode: status}
+	fillHTTPHeaders(details, payload)
+	return details
+}
+
+// fillHTTPHeaders copies the metadata headers of the message in data.
+func fillHTTPHeaders(details *SnifPacketDetailsHTTP, data []byte) {
+	headers := data
+	if end := indexOf(data, []byte("\r\n\r\n")); end >= 0 {
+		headers = data[:end+2]
+	}
+	details.UserAgent = httpHeader(headers, "User-Agent")
+	details.ContentType = httpHeader(headers, "Content-Type")
+	if v := httpHeader(headers, "Content-Length"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			details.ContentLength = n
+		}
+	}
+}
+
+// httpHeader returns the value of the first header called name, matched
+// case-insensitively, from a header block.
+func httpHeader(headers []byte, name string) string {
+	lineStart := indexOf(headers, []byte("\r\n"))
+	for lineStart >= 0 {
+		line := headers[lineStart+2:]
+		lineEnd := indexOf(line, []byte("\r\n"))
+		if lineEnd >= 0 {
+			line = line[:lineEnd]
+		}
+		if len(line) > len(name) && line[len(name)] == ':' && bytes.EqualFold(line[:len(name)], []byte(name)) {
+			return string(bytes.TrimSpace(line[len(name)+1:]))
+		}
+		if lineEnd < 0 {
+			break
+		}
+		lineStart += 2 + lineEnd
+	}
+	return ""
+}
+
+// httpBodySize is the declared Content-Length of the message, or the body
+// bytes captured after its headers when there is none.
+func httpBodySize(details *SnifPacketDetailsHTTP, data []byte) int64 {
+	if details.ContentLength > 0 {
+		return details.ContentLength
+	}
+	if end := indexOf(data, []byte("\r\n\r\n")); end >= 0 {
+		return int64(len(data) - end - 4)
+	}
+	return 0
+}
+
+// parseHTTPMessage parses a request or response found on any port.
+func (p *Processor) parseHTTPMessage(data []byte, src, dst net.IP) *SnifPacketDetailsHTTP {
+	var details *SnifPacketDetailsHTTP
+	switch {
+	case isHTTPRequestStart(data):
+		details = parseHTTP(data, src, dst, len(data))
+	case isHTTPResponseStart(data):
+		details = parseHTTPResponse(data, src, dst, len(data))
+	}
+	if details != nil && p.opts.HTTPBodySizes {
+		details.BodySize = httpBodySize(details, data)
+	}
+	return details
+}
@@ -0,0 +1,219 @@
+package snifpacket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// AnyInterface captures on all interfaces at once, with their frames
+// rewritten to Linux cooked headers.
+const AnyInterface = "any"
+
+// ARP hardware types of interfaces whose frames carry an Ethernet header
+const (
+	arphrdEther    = 1
+	arphrdLoopback = 772
+)
+
+// Linux cooked header fields
+const (
+	sllHeaderLen       = 16
+	sllPacketHost      = 0
+	sllPacketBroadcast = 1
+	sllPacketMulticast = 2
+	sllPacketOtherHost = 3
+	sllPacketOutgoing  = 4
+)
+
+// CaptureLink is how the frames of a live interface are read and decoded.
+type CaptureLink struct {
+	LinkType layers.LinkType
+	// Cooked reads packets without their link-layer header (SOCK_DGRAM),
+	// they start at the IP header.
+	Cooked bool
+	// AllInterfaces reads every interface with its own link-layer header,
+	// NewAnySource rewrites them to Linux cooked headers.
+	AllInterfaces bool
+}
+
+// DetectLink picks the capture link of a live interface from its ARP
+// hardware type. Ethernet and loopback frames are read whole; PPP,
+// WireGuard, tun and other interfaces without an Ethernet header are read
+// cooked and decoded as raw IP. "any" is decoded as Linux cooked, which
+// keeps the source MAC of Ethernet interfaces.
+func DetectLink(iface string) (CaptureLink, error) {
+	if iface == AnyInterface {
+		return CaptureLink{LinkType: layers.LinkTypeLinuxSLL, AllInterfaces: true}, nil
+	}
+	hwType, err := hardwareType(iface)
+	if err != nil {
+		return CaptureLink{}, err
+	}
+	if hasEthernetHeader(hwType) {
+		return CaptureLink{LinkType: layers.LinkTypeEthernet}, nil
+	}
+	return CaptureLink{LinkType: layers.LinkTypeRaw, Cooked: true}, nil
+}
+
+// Source returns the frames of a socket opened for the link as they are
+// decoded.
+func (l CaptureLink) Source(src gopacket.PacketDataSource) gopacket.PacketDataSource {
+	if l.AllInterfaces {
+		return NewAnySource(src)
+	}
+	return src
+}
+
+func hardwareType(iface string) (int, error) {
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", iface, "type"))
+	if err != nil {
+		return 0, err
+	}
+	hwType, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid hardware type of %s: %w", iface, err)
+	}
+	return hwType, nil
+}
+
+func hasEthernetHeader(hwType int) bool {
+	return hwType == arphrdEther || hwType == arphrdLoopback
+}
+
+// anyInterface is what anySource needs to know about a captured interface.
+type anyInterface struct {
+	hwType int
+	mac    net.HardwareAddr
+}
+
+// anySource rewrites the frames of a socket bound to all interfaces, each
+// with its own link-layer header, to Linux cooked headers.
+type anySource struct {
+	src        gopacket.PacketDataSource
+	interfaces map[int]anyInterface
+}
+
+// NewAnySource wraps a SOCK_RAW socket bound to all interfaces. Frames of
+// Ethernet interfaces keep their source MAC, the others are taken for raw
+// IP.
+func NewAnySource(src gopacket.PacketDataSource) gopacket.PacketDataSource {
+	return &anySource{src: src, interfaces: make(map[int]anyInterface)}
+}
+
+func (s *anySource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	data, ci, err := s.src.ReadPacketData()
+	if err != nil {
+		return data, ci, err
+	}
+	iface := s.lookup(ci.InterfaceIndex)
+
+	var cooked []byte
+	if hasEthernetHeader(iface.hwType) && len(data) >= 14 {
+		// The EtherType and everything after it stay as they are
+		cooked = make([]byte, sllHeaderLen-2+len(data)-12)
+		binary.BigEndian.PutUint16(cooked[0:], ethernetPacketType(data, iface.mac))
+		binary.BigEndian.PutUint16(cooked[4:], 6)
+		copy(cooked[6:12], data[6:12])
+		copy(cooked[sllHeaderLen-2:], data[12:])
+	} else {
+		cooked = make([]byte, sllHeaderLen+len(data))
+		if len(data) > 0 {
+			switch data[0] >> 4 {
+			case 4:
+				binary.BigEndian.PutUint16(cooked[14:], uint16(layers.EthernetTypeIPv4))
+			case 6:
+				binary.BigEndian.PutUint16(cooked[14:], uint16(layers.EthernetTypeIPv6))
+			}
+		}
+		copy(cooked[sllHeaderLen:], data)
+	}
+	binary.BigEndian.PutUint16(cooked[2:], uint16(iface.hwType))
+
+	ci.CaptureLength += len(cooked) - len(data)
+	ci.Length += len(cooked) - len(data)
+	return cooked, ci, nil
+}
+
+// lookup caches the interfaces by index, looking up new ones as they
+// appear. Unknown interfaces are taken for raw IP.
+func (s *anySource) lookup(index int) anyInterface {
+	if iface, ok := s.interfaces[index]; ok {
+		return iface
+	}
+	// Not cached when gone, the index may be reused by a new interface
+	ifi, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return anyInterface{hwType: -1}
+	}
+	hwType, err := hardwareType(ifi.Name)
+	if err != nil {
+		return anyInterface{hwType: -1}
+	}
+	iface := anyInterface{hwType: hwType, mac: ifi.HardwareAddr}
+	s.interfaces[index] = iface
+	return iface
+}
+
+// ethernetPacketType tells from the MACs of a frame whom it was for, like
+// the kernel does for cooked sockets.
+func ethernetPacketType(frame []byte, own net.HardwareAddr) uint16 {
+	dst, src := net.HardwareAddr(frame[0:6]), net.HardwareAddr(frame[6:12])
+	switch {
+	case bytes.Equal(dst, layers.EthernetBroadcast):
+		return sllPacketBroadcast
+	case dst[0]&1 == 1:
+		return sllPacketMulticast
+	case len(own) == 0 || bytes.Equal(dst, own):
+		return sllPacketHost
+	case bytes.Equal(src, own):
+		return sllPacketOutgoing
+	}
+	return sllPacketOtherHost
+}
+
+// linkAddresses returns the source and destination MAC of a frame. Linux
+// cooked captures only keep the source, raw IP has neither.
+func linkAddresses(packet gopacket.Packet) (src, dst string) {
+	if l := packet.Layer(layers.LayerTypeEthernet); l != nil {
+		eth := l.(*layers.Ethernet)
+		return eth.SrcMAC.String(), eth.DstMAC.String()
+	}
+	if l := packet.Layer(layers.LayerTypeLinuxSLL); l != nil {
+		if sll := l.(*layers.LinuxSLL); sll.AddrLen == 6 {
+			return sll.Addr.String(), ""
+		}
+	}
+	if l := packet.Layer(layers.LayerTypeLinuxSLL2); l != nil {
+		if sll := l.(*layers.LinuxSLL2); sll.AddrLength == 6 {
+			return sll.Addr.String(), ""
+		}
+	}
+	return "", ""
+}
+
+// vlanIDs returns the VLAN ID of a tagged frame and, for QinQ, the ID of
+// the outer service tag.
+func vlanIDs(packet gopacket.Packet) (vlan, outer uint16) {
+	var ids []uint16
+	for _, l := range packet.Layers() {
+		if tag, ok := l.(*layers.Dot1Q); ok {
+			ids = append(ids, tag.VLANIdentifier)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return 0, 0
+	case 1:
+		return ids[0], 0
+	}
+	return ids[len(ids)-1], ids[0]
+}
@@ -0,0 +1,194 @@
+package snifpacket
+
+import (
+	"encoding/binary"
+)
+
+// Cleartext MQTT; 8883 is TLS and only yields a ClientHello
+const mqttPort = 1883
+
+// MQTT control packet types
+const (
+	mqttConnect   = 1
+	mqttPublish   = 3
+	mqttSubscribe = 8
+)
+
+// parseMQTT reads the CONNECT, PUBLISH and SUBSCRIBE packets found in a
+// segment. Packets cut off by the segment end are skipped, so a PUBLISH
+// with a large payload still gives its topic as long as the variable
+// header is complete.
+func parseMQTT(payload []byte) *SnifPacketDetailsMQTT {
+	details := &SnifPacketDetailsMQTT{}
+	found := false
+	for len(payload) >= 2 {
+		packetType := payload[0] >> 4
+		if packetType == 0 || packetType == 15 {
+			break
+		}
+		length, n := mqttVarint(payload[1:])
+		if n == 0 {
+			break
+		}
+		body := payload[1+n:]
+		whole := len(body) >= length
+		if whole {
+			body = body[:length]
+		}
+
+		switch packetType {
+		case mqttConnect:
+			if !whole || !parseMQTTConnect(body, details) {
+				return mqttResult(details, found)
+			}
+			details.Types = appendUnique(details.Types, "CONNECT")
+			found = true
+		case mqttPublish:
+			topic, ok := mqttString(body)
+			if !ok || topic == "" {
+				return mqttResult(details, found)
+			}
+			details.Types = appendUnique(details.Types, "PUBLISH")
+			details.Published = appendUnique(details.Published, topic)
+			found = true
+		case mqttSubscribe:
+			if !whole || payload[0]&0x0f != 0x02 {
+				return mqttResult(details, found)
+			}
+			topics, ok := parseMQTTSubscribe(body, details.ProtocolLevel)
+			if !ok && details.ProtocolLevel == 0 {
+				// The CONNECT was in an earlier segment, try with MQTT 5
+				// properties
+				topics, ok = parseMQTTSubscribe(body, 5)
+			}
+			if !ok {
+				return mqttResult(details, found)
+			}
+			details.Types = appendUnique(details.Types, "SUBSCRIBE")
+			for _, topic := range topics {
+				details.Subscribed = appendUnique(details.Subscribed, topic)
+			}
+			found = true
+		default:
+			// Other control packets are only used to stay in sync
+			if !found && !whole {
+				return nil
+			}
+		}
+		if !whole {
+			break
+		}
+		payload = payload[1+n+length:]
+	}
+	return mqttResult(details, found)
+}
+
+func mqttResult(details *SnifPacketDetailsMQTT, found bool) *SnifPacketDetailsMQTT {
+	if !found {
+		return nil
+	}
+	return details
+}
+
+// parseMQTTConnect reads the protocol level, flags, keep alive and client ID
+// of a CONNECT packet.
+func parseMQTTConnect(body []byte, details *SnifPacketDetailsMQTT) bool {
+	name, ok := mqttString(body)
+	if !ok || (name != "MQTT" && name != "MQIsdp") {
+		return false
+	}
+	pos := 2 + len(name)
+	if len(body) < pos+4 {
+		return false
+	}
+	level := body[pos]
+	flags := body[pos+1]
+	details.ProtocolLevel = int(level)
+	details.KeepAlive = binary.BigEndian.Uint16(body[pos+2:])
+	details.HasUsername = flags&0x80 != 0
+	details.HasPassword = flags&0x40 != 0
+	pos += 4
+	// MQTT 5 properties
+	if level == 5 {
+		propLen, n := mqttVarint(body[pos:])
+		if n == 0 || len(body) < pos+n+propLen {
+			return false
+		}
+		pos += n + propLen
+	}
+	clientID, ok := mqttString(body[pos:])
+	if !ok {
+		return false
+	}
+	details.ClientID = clientID
+	return true
+}
+
+// parseMQTTSubscribe returns the topic filters of a SUBSCRIBE packet.
+func parseMQTTSubscribe(body []byte, level int) ([]string, bool) {
+	if len(body) < 2 {
+		return nil, false
+	}
+	pos := 2
+	if level == 5 {
+		propLen, n := mqttVarint(body[pos:])
+		if n == 0 || len(body) < pos+n+propLen {
+			return nil, false
+		}
+		pos += n + propLen
+	}
+	var topics []string
+	for pos < len(body) {
+		topic, ok := mqttString(body[pos:])
+		if !ok || len(body) < pos+2+len(topic)+1 {
+			return nil, false
+		}
+		topics = append(topics, topic)
+		// Topic and its subscription options byte
+		pos += 2 + len(topic) + 1
+	}
+	return topics, len(topics) > 0
+}
+
+// mqttVarint decodes the remaining length of the fixed header and returns
+// it with its size, or a size of 0 when it is cut off or malformed.
+func mqttVarint(data []byte) (int, int) {
+	value, shift := 0, 0
+	for i := 0; i < 4 && i < len(data); i++ {
+		value |= int(data[i]&0x7f) << shift
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+		shift += 7
+	}
+	return 0, 0
+}
+
+// mqttString reads a length-prefixed UTF-8 string.
+func mqttString(data []byte) (string, bool) {
+	if len(data) < 2 {
+		return "", false
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+n {
+		return "", false
+	}
+	return string(data[2 : 2+n]), true
+}
+
+// merge adds the packets of a later segment of the same connection.
+func (d *SnifPacketDetailsMQTT) merge(o *SnifPacketDetailsMQTT) {
+	for _, t := range o.Types {
+		d.Types = appendUnique(d.Types, t)
+	}
+	for _, t := range o.Published {
+		d.Published = appendUnique(d.Published, t)
+	}
+	for _, t := range o.Subscribed {
+		d.Subscribed = appendUnique(d.Subscribed, t)
+	}
+	if o.ClientID != "" {
+		d.ClientID, d.ProtocolLevel, d.KeepAlive = o.ClientID, o.ProtocolLevel, o.KeepAlive
+		d.HasUsername, d.HasPassword = o.HasUsername, o.HasPassword
+	}
+}
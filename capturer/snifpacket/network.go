@@ -0,0 +1,216 @@
+package snifpacket
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// Protocol labels of packets without TCP or UDP; other protocols keep the
+// gopacket name or their number
+var ipProtocolLabels = map[layers.IPProtocol]string{
+	layers.IPProtocolICMPv4: "ICMP",
+	layers.IPProtocolICMPv6: "ICMPv6",
+	layers.IPProtocolESP:    "ESP",
+	layers.IPProtocolAH:     "AH",
+}
+
+var icmpv4Names = map[uint8]string{
+	layers.ICMPv4TypeEchoReply:              "echo-reply",
+	layers.ICMPv4TypeDestinationUnreachable: "destination-unreachable",
+	layers.ICMPv4TypeRedirect:               "redirect",
+	layers.ICMPv4TypeEchoRequest:            "echo-request",
+	layers.ICMPv4TypeRouterAdvertisement:    "router-advertisement",
+	layers.ICMPv4TypeRouterSolicitation:     "router-solicitation",
+	layers.ICMPv4TypeTimeExceeded:           "time-exceeded",
+	layers.ICMPv4TypeParameterProblem:       "parameter-problem",
+}
+
+var icmpv6Names = map[uint8]string{
+	layers.ICMPv6TypeDestinationUnreachable:                "destination-unreachable",
+	layers.ICMPv6TypePacketTooBig:                          "packet-too-big",
+	layers.ICMPv6TypeTimeExceeded:                          "time-exceeded",
+	layers.ICMPv6TypeParameterProblem:                      "parameter-problem",
+	layers.ICMPv6TypeEchoRequest:                           "echo-request",
+	layers.ICMPv6TypeEchoReply:                             "echo-reply",
+	layers.ICMPv6TypeMLDv1MulticastListenerQueryMessage:    "mld-query",
+	layers.ICMPv6TypeMLDv1MulticastListenerReportMessage:   "mld-report",
+	layers.ICMPv6TypeMLDv1MulticastListenerDoneMessage:     "mld-done",
+	layers.ICMPv6TypeMLDv2MulticastListenerReportMessageV2: "mld-report",
+	layers.ICMPv6TypeRouterSolicitation:                    "router-solicitation",
+	layers.ICMPv6TypeRouterAdvertisement:                   "router-advertisement",
+	layers.ICMPv6TypeNeighborSolicitation:                  "neighbor-solicitation",
+	layers.ICMPv6TypeNeighborAdvertisement:                 "neighbor-advertisement",
+	layers.ICMPv6TypeRedirect:                              "redirect",
+}
+
+// processARP turns an ARP request or reply into a packet from its sender.
+// ARP has no IP layer, the sender and target addresses take its place.
+func processARP(srcMAC, dstMAC string, arp *layers.ARP, ts int64) (*SnifPacket, bool) {
+	if arp.Protocol != layers.EthernetTypeIPv4 || arp.HwAddressSize != 6 || arp.ProtAddressSize != 4 {
+		return nil, false
+	}
+	details := &SnifPacketDetailsARP{
+		SenderMAC: net.HardwareAddr(arp.SourceHwAddress).String(),
+		SenderIP:  net.IP(arp.SourceProtAddress).String(),
+		TargetMAC: net.HardwareAddr(arp.DstHwAddress).String(),
+		TargetIP:  net.IP(arp.DstProtAddress).String(),
+	}
+	switch arp.Operation {
+	case layers.ARPRequest:
+		details.Operation = "who-has"
+	case layers.ARPReply:
+		details.Operation = "is-at"
+	default:
+		return nil, false
+	}
+	sp := &SnifPacket{
+		SrcIP:     details.SenderIP,
+		DstIP:     details.TargetIP,
+		SrcMAC:    srcMAC,
+		DstMAC:    dstMAC,
+		Size:      len(arp.Contents),
+		Protocol:  "ARP",
+		Timestamp: ts,
+	}
+	sp.Details.ARP = details
+	sp.Details.Type = SnifPacketTypeARP
+	return sp, true
+}
+
+// processIP labels packets carrying neither TCP nor UDP: ICMP and ICMPv6
+// with their type and code, ESP and AH with their SPI, anything else with
+// its protocol name.
+func processIP(sp *SnifPacket, packet gopacket.Packet) {
+	proto := ipProtocol(packet)
+	sp.Size = len(packet.NetworkLayer().LayerPayload())
+	sp.Protocol = ipProtocolLabel(proto)
+	sp.Details.Type = SnifPacketTypeIP
+
+	switch {
+	case proto == layers.IPProtocolTCP:
+		// Fragments after the first carry no TCP or UDP header
+		sp.Details.Type = SnifPacketTypeTCP
+	case proto == layers.IPProtocolUDP:
+		sp.Details.Type = SnifPacketTypeUDP
+	case packet.Layer(layers.LayerTypeICMPv4) != nil:
+		icmp := packet.Layer(layers.LayerTypeICMPv4).(*layers.ICMPv4)
+		t, c := icmp.TypeCode.Type(), icmp.TypeCode.Code()
+		sp.Details.ICMP = &SnifPacketDetailsICMP{Version: 4, Type: t, Code: c, Name: icmpv4Names[t]}
+		sp.Details.Type = SnifPacketTypeICMP
+	case packet.Layer(layers.LayerTypeICMPv6) != nil:
+		icmp := packet.Layer(layers.LayerTypeICMPv6).(*layers.ICMPv6)
+		t, c := icmp.TypeCode.Type(), icmp.TypeCode.Code()
+		details := &SnifPacketDetailsICMP{Version: 6, Type: t, Code: c, Name: icmpv6Names[t]}
+		neighbourDiscovery(packet, details)
+		sp.Details.ICMP = details
+		sp.Details.Type = SnifPacketTypeICMP
+	case packet.Layer(layers.LayerTypeIPSecESP) != nil:
+		esp := packet.Layer(layers.LayerTypeIPSecESP).(*layers.IPSecESP)
+		sp.Details.IPsec = &SnifPacketDetailsIPsec{Protocol: "ESP", SPI: esp.SPI, Seq: esp.Seq}
+		sp.Details.Type = SnifPacketTypeIPsec
+	case packet.Layer(layers.LayerTypeIPSecAH) != nil:
+		ah := packet.Layer(layers.LayerTypeIPSecAH).(*layers.IPSecAH)
+		sp.Details.IPsec = &SnifPacketDetailsIPsec{Protocol: "AH", SPI: ah.SPI, Seq: ah.Seq}
+		sp.Details.Type = SnifPacketTypeIPsec
+	}
+}
+
+// neighbourDiscovery adds the address a solicitation asks about or an
+// advertisement announces, and the MAC of the link-layer address option.
+func neighbourDiscovery(packet gopacket.Packet, details *SnifPacketDetailsICMP) {
+	var options layers.ICMPv6Options
+	var want layers.ICMPv6Opt = layers.ICMPv6OptSourceAddress
+	if l := packet.Layer(layers.LayerTypeICMPv6NeighborSolicitation); l != nil {
+		ns := l.(*layers.ICMPv6NeighborSolicitation)
+		details.TargetIP = ns.TargetAddress.String()
+		options = ns.Options
+	} else if l := packet.Layer(layers.LayerTypeICMPv6NeighborAdvertisement); l != nil {
+		na := l.(*layers.ICMPv6NeighborAdvertisement)
+		details.TargetIP = na.TargetAddress.String()
+		options = na.Options
+		want = layers.ICMPv6OptTargetAddress
+	} else if l := packet.Layer(layers.LayerTypeICMPv6RouterSolicitation); l != nil {
+		options = l.(*layers.ICMPv6RouterSolicitation).Options
+	} else if l := packet.Layer(layers.LayerTypeICMPv6RouterAdvertisement); l != nil {
+		options = l.(*layers.ICMPv6RouterAdvertisement).Options
+	}
+	for _, o := range options {
+		if o.Type == want && len(o.Data) == 6 {
+			details.LinkMAC = net.HardwareAddr(o.Data).String()
+		}
+	}
+}
+
+// isNeighbourDiscovery reports whether the message is an IPv6 router or
+// neighbour solicitation or advertisement.
+func (d *SnifPacketDetailsICMP) isNeighbourDiscovery() bool {
+	return d.Version == 6 && d.Type >= layers.ICMPv6TypeRouterSolicitation && d.Type <= layers.ICMPv6TypeNeighborAdvertisement
+}
+
+// AddressBinding returns the MAC and IP address a packet announces for its
+// sender: the sender of an ARP message, or the source or advertised target
+// of IPv6 neighbour discovery. source is "arp" or "ndp".
+func (sp *SnifPacket) AddressBinding() (mac, ip, source string, ok bool) {
+	if a := sp.Details.ARP; a != nil {
+		// Probes are sent before the address is taken
+		if a.SenderIP == "0.0.0.0" {
+			return "", "", "", false
+		}
+		return a.SenderMAC, a.SenderIP, "arp", true
+	}
+	d := sp.Details.ICMP
+	if d == nil || !d.isNeighbourDiscovery() {
+		return "", "", "", false
+	}
+	if d.Type == layers.ICMPv6TypeNeighborAdvertisement {
+		// Solicited advertisements may leave out the target address option
+		mac = d.LinkMAC
+		if mac == "" {
+			mac = sp.SrcMAC
+		}
+		return mac, d.TargetIP, "ndp", mac != ""
+	}
+	// Solicitations from the unspecified address are duplicate address
+	// detection
+	if d.LinkMAC == "" || sp.SrcIP == "::" {
+		return "", "", "", false
+	}
+	return d.LinkMAC, sp.SrcIP, "ndp", true
+}
+
+// ipProtocol returns the protocol an IP packet carries, after any IPv6
+// extension headers.
+func ipProtocol(packet gopacket.Packet) layers.IPProtocol {
+	if ip := packet.Layer(layers.LayerTypeIPv4); ip != nil {
+		return ip.(*layers.IPv4).Protocol
+	}
+	var proto layers.IPProtocol
+	for _, l := range packet.Layers() {
+		switch h := l.(type) {
+		case *layers.IPv6:
+			proto = h.NextHeader
+		case *layers.IPv6HopByHop:
+			proto = h.NextHeader
+		case *layers.IPv6Routing:
+			proto = h.NextHeader
+		case *layers.IPv6Fragment:
+			proto = h.NextHeader
+		case *layers.IPv6Destination:
+			proto = h.NextHeader
+		}
+	}
+	return proto
+}
+
+func ipProtocolLabel(proto layers.IPProtocol) string {
+	if label, ok := ipProtocolLabels[proto]; ok {
+		return label
+	}
+	if name := proto.String(); name != "UnknownIPProtocol" {
+		return name
+	}
+	return "IP-" + strconv.Itoa(int(proto))
+}
@@ -9,6 +9,37 @@ const (
 	SnifPacketTypeFTP
 	SnifPacketTypeTCP
 	SnifPacketTypeUDP
+	SnifPacketTypeQUIC
+	SnifPacketTypeDHCP
+	SnifPacketTypeMDNS
+	SnifPacketTypeSSDP
+	SnifPacketTypeMQTT
+	SnifPacketTypeCoAP
+	SnifPacketTypeICMP
+	SnifPacketTypeARP
+	SnifPacketTypeIPsec
+	// Other protocols over IP, named by Protocol
+	SnifPacketTypeIP
+)
+
+// SnifPacketDirection tells which way a packet travels relative to the
+// local device. Packets from older capturers carry no direction and are
+// treated as uploads.
+type SnifPacketDirection string
+
+const (
+	SnifPacketDirectionUp   SnifPacketDirection = "up"
+	SnifPacketDirectionDown SnifPacketDirection = "down"
+	// Both ends are in the home networks
+	SnifPacketDirectionLAN  SnifPacketDirection = "lan"
+)
+
+const (
+	TCPFlagFIN uint8 = 1 << iota
+	TCPFlagSYN
+	TCPFlagRST
+	TCPFlagPSH
+	TCPFlagACK
 )
 
 type SnifPacketDetailsHTTP struct {
@@ -17,21 +48,188 @@ type SnifPacketDetailsHTTP struct {
 	Path       string                  `json:"path"`
 	Body       string                  `json:"body"`
 	Sni        string                  `json:"sni"`
+	// Responses carry the status code instead of method, host and path
+	IsResponse bool                    `json:"is_response,omitempty"`
+	StatusCode int                     `json:"status_code,omitempty"`
+	UserAgent  string                  `json:"user_agent,omitempty"`
+	ContentType string                 `json:"content_type,omitempty"`
+	ContentLength int64                `json:"content_length,omitempty"`
+	// BodySize is only set with HTTP body size accounting enabled
+	BodySize   int64                   `json:"body_size,omitempty"`
 }
 
 type SnifPacketDetailsTLS struct {
 	Sni        string                  `json:"sni"`
+	// TLSVersion is the highest version in supported_versions, if sent
 	TLSVersion string                  `json:"tls_version"`
+	JA3        string                  `json:"ja3,omitempty"`
+	JA4        string                  `json:"ja4,omitempty"`
+}
+
+type SnifPacketDNSQuestion struct {
+	Name       string                  `json:"name"`
+	Type       string                  `json:"type"`
+}
+
+// SnifPacketDNSAnswer is an A, AAAA, CNAME, SVCB or HTTPS record. Data
+// holds the address, the canonical name or the SVCB presentation form.
+type SnifPacketDNSAnswer struct {
+	Name       string                  `json:"name"`
+	Type       string                  `json:"type"`
+	TTL        uint32                  `json:"ttl"`
+	Data       string                  `json:"data"`
+	// IPHints are the ipv4hint/ipv6hint addresses of SVCB and HTTPS records
+	IPHints    []string                `json:"ip_hints,omitempty"`
+}
+
+type SnifPacketTLSCertificate struct {
+	Subject    string                  `json:"subject"`
+	Issuer     string                  `json:"issuer"`
+	// SANs holds the DNS names and IP addresses of the certificate
+	SANs       []string                `json:"sans,omitempty"`
+	NotBefore  int64                   `json:"not_before"`
+	NotAfter   int64                   `json:"not_after"`
+	SelfSigned bool                    `json:"self_signed"`
+	SignatureAlgorithm string          `json:"signature_algorithm"`
+}
+
+// SnifPacketDetailsTLSServer is what the server chose in its ServerHello.
+// The certificate is only visible up to TLS 1.2.
+type SnifPacketDetailsTLSServer struct {
+	Version     string                    `json:"version"`
+	CipherSuite uint16                    `json:"cipher_suite"`
+	Cipher      string                    `json:"cipher"`
+	ALPN        string                    `json:"alpn,omitempty"`
+	Certificate *SnifPacketTLSCertificate `json:"certificate,omitempty"`
 }
 
 type SnifPacketDetailsDNS struct {
 	Queries    []string                `json:"queries"`
 	IsQuery    bool                    `json:"is_query"`
+	ID         uint16                  `json:"id,omitempty"`
+	Questions  []SnifPacketDNSQuestion `json:"questions,omitempty"`
+	Answers    []SnifPacketDNSAnswer   `json:"answers,omitempty"`
+	// RCode is set on responses, e.g. NOERROR or NXDOMAIN
+	RCode      string                  `json:"rcode,omitempty"`
+}
+
+type SnifPacketDetailsQUIC struct {
+	Sni        string                  `json:"sni"`
+	ALPN       []string                `json:"alpn,omitempty"`
+	// Version is v1, v2 or draft-29
+	Version    string                  `json:"version"`
+	JA3        string                  `json:"ja3,omitempty"`
+	JA4        string                  `json:"ja4,omitempty"`
 }
 
+// SnifPacketDetailsDHCP describes a DHCPv4 or DHCPv6 client message.
+type SnifPacketDetailsDHCP struct {
+	Version     int                    `json:"version"`
+	MessageType string                 `json:"message_type"`
+	ClientMAC   string                 `json:"client_mac,omitempty"`
+	ClientIP    string                 `json:"client_ip,omitempty"`
+	RequestedIP string                 `json:"requested_ip,omitempty"`
+	Hostname    string                 `json:"hostname,omitempty"`
+	VendorClass string                 `json:"vendor_class,omitempty"`
+	// ParamRequestList is option 55 (ORO for DHCPv6) as comma-separated
+	// codes, which tells operating systems apart
+	ParamRequestList string            `json:"param_request_list,omitempty"`
+}
+
+// SnifPacketDetailsMDNS is what an mDNS response announces about its sender.
+type SnifPacketDetailsMDNS struct {
+	Hostname     string                `json:"hostname,omitempty"`
+	// Service types such as _ipp._tcp and the instance names offering them
+	Services     []string              `json:"services,omitempty"`
+	Instances    []string              `json:"instances,omitempty"`
+	// From TXT keys such as md, usb_MFG or fv
+	Model        string                `json:"model,omitempty"`
+	Manufacturer string                `json:"manufacturer,omitempty"`
+	Version      string                `json:"version,omitempty"`
+}
+
+// SnifPacketDetailsSSDP holds the headers of an SSDP message.
+type SnifPacketDetailsSSDP struct {
+	// Method is NOTIFY, M-SEARCH or RESPONSE
+	Method    string                   `json:"method"`
+	Server    string                   `json:"server,omitempty"`
+	UserAgent string                   `json:"user_agent,omitempty"`
+	USN       string                   `json:"usn,omitempty"`
+	Location  string                   `json:"location,omitempty"`
+	// Target is the NT or ST header
+	Target    string                   `json:"target,omitempty"`
+}
+
+// SnifPacketDetailsMQTT sums up the cleartext MQTT control packets of a
+// segment.
+type SnifPacketDetailsMQTT struct {
+	// Types lists the parsed packets: CONNECT, PUBLISH and SUBSCRIBE
+	Types         []string             `json:"types"`
+	ClientID      string               `json:"client_id,omitempty"`
+	ProtocolLevel int                  `json:"protocol_level,omitempty"`
+	KeepAlive     uint16               `json:"keep_alive,omitempty"`
+	// Only the presence of credentials is recorded, never their values
+	HasUsername   bool                 `json:"has_username,omitempty"`
+	HasPassword   bool                 `json:"has_password,omitempty"`
+	Published     []string             `json:"published,omitempty"`
+	Subscribed    []string             `json:"subscribed,omitempty"`
+}
+
+// SnifPacketDetailsCoAP describes a CoAP request or response.
+type SnifPacketDetailsCoAP struct {
+	// Type is CON, NON, ACK or RST
+	Type          string               `json:"type"`
+	// Method is GET, POST, PUT, ... or the response code such as 2.05
+	Method        string               `json:"method"`
+	URIHost       string               `json:"uri_host,omitempty"`
+	URIPath       string               `json:"uri_path,omitempty"`
+}
+
+// SnifPacketDetailsFTP describes a control connection command or reply, or
+// marks a segment of a data connection. Passwords are never kept.
 type SnifPacketDetailsFTP struct {
 	Command    string                  `json:"command"`
 	Args       string                  `json:"args"`
+	// ResponseCode is set on server replies instead of Command
+	ResponseCode int                   `json:"response_code,omitempty"`
+	// DataEndpoint is the address announced by PORT/EPRT or a PASV/EPSV
+	// reply for the next data connection
+	DataEndpoint string                `json:"data_endpoint,omitempty"`
+	// Data marks segments of a data connection
+	Data       bool                    `json:"data,omitempty"`
+	// Session is the control connection, "client:port>server:port"
+	Session    string                  `json:"session,omitempty"`
+}
+
+// SnifPacketDetailsICMP describes an ICMP or ICMPv6 message. Neighbour
+// discovery messages also carry the address they are about and the MAC of
+// their link-layer address option.
+type SnifPacketDetailsICMP struct {
+	Version    int                     `json:"version"`
+	Type       uint8                   `json:"type"`
+	Code       uint8                   `json:"code"`
+	// Name is the message type, e.g. echo-request or neighbor-solicitation
+	Name       string                  `json:"name,omitempty"`
+	TargetIP   string                  `json:"target_ip,omitempty"`
+	LinkMAC    string                  `json:"link_mac,omitempty"`
+}
+
+// SnifPacketDetailsARP is an ARP request or reply for IPv4 over Ethernet.
+type SnifPacketDetailsARP struct {
+	// Operation is who-has or is-at
+	Operation  string                  `json:"operation"`
+	SenderMAC  string                  `json:"sender_mac"`
+	SenderIP   string                  `json:"sender_ip"`
+	TargetMAC  string                  `json:"target_mac"`
+	TargetIP   string                  `json:"target_ip"`
+}
+
+// SnifPacketDetailsIPsec holds the security association of an ESP or AH
+// packet; the payload itself is encrypted.
+type SnifPacketDetailsIPsec struct {
+	Protocol   string                  `json:"protocol"`
+	SPI        uint32                  `json:"spi"`
+	Seq        uint32                  `json:"seq"`
 }
 
 type SnifPacketDetailsTCP struct {
@@ -42,6 +240,18 @@ type SnifPacketDetailsUDP struct {
 	Data 	   []byte                  `json:"data"`
 }
 
+// SnifPacketFlow carries the counters of an aggregated flow. Flow records
+// are oriented from the local device: "up" is device to remote.
+type SnifPacketFlow struct {
+	Start       int64                   `json:"start"`
+	End         int64                   `json:"end"`
+	UpBytes     uint64                  `json:"up_bytes"`
+	DownBytes   uint64                  `json:"down_bytes"`
+	UpPackets   uint64                  `json:"up_packets"`
+	DownPackets uint64                  `json:"down_packets"`
+	EndReason   string                  `json:"end_reason"`
+}
+
 type SnifPacketDetails struct {
 	HTTP       *SnifPacketDetailsHTTP  `json:"http,omitempty"`
 	TLS        *SnifPacketDetailsTLS   `json:"tls,omitempty"`
@@ -49,6 +259,16 @@ type SnifPacketDetails struct {
 	FTP        *SnifPacketDetailsFTP   `json:"ftp,omitempty"`
 	TCP 	     *SnifPacketDetailsTCP   `json:"tcp,omitempty"`
 	UDP        *SnifPacketDetailsUDP   `json:"udp,omitempty"`
+	QUIC       *SnifPacketDetailsQUIC  `json:"quic,omitempty"`
+	TLSServer  *SnifPacketDetailsTLSServer `json:"tls_server,omitempty"`
+	DHCP       *SnifPacketDetailsDHCP  `json:"dhcp,omitempty"`
+	MDNS       *SnifPacketDetailsMDNS  `json:"mdns,omitempty"`
+	SSDP       *SnifPacketDetailsSSDP  `json:"ssdp,omitempty"`
+	MQTT       *SnifPacketDetailsMQTT  `json:"mqtt,omitempty"`
+	CoAP       *SnifPacketDetailsCoAP  `json:"coap,omitempty"`
+	ICMP       *SnifPacketDetailsICMP  `json:"icmp,omitempty"`
+	ARP        *SnifPacketDetailsARP   `json:"arp,omitempty"`
+	IPsec      *SnifPacketDetailsIPsec `json:"ipsec,omitempty"`
 	Type       SnifPacketType          `json:"type"`
 }
 
@@ -63,4 +283,38 @@ type SnifPacket struct {
 	Protocol   string                  `json:"protocol"`
 	Details    SnifPacketDetails       `json:"details"`
 	Timestamp  int64                   `json:"timestamp"`
+	Direction  SnifPacketDirection     `json:"direction,omitempty"`
+	TCPFlags   uint8                   `json:"tcp_flags,omitempty"`
+	Flow       *SnifPacketFlow         `json:"flow,omitempty"`
+	Interface  string                  `json:"interface,omitempty"`
+	VLAN       uint16                  `json:"vlan,omitempty"`
+	OuterVLAN  uint16                  `json:"outer_vlan,omitempty"`
+}
+
+func (sp *SnifPacket) IsDownload() bool {
+	return sp.Direction == SnifPacketDirectionDown
+}
+
+// DeviceMAC returns the MAC of the local device the packet belongs to.
+func (sp *SnifPacket) DeviceMAC() string {
+	if sp.IsDownload() {
+		return sp.DstMAC
+	}
+	return sp.SrcMAC
+}
+
+// DeviceIP returns the IP of the local device the packet belongs to.
+func (sp *SnifPacket) DeviceIP() string {
+	if sp.IsDownload() {
+		return sp.DstIP
+	}
+	return sp.SrcIP
+}
+
+// RemoteIP returns the IP of the peer outside the local network.
+func (sp *SnifPacket) RemoteIP() string {
+	if sp.IsDownload() {
+		return sp.SrcIP
+	}
+	return sp.DstIP
 }
\ No newline at end of file
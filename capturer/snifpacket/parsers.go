@@ -2,24 +2,9 @@ package snifpacket
 
 import (
 	"encoding/binary"
-	"fmt"
 	"net"
-
-	"github.com/gopacket/gopacket/layers"
 )
 
-func parseDNS(dns *layers.DNS, src, dst net.IP) *SnifPacketDetailsDNS {
-	if !dns.QR && dns.OpCode == layers.DNSOpCodeQuery {
-		for _, q := range dns.Questions {
-			return &SnifPacketDetailsDNS{
-				Queries:  []string{string(q.Name)},
-				IsQuery:  true,
-			}
-		}
-	}
-	return nil
-}
-
 func parseHTTP(payload []byte, src, dst net.IP, size int) *SnifPacketDetailsHTTP {
 	if len(payload) == 0 {
 		return nil
@@ -97,15 +82,18 @@ func parseHTTP(payload []byte, src, dst net.IP, size int) *SnifPacketDetailsHTTP
 		sni = host
 	}
 
+	// The body itself is never kept
 	body = ""
 
-	return &SnifPacketDetailsHTTP{
+	details := &SnifPacketDetailsHTTP{
 		Method: method,
 		Host:   host,
 		Path:   path,
 		Body:   body,
 		Sni:    sni,
 	}
+	fillHTTPHeaders(details, payload)
+	return details
 }
 
 func parseTLSClientHello(payload []byte, src, dst net.IP, size int) *SnifPacketDetailsTLS {
@@ -120,55 +108,84 @@ func parseTLSClientHello(payload []byte, src, dst net.IP, size int) *SnifPacketD
 		return nil
 	}
 
-	var tlsVer string
-	if len(payload) >= 11 {
-		vMajor := payload[9]
-		vMinor := payload[10]
-		switch {
-		case vMajor == 3 && vMinor == 0:
-			tlsVer = "SSL 3.0"
-		case vMajor == 3 && vMinor == 1:
-			tlsVer = "TLS 1.0"
-		case vMajor == 3 && vMinor == 2:
-			tlsVer = "TLS 1.1"
-		case vMajor == 3 && vMinor == 3:
-			tlsVer = "TLS 1.2"
-		case vMajor == 3 && vMinor == 4:
-			tlsVer = "TLS 1.3"
-		default:
-			tlsVer = fmt.Sprintf("0x%02x%02x", vMajor, vMinor)
-		}
+	ch, ok := parseClientHello(payload)
+	if !ok {
+		return nil
 	}
 
-	sni := extractSNI(payload)
-	return &SnifPacketDetailsTLS{
-		Sni:        sni,
-		TLSVersion: tlsVer,
+	details := &SnifPacketDetailsTLS{
+		Sni:        extractSNI(payload),
+		TLSVersion: tlsVersionName(ch.version()),
+	}
+	// Fingerprints of a cut-off hello would not match anything
+	helloLen := int(payload[6])<<16 | int(payload[7])<<8 | int(payload[8])
+	if len(payload) >= 9+helloLen {
+		details.JA3 = ch.ja3()
+		details.JA4 = ch.ja4('t')
 	}
+	return details
 }
 
 func extractSNI(data []byte) string {
-	if len(data) < 43 {
-		return ""
-	}
+	sni := ""
+	walkExtensions(data, func(extType uint16, ext []byte) bool {
+		if extType != 0x00 {
+			return true
+		}
+		if len(ext) >= 5 {
+			nameLen := int(binary.BigEndian.Uint16(ext[3:]))
+			if 5+nameLen <= len(ext) {
+				sni = string(ext[5 : 5+nameLen])
+			}
+		}
+		return false
+	})
+	return sni
+}
+
+// extractALPN returns the protocols offered in the ALPN extension.
+func extractALPN(data []byte) []string {
+	var protos []string
+	walkExtensions(data, func(extType uint16, ext []byte) bool {
+		if extType != 0x10 {
+			return true
+		}
+		if len(ext) < 2 {
+			return false
+		}
+		list := ext[2:]
+		for len(list) > 0 && len(list) >= 1+int(list[0]) {
+			protos = append(protos, string(list[1:1+int(list[0])]))
+			list = list[1+int(list[0]):]
+		}
+		return false
+	})
+	return protos
+}
 
+// walkExtensions calls fn for each extension of the ClientHello record in
+// data until fn returns false. A truncated last extension is cut short.
+func walkExtensions(data []byte, fn func(extType uint16, ext []byte) bool) {
 	sessionIDLenOffset := 43
 	if len(data) < sessionIDLenOffset+1 {
-		return ""
+		return
 	}
 
 	sessionIDLen := int(data[sessionIDLenOffset])
-	extStart := sessionIDLenOffset + 1 + sessionIDLen
-
-	if len(data) < extStart+2 {
-		return ""
+	csStart := sessionIDLenOffset + 1 + sessionIDLen
+	if len(data) < csStart+2 {
+		return
 	}
 
-	csLen := int(binary.BigEndian.Uint16(data[extStart:]))
-	extPos := extStart + 2 + csLen + 2
+	csLen := int(binary.BigEndian.Uint16(data[csStart:]))
+	compStart := csStart + 2 + csLen
+	if len(data) < compStart+1 {
+		return
+	}
 
+	extPos := compStart + 1 + int(data[compStart])
 	if len(data) < extPos+2 {
-		return ""
+		return
 	}
 
 	extTotalLen := int(binary.BigEndian.Uint16(data[extPos:]))
@@ -184,17 +201,86 @@ func extractSNI(data []byte) string {
 		extLen := int(binary.BigEndian.Uint16(data[p+2:]))
 		p += 4
 
-		if extType == 0x00 {
-			if p+5 <= end {
-				nameLen := int(binary.BigEndian.Uint16(data[p+3:]))
-				if p+5+nameLen <= end {
-					return string(data[p+5 : p+5+nameLen])
-				}
-			}
-			return ""
+		extEnd := p + extLen
+		if extEnd > end {
+			extEnd = end
+		}
+		if !fn(extType, data[p:extEnd]) {
+			return
 		}
 		p += extLen
 	}
+}
+
+// httpHeaderComplete reports whether data holds the full header block of
+// an HTTP request or response. ok is false once data can't be either.
+func httpHeaderComplete(data []byte) (complete bool, ok bool) {
+	if len(data) >= 5 && string(data[:5]) == "HTTP/" {
+		return indexOf(data, []byte("\r\n\r\n")) >= 0, true
+	}
+	// Request methods are upper case tokens followed by a space
+	for i, c := range data {
+		if c == ' ' && i > 0 {
+			break
+		}
+		if c < 'A' || c > 'Z' || i >= 16 {
+			return false, false
+		}
+	}
+	return indexOf(data, []byte("\r\n\r\n")) >= 0, true
+}
+
+// tlsClientHelloRecord joins the handshake fragments of the TLS records at
+// the start of data and returns them as a single record, the layout
+// parseTLSClientHello expects. ok is false when data is not a ClientHello.
+func tlsClientHelloRecord(data []byte) (record []byte, complete bool, ok bool) {
+	if len(data) == 0 {
+		return nil, false, true
+	}
+	if data[0] != 0x16 {
+		return nil, false, false
+	}
+	if len(data) < 5 {
+		return nil, false, true
+	}
+
+	// Common case: the whole ClientHello in the first record
+	recordLen := int(binary.BigEndian.Uint16(data[3:]))
+	if len(data) >= 9 && data[5] == 0x01 {
+		helloLen := 4 + (int(data[6])<<16 | int(data[7])<<8 | int(data[8]))
+		if helloLen <= recordLen && len(data) >= 5+recordLen {
+			return data[:5+recordLen], true, true
+		}
+	}
+
+	var handshake []byte
+	for pos := 0; pos+5 <= len(data) && data[pos] == 0x16; {
+		n := int(binary.BigEndian.Uint16(data[pos+3:]))
+		avail := len(data) - pos - 5
+		if avail > n {
+			avail = n
+		}
+		handshake = append(handshake, data[pos+5:pos+5+avail]...)
+		if avail < n {
+			break
+		}
+		pos += 5 + n
+	}
 
-	return ""
-}
\ No newline at end of file
+	if len(handshake) > 0 && handshake[0] != 0x01 {
+		return nil, false, false
+	}
+	if len(handshake) < 4 {
+		return nil, false, true
+	}
+
+	need := 4 + (int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3]))
+	if len(handshake) > need {
+		handshake = handshake[:need]
+	}
+
+	record = make([]byte, 0, 5+len(handshake))
+	record = append(record, data[0], data[1], data[2], byte(len(handshake)>>8), byte(len(handshake)))
+	record = append(record, handshake...)
+	return record, len(handshake) >= need, true
+}
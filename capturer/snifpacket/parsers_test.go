@@ -0,0 +1,81 @@
+package snifpacket
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// testClientHello returns the first TLS record a Go client sends for sni.
+func testClientHello(t *testing.T, sni string) []byte {
+	t.Helper()
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: sni}).Handshake()
+		client.Close()
+	}()
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(server, header); err != nil {
+		t.Fatalf("reading record header: %v", err)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[3:]))
+	if _, err := io.ReadFull(server, body); err != nil {
+		t.Fatalf("reading record body: %v", err)
+	}
+	return append(header, body...)
+}
+
+func TestTLSClientHelloRecordTruncated(t *testing.T) {
+	hello := testClientHello(t, "example.com")
+
+	// The first segment of a ClientHello larger than one segment
+	if _, complete, ok := tlsClientHelloRecord(hello[:len(hello)/2]); !ok || complete {
+		t.Fatalf("half a ClientHello: complete=%v ok=%v, want incomplete", complete, ok)
+	}
+
+	// A record longer than the segment, with a handshake length that used
+	// to be ORed instead of added
+	bad := make([]byte, 514)
+	copy(bad, []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00})
+	if record, _, _ := tlsClientHelloRecord(bad); len(record) > len(bad) {
+		t.Fatalf("record of %d bytes from %d bytes of data", len(record), len(bad))
+	}
+
+	// Lengths beyond the data in every header field
+	for i := 9; i < 40; i++ {
+		data := []byte{0x16, 0x03, 0x01, 0x02, 0xfa, 0x01, 0x00, 0x02, 0xf6}
+		data = append(data, make([]byte, i)...)
+		tlsClientHelloRecord(data)
+	}
+}
+
+func TestTLSClientHelloRecordSplit(t *testing.T) {
+	hello := testClientHello(t, "example.com")
+	body := hello[5:]
+
+	// The same handshake message split into two records
+	half := len(body) / 2
+	var split []byte
+	for _, part := range [][]byte{body[:half], body[half:]} {
+		split = append(split, 0x16, hello[1], hello[2], byte(len(part)>>8), byte(len(part)))
+		split = append(split, part...)
+	}
+
+	record, complete, ok := tlsClientHelloRecord(split)
+	if !ok || !complete {
+		t.Fatalf("split ClientHello: complete=%v ok=%v, want complete", complete, ok)
+	}
+	details := parseTLSClientHello(record, net.IPv4(10, 0, 0, 2), net.IPv4(1, 1, 1, 1), len(record))
+	if details == nil || details.Sni != "example.com" {
+		t.Fatalf("split ClientHello: got %+v, want SNI example.com", details)
+	}
+
+	// Only the first record arrived so far
+	if _, complete, ok := tlsClientHelloRecord(split[:5+half]); !ok || complete {
+		t.Fatalf("first record only: complete=%v ok=%v, want incomplete", complete, ok)
+	}
+}
@@ -3,16 +3,58 @@ package snifpacket
 import (
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/gopacket/gopacket"
 	"github.com/gopacket/gopacket/layers"
 )
 
+type ProcessorOptions struct {
+	// HTTPBodySizes reports the body size of HTTP messages, the body
+	// itself is never kept.
+	HTTPBodySizes bool
+	// Dissectors selects the dissectors and the ports they run on.
+	Dissectors DissectorOptions
+}
+
+// Processor turns captured frames into SnifPackets. With a reassembler it
+// also parses HTTP requests and ClientHellos split across TCP segments.
+type Processor struct {
+	reasm      *Reassembler
+	ftp        *ftpTracker
+	dissectors *dissectorSet
+	opts       ProcessorOptions
+}
+
+// NewProcessor creates a processor; a nil reassembler parses single
+// segments only.
+func NewProcessor(reasm *Reassembler, opts ProcessorOptions) *Processor {
+	return &Processor{
+		reasm:      reasm,
+		ftp:        newFTPTracker(),
+		dissectors: newDissectorSet(opts.Dissectors),
+		opts:       opts,
+	}
+}
+
+// ProcessPacket parses a single frame without any stream state.
 func ProcessPacket(packet gopacket.Packet) (*SnifPacket, error) {
-	// Ethernet
-	ethLayer := packet.Layer(layers.LayerTypeEthernet)
-	if ethLayer == nil {
-		return nil, fmt.Errorf("no ethernet layer found")
+	return (&Processor{dissectors: newDissectorSet(DissectorOptions{})}).Process(packet)
+}
+
+func (p *Processor) Process(packet gopacket.Packet) (*SnifPacket, error) {
+	// Link layer: Ethernet, Linux cooked or none at all for raw IP
+	srcMAC, dstMAC := linkAddresses(packet)
+	vlan, outerVLAN := vlanIDs(packet)
+
+	// ARP has no IP layer
+	if arp := packet.Layer(layers.LayerTypeARP); arp != nil {
+		sp, ok := processARP(srcMAC, dstMAC, arp.(*layers.ARP), packet.Metadata().Timestamp.Unix())
+		if !ok {
+			return nil, fmt.Errorf("unsupported ARP packet")
+		}
+		sp.VLAN, sp.OuterVLAN = vlan, outerVLAN
+		return sp, nil
 	}
 
 	// IP
@@ -36,27 +78,23 @@ func ProcessPacket(packet gopacket.Packet) (*SnifPacket, error) {
 	snif_packet := &SnifPacket{
 		SrcIP:      srcIP.String(),
 		DstIP:      dstIP.String(),
-		SrcMAC:     ethLayer.(*layers.Ethernet).SrcMAC.String(),
-		DstMAC:     ethLayer.(*layers.Ethernet).DstMAC.String(),
+		SrcMAC:     srcMAC,
+		DstMAC:     dstMAC,
 		Timestamp:  packet.Metadata().Timestamp.Unix(),
+		VLAN:       vlan,
+		OuterVLAN:  outerVLAN,
 	}
 
-	// UDP → DNS?
+	// UDP → registered dissectors
 	if udp := packet.Layer(layers.LayerTypeUDP); udp != nil {
 		u := udp.(*layers.UDP)
 		snif_packet.SrcPort = u.SrcPort.String()
 		snif_packet.DstPort = u.DstPort.String()
 		snif_packet.Size = len(u.Payload)
 		snif_packet.Protocol = "UDP"
-		if u.DstPort == 53 {
-			if dns := packet.Layer(layers.LayerTypeDNS); dns != nil {
-				details := parseDNS(dns.(*layers.DNS), srcIP, dstIP)
-				if details != nil {
-					snif_packet.Details.DNS = details
-					snif_packet.Details.Type = SnifPacketTypeDNS
-					return snif_packet, nil
-				}
-			}
+		ctx := p.dissectContext(snif_packet, u.Payload, srcIP, dstIP, uint16(u.SrcPort), uint16(u.DstPort), packet.Metadata().Timestamp)
+		if p.dissectors.dissect(TransportUDP, ctx) {
+			return snif_packet, nil
 		}
 		snif_packet.Details.Type = SnifPacketTypeUDP
 		return snif_packet, nil
@@ -71,30 +109,90 @@ func ProcessPacket(packet gopacket.Packet) (*SnifPacket, error) {
 		snif_packet.DstPort = t.DstPort.String()
 		snif_packet.Size = size
 		snif_packet.Protocol = "TCP"
+		snif_packet.TCPFlags = tcpFlags(t)
 
-		// HTTP (port 80)
-		if t.DstPort == 80 {
-			details := parseHTTP(payload, srcIP, dstIP, size)
-			if details != nil {
-				snif_packet.Details.HTTP = details
-				snif_packet.Details.Type = SnifPacketTypeHTTP
-				return snif_packet, nil
-			}
+		ctx := p.dissectContext(snif_packet, payload, srcIP, dstIP, uint16(t.SrcPort), uint16(t.DstPort), packet.Metadata().Timestamp)
+		if p.reasm != nil && p.streamed(ctx) {
+			p.processStream(ctx, t)
+			return snif_packet, nil
 		}
 
-		// HTTPS (port 443 → TLS ClientHello)
-		if t.DstPort == 443 {
-			details := parseTLSClientHello(payload, srcIP, dstIP, size)
-			if details != nil {
-				snif_packet.Details.TLS = details
-				snif_packet.Details.Type = SnifPacketTypeTLS
-				return snif_packet, nil
-			}
+		if p.dissectors.dissect(TransportTCP, ctx) {
+			return snif_packet, nil
 		}
 
 		// On plain TCP
 		snif_packet.Details.Type = SnifPacketTypeTCP
 		return snif_packet, nil
 	}
-	return nil, fmt.Errorf("no TCP/UDP layer found")
-}
\ No newline at end of file
+	// ICMP, IPsec and other protocols over IP
+	processIP(snif_packet, packet)
+	return snif_packet, nil
+}
+
+func (p *Processor) dissectContext(sp *SnifPacket, payload []byte, src, dst net.IP, srcPort, dstPort uint16, ts time.Time) *DissectContext {
+	return &DissectContext{
+		Packet:    sp,
+		Payload:   payload,
+		SrcIP:     src,
+		DstIP:     dst,
+		SrcPort:   srcPort,
+		DstPort:   dstPort,
+		Timestamp: ts,
+		proc:      p,
+	}
+}
+
+// streamed reports whether a segment belongs to a stream the reassembler
+// handles: one on the ports of a stream dissector or recognised by its
+// heuristic, or a stream already being buffered.
+func (p *Processor) streamed(ctx *DissectContext) bool {
+	if _, ok := p.dissectors.stream(ctx); ok {
+		return true
+	}
+	key := streamKey{srcIP: ctx.Packet.SrcIP, dstIP: ctx.Packet.DstIP, srcPort: ctx.SrcPort, dstPort: ctx.DstPort}
+	return p.reasm.Tracking(key)
+}
+
+// processStream feeds either side of a connection a stream dissector
+// handles into the reassembler, from its SYN or from a segment the
+// dissector takes for a message start, and parses the message once it is
+// complete. Until then the segments are reported as plain TCP.
+func (p *Processor) processStream(ctx *DissectContext, t *layers.TCP) {
+	ctx.Packet.Details.Type = SnifPacketTypeTCP
+
+	key := streamKey{srcIP: ctx.Packet.SrcIP, dstIP: ctx.Packet.DstIP, srcPort: ctx.SrcPort, dstPort: ctx.DstPort}
+	if t.SYN {
+		p.reasm.Start(key, t.Seq, ctx.Timestamp)
+		return
+	}
+	if len(t.Payload) == 0 {
+		if t.FIN || t.RST {
+			p.reasm.Done(key)
+		}
+		return
+	}
+
+	if !p.reasm.Tracking(key) {
+		// Without the SYN only a segment starting a message begins a
+		// stream, buffering every mid-stream segment would crowd out the
+		// streams being tracked
+		if d, ok := p.dissectors.stream(ctx); !ok || !d.StreamStart(ctx) {
+			p.dissectors.dissect(TransportTCP, ctx)
+			return
+		}
+	}
+
+	// The dissector is chosen on the stream start, which is what its
+	// heuristic looks for
+	ctx.Payload = p.reasm.Add(key, t.Seq, t.Payload, ctx.Timestamp)
+	if len(ctx.Payload) == 0 {
+		// Out of order, the start is still missing
+		return
+	}
+	full := len(ctx.Payload) >= p.reasm.opts.MaxBytes
+	d, ok := p.dissectors.stream(ctx)
+	if !ok || !d.DissectStream(ctx, full) {
+		p.reasm.Done(key)
+	}
+}
@@ -0,0 +1,357 @@
+package snifpacket
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// QUIC versions with known Initial salts (RFC 9001, RFC 9369)
+const (
+	quicVersion1       = 0x00000001
+	quicVersion2       = 0x6b3343cf
+	quicVersionDraft29 = 0xff00001d
+)
+
+var (
+	quicSaltV1      = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+	quicSaltV2      = []byte{0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9}
+	quicSaltDraft29 = []byte{0xaf, 0xbf, 0xec, 0x28, 0x99, 0x93, 0xd2, 0x4c, 0x9e, 0x97, 0x86, 0xf1, 0x9c, 0x61, 0x11, 0xe0, 0x43, 0x90, 0xa8, 0x99}
+)
+
+type quicCryptoFrame struct {
+	offset uint64
+	data   []byte
+}
+
+// parseQUICInitial decrypts the client Initial packets of a UDP datagram and
+// returns the version name and their CRYPTO frames.
+func parseQUICInitial(datagram []byte) (string, []quicCryptoFrame, bool) {
+	var frames []quicCryptoFrame
+	version := ""
+	for len(datagram) > 0 {
+		// Long header with the fixed bit set
+		if datagram[0]&0xc0 != 0xc0 || len(datagram) < 7 {
+			break
+		}
+		v := binary.BigEndian.Uint32(datagram[1:])
+		salt, prefix, initialType := quicVersionParams(v)
+		if salt == nil {
+			break
+		}
+		if (datagram[0]>>4)&0x03 != initialType {
+			break
+		}
+
+		packetLen, packetFrames, ok := decryptQUICInitial(datagram, salt, prefix)
+		if !ok {
+			break
+		}
+		version = quicVersionName(v)
+		frames = append(frames, packetFrames...)
+		datagram = datagram[packetLen:]
+	}
+	return version, frames, version != ""
+}
+
+func quicVersionParams(v uint32) (salt []byte, labelPrefix string, initialType byte) {
+	switch v {
+	case quicVersion1:
+		return quicSaltV1, "quic ", 0
+	case quicVersion2:
+		return quicSaltV2, "quicv2 ", 1
+	case quicVersionDraft29:
+		return quicSaltDraft29, "quic ", 0
+	}
+	return nil, "", 0
+}
+
+func quicVersionName(v uint32) string {
+	switch v {
+	case quicVersion1:
+		return "v1"
+	case quicVersion2:
+		return "v2"
+	case quicVersionDraft29:
+		return "draft-29"
+	}
+	return fmt.Sprintf("0x%08x", v)
+}
+
+// decryptQUICInitial removes header protection from the Initial packet at
+// the start of data and decrypts it with the keys derived from its DCID.
+func decryptQUICInitial(data []byte, salt []byte, prefix string) (int, []quicCryptoFrame, bool) {
+	pos := 5
+	dcidLen := int(data[pos])
+	pos++
+	if dcidLen > 20 || len(data) < pos+dcidLen+1 {
+		return 0, nil, false
+	}
+	dcid := data[pos : pos+dcidLen]
+	pos += dcidLen
+
+	scidLen := int(data[pos])
+	pos += 1 + scidLen
+	if scidLen > 20 || len(data) < pos {
+		return 0, nil, false
+	}
+
+	tokenLen, n := quicVarint(data[pos:])
+	if n == 0 || uint64(len(data)-pos-n) < tokenLen {
+		return 0, nil, false
+	}
+	pos += n + int(tokenLen)
+
+	length, n := quicVarint(data[pos:])
+	if n == 0 {
+		return 0, nil, false
+	}
+	pos += n
+	pnOffset := pos
+	if uint64(len(data)-pnOffset) < length || length < 20 {
+		return 0, nil, false
+	}
+	packetEnd := pnOffset + int(length)
+
+	key, iv, hp, err := quicInitialKeys(dcid, salt, prefix)
+	if err != nil {
+		return 0, nil, false
+	}
+
+	// Header protection, sampled 4 bytes after the packet number start
+	hpBlock, err := aes.NewCipher(hp)
+	if err != nil {
+		return 0, nil, false
+	}
+	mask := make([]byte, aes.BlockSize)
+	hpBlock.Encrypt(mask, data[pnOffset+4:pnOffset+4+aes.BlockSize])
+
+	header := append([]byte(nil), data[:pnOffset+4]...)
+	header[0] ^= mask[0] & 0x0f
+	pnLen := int(header[0]&0x03) + 1
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		header[pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(header[pnOffset+i])
+	}
+	header = header[:pnOffset+pnLen]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return 0, nil, false
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return 0, nil, false
+	}
+	nonce := append([]byte(nil), iv...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+	plain, err := aead.Open(nil, nonce, data[pnOffset+pnLen:packetEnd], header)
+	if err != nil {
+		return 0, nil, false
+	}
+
+	frames, ok := quicCryptoFrames(plain)
+	return packetEnd, frames, ok
+}
+
+func quicInitialKeys(dcid, salt []byte, prefix string) (key, iv, hp []byte, err error) {
+	initialSecret, err := hkdf.Extract(sha256.New, dcid, salt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	clientSecret, err := hkdfExpandLabel(initialSecret, "client in", sha256.Size)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if key, err = hkdfExpandLabel(clientSecret, prefix+"key", 16); err != nil {
+		return nil, nil, nil, err
+	}
+	if iv, err = hkdfExpandLabel(clientSecret, prefix+"iv", 12); err != nil {
+		return nil, nil, nil, err
+	}
+	if hp, err = hkdfExpandLabel(clientSecret, prefix+"hp", 16); err != nil {
+		return nil, nil, nil, err
+	}
+	return key, iv, hp, nil
+}
+
+// hkdfExpandLabel is HKDF-Expand-Label from TLS 1.3 with an empty context.
+func hkdfExpandLabel(secret []byte, label string, length int) ([]byte, error) {
+	full := "tls13 " + label
+	info := make([]byte, 0, 4+len(full))
+	info = append(info, byte(length>>8), byte(length), byte(len(full)))
+	info = append(info, full...)
+	info = append(info, 0)
+	return hkdf.Expand(sha256.New, secret, string(info), length)
+}
+
+// quicCryptoFrames collects the CRYPTO frames of a decrypted Initial
+// payload. Initial packets only carry PADDING, PING, ACK, CRYPTO and
+// CONNECTION_CLOSE frames.
+func quicCryptoFrames(payload []byte) ([]quicCryptoFrame, bool) {
+	var frames []quicCryptoFrame
+	for p := 0; p < len(payload); {
+		frameType := payload[p]
+		p++
+		switch frameType {
+		case 0x00, 0x01:
+			// PADDING, PING
+		case 0x02, 0x03:
+			// ACK: largest, delay, range count, first range, ranges
+			var fields [4]uint64
+			for i := range fields {
+				v, n := quicVarint(payload[p:])
+				if n == 0 {
+					return frames, false
+				}
+				fields[i] = v
+				p += n
+			}
+			for i := uint64(0); i < 2*fields[2]; i++ {
+				_, n := quicVarint(payload[p:])
+				if n == 0 {
+					return frames, false
+				}
+				p += n
+			}
+			if frameType == 0x03 {
+				// ECN counts
+				for i := 0; i < 3; i++ {
+					_, n := quicVarint(payload[p:])
+					if n == 0 {
+						return frames, false
+					}
+					p += n
+				}
+			}
+		case 0x06:
+			offset, n := quicVarint(payload[p:])
+			if n == 0 {
+				return frames, false
+			}
+			p += n
+			length, n := quicVarint(payload[p:])
+			if n == 0 || uint64(len(payload)-p-n) < length {
+				return frames, false
+			}
+			p += n
+			frames = append(frames, quicCryptoFrame{offset: offset, data: payload[p : p+int(length)]})
+			p += int(length)
+		default:
+			// CONNECTION_CLOSE or garbage, nothing more to learn
+			return frames, len(frames) > 0
+		}
+	}
+	return frames, true
+}
+
+// quicVarint decodes a variable-length integer and returns it with its
+// size, or a size of 0 when data is too short.
+func quicVarint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	n := 1 << (data[0] >> 6)
+	if len(data) < n {
+		return 0, 0
+	}
+	v := uint64(data[0] & 0x3f)
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(data[i])
+	}
+	return v, n
+}
+
+// quicClientHello joins CRYPTO frames of one datagram into the contiguous
+// start of the handshake stream.
+func quicClientHello(frames []quicCryptoFrame) []byte {
+	sort.Slice(frames, func(i, j int) bool { return frames[i].offset < frames[j].offset })
+	var out []byte
+	for _, f := range frames {
+		if f.offset > uint64(len(out)) {
+			break
+		}
+		if end := f.offset + uint64(len(f.data)); end > uint64(len(out)) {
+			out = append(out, f.data[uint64(len(out))-f.offset:]...)
+		}
+	}
+	return out
+}
+
+// parseQUICClientHello parses the handshake stream of a QUIC connection.
+// ok is false until it holds the whole ClientHello.
+func parseQUICClientHello(handshake []byte, version string, src, dst net.IP) (*SnifPacketDetailsQUIC, bool) {
+	if len(handshake) < 4 || handshake[0] != 0x01 {
+		return nil, false
+	}
+	helloLen := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+	if len(handshake) < 4+helloLen {
+		return nil, false
+	}
+
+	// Same layout as a TLS record so the TLS helpers apply
+	record := make([]byte, 0, 5+4+helloLen)
+	record = append(record, 0x16, 0x03, 0x01, byte((4+helloLen)>>8), byte(4+helloLen))
+	record = append(record, handshake[:4+helloLen]...)
+
+	details := &SnifPacketDetailsQUIC{
+		Sni:     extractSNI(record),
+		ALPN:    extractALPN(record),
+		Version: version,
+	}
+	if ch, ok := parseClientHello(record); ok {
+		details.JA3 = ch.ja3()
+		details.JA4 = ch.ja4('q')
+	}
+	return details, true
+}
+
+// processQUIC parses client Initial packets to UDP port 443. ClientHellos
+// spread over several Initials are joined with the reassembler when one is
+// configured.
+func (p *Processor) processQUIC(sp *SnifPacket, payload []byte, srcPort, dstPort uint16, ts time.Time) bool {
+	version, frames, ok := parseQUICInitial(payload)
+	if !ok {
+		return false
+	}
+	src, dst := net.ParseIP(sp.SrcIP), net.ParseIP(sp.DstIP)
+
+	if p.reasm == nil {
+		details, ok := parseQUICClientHello(quicClientHello(frames), version, src, dst)
+		if !ok {
+			return false
+		}
+		sp.Details.QUIC = details
+		sp.Details.Type = SnifPacketTypeQUIC
+		return true
+	}
+
+	key := streamKey{srcIP: sp.SrcIP, dstIP: sp.DstIP, srcPort: srcPort, dstPort: dstPort, udp: true}
+	var data []byte
+	for _, f := range frames {
+		if f.offset > uint64(p.reasm.opts.MaxBytes) {
+			continue
+		}
+		data = p.reasm.AddAt(key, uint32(f.offset), f.data, ts)
+	}
+	details, complete := parseQUICClientHello(data, version, src, dst)
+	if !complete {
+		if len(data) >= p.reasm.opts.MaxBytes {
+			p.reasm.Done(key)
+		}
+		return false
+	}
+	p.reasm.Done(key)
+	sp.Details.QUIC = details
+	sp.Details.Type = SnifPacketTypeQUIC
+	return true
+}
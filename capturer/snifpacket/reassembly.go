@@ -0,0 +1,187 @@
+package snifpacket
+
+import (
+	"container/list"
+	"sort"
+	"time"
+)
+
+type ReassemblyOptions struct {
+	// MaxBytes is how much of the start of each stream direction is kept.
+	MaxBytes int
+	// MaxStreams bounds the number of directions buffered at once.
+	MaxStreams int
+	// Timeout drops streams that saw no segment for this long.
+	Timeout time.Duration
+}
+
+type streamKey struct {
+	srcIP   string
+	dstIP   string
+	srcPort uint16
+	dstPort uint16
+	// QUIC handshake streams share the table with TCP
+	udp bool
+}
+
+type streamSegment struct {
+	seq  uint32
+	data []byte
+}
+
+type tcpStream struct {
+	key      streamKey
+	base     uint32
+	synSeen  bool
+	segments []streamSegment
+	stored   int
+	lastSeen time.Time
+	elem     *list.Element
+}
+
+// Reassembler rebuilds the first bytes of TCP stream directions so that
+// messages split across segments, like large ClientHellos, can be parsed.
+// It is not safe for concurrent use.
+type Reassembler struct {
+	opts    ReassemblyOptions
+	streams map[streamKey]*tcpStream
+	lru     *list.List
+}
+
+func NewReassembler(opts ReassemblyOptions) *Reassembler {
+	return &Reassembler{
+		opts:    opts,
+		streams: make(map[streamKey]*tcpStream),
+		lru:     list.New(),
+	}
+}
+
+// Start records the SYN of a stream direction, which fixes where its data
+// begins.
+func (r *Reassembler) Start(key streamKey, synSeq uint32, ts time.Time) {
+	s := r.stream(key, ts)
+	s.base = synSeq + 1
+	s.synSeen = true
+	s.segments = nil
+	s.stored = 0
+}
+
+// Add stores a segment and returns the contiguous data from the start of
+// the stream direction, at most MaxBytes long.
+func (r *Reassembler) Add(key streamKey, seq uint32, payload []byte, ts time.Time) []byte {
+	r.expire(ts)
+
+	s, ok := r.streams[key]
+	if !ok {
+		s = r.stream(key, ts)
+		s.base = seq
+	}
+	s.lastSeen = ts
+	r.lru.MoveToBack(s.elem)
+
+	off := int(int32(seq - s.base))
+	if off < 0 {
+		if s.synSeen || -off > r.opts.MaxBytes {
+			// Before the stream start or a stale retransmission
+			return r.contiguous(s)
+		}
+		// Segments arrived out of order before the first one seen
+		s.base = seq
+		off = 0
+	}
+
+	if off < r.opts.MaxBytes && len(payload) > 0 {
+		data := payload
+		if off+len(data) > r.opts.MaxBytes {
+			data = data[:r.opts.MaxBytes-off]
+		}
+		if s.stored+len(data) <= 2*r.opts.MaxBytes {
+			s.segments = append(s.segments, streamSegment{seq: seq, data: append([]byte(nil), data...)})
+			s.stored += len(data)
+		}
+	}
+
+	return r.contiguous(s)
+}
+
+// AddAt stores data at an offset from the start of a stream that has no
+// sequence numbers of its own, like the QUIC CRYPTO stream.
+func (r *Reassembler) AddAt(key streamKey, offset uint32, payload []byte, ts time.Time) []byte {
+	if _, ok := r.streams[key]; !ok {
+		r.stream(key, ts).synSeen = true
+	}
+	return r.Add(key, offset, payload, ts)
+}
+
+// Tracking reports whether data of the stream direction is being buffered.
+func (r *Reassembler) Tracking(key streamKey) bool {
+	_, ok := r.streams[key]
+	return ok
+}
+
+// Done releases a stream direction once its data was parsed.
+func (r *Reassembler) Done(key streamKey) {
+	if s, ok := r.streams[key]; ok {
+		r.remove(s)
+	}
+}
+
+func (r *Reassembler) stream(key streamKey, ts time.Time) *tcpStream {
+	if s, ok := r.streams[key]; ok {
+		return s
+	}
+	if r.opts.MaxStreams > 0 && len(r.streams) >= r.opts.MaxStreams {
+		r.remove(r.lru.Front().Value.(*tcpStream))
+	}
+	s := &tcpStream{key: key, lastSeen: ts}
+	s.elem = r.lru.PushBack(s)
+	r.streams[key] = s
+	return s
+}
+
+func (r *Reassembler) remove(s *tcpStream) {
+	r.lru.Remove(s.elem)
+	delete(r.streams, s.key)
+}
+
+func (r *Reassembler) expire(now time.Time) {
+	if r.opts.Timeout <= 0 {
+		return
+	}
+	for e := r.lru.Front(); e != nil; {
+		s := e.Value.(*tcpStream)
+		if now.Sub(s.lastSeen) < r.opts.Timeout {
+			return
+		}
+		e = e.Next()
+		r.remove(s)
+	}
+}
+
+func (r *Reassembler) contiguous(s *tcpStream) []byte {
+	if len(s.segments) == 1 && s.segments[0].seq == s.base {
+		return s.segments[0].data
+	}
+
+	sort.SliceStable(s.segments, func(i, j int) bool {
+		return int32(s.segments[i].seq-s.base) < int32(s.segments[j].seq-s.base)
+	})
+
+	var out []byte
+	for _, seg := range s.segments {
+		off := int(int32(seg.seq - s.base))
+		if off < 0 {
+			continue
+		}
+		if off > len(out) {
+			break
+		}
+		if end := off + len(seg.data); end > len(out) {
+			out = append(out, seg.data[len(out)-off:]...)
+		}
+	}
+	if len(out) > r.opts.MaxBytes {
+		out = out[:r.opts.MaxBytes]
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package snifpacket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+const (
+	ReplayPacingRealtime = "realtime"
+	ReplayPacingMax      = "max"
+)
+
+// pcapng files start with a Section Header Block.
+const pcapngMagic = 0x0A0D0D0A
+
+type ReplayOptions struct {
+	Pacing         string
+	Speed          float64
+	KeepTimestamps bool
+	// Filter is a BPF expression, compiled for the link type of the file
+	// and applied in user space.
+	Filter string
+}
+
+type replayReader interface {
+	gopacket.PacketDataSource
+	LinkType() layers.LinkType
+}
+
+// pacedSource replays frames from a capture file, sleeping between them
+// according to the pacing mode and optionally shifting timestamps to now.
+type pacedSource struct {
+	reader    replayReader
+	opts      ReplayOptions
+	match     func([]byte) bool
+	firstTs   time.Time
+	startedAt time.Time
+}
+
+func (s *pacedSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	data, ci, err := s.reader.ReadPacketData()
+	for err == nil && s.match != nil && !s.match(data) {
+		data, ci, err = s.reader.ReadPacketData()
+	}
+	if err != nil {
+		return nil, ci, err
+	}
+
+	if s.startedAt.IsZero() {
+		s.firstTs = ci.Timestamp
+		s.startedAt = time.Now()
+	}
+	offset := ci.Timestamp.Sub(s.firstTs)
+	if offset < 0 {
+		offset = 0
+	}
+
+	if s.opts.Pacing == ReplayPacingRealtime {
+		speed := s.opts.Speed
+		if speed <= 0 {
+			speed = 1
+		}
+		due := s.startedAt.Add(time.Duration(float64(offset) / speed))
+		if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+
+	if !s.opts.KeepTimestamps {
+		ci.Timestamp = s.startedAt.Add(offset)
+	}
+	return data, ci, nil
+}
+
+// OpenReplaySource opens a pcap or pcapng file and returns a packet source
+// that yields its frames as if they were captured live.
+func OpenReplaySource(path string, opts ReplayOptions) (*gopacket.PacketSource, io.Closer, error) {
+	switch opts.Pacing {
+	case ReplayPacingRealtime, ReplayPacingMax:
+	default:
+		return nil, nil, fmt.Errorf("unknown replay pacing %q", opts.Pacing)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(4)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to read capture header: %w", err)
+	}
+
+	var reader replayReader
+	if binary.BigEndian.Uint32(magic) == pcapngMagic {
+		reader, err = pcapgo.NewNgReader(br, pcapgo.DefaultNgReaderOptions)
+	} else {
+		reader, err = pcapgo.NewReader(br)
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to open capture file %s: %w", path, err)
+	}
+
+	paced := &pacedSource{reader: reader, opts: opts}
+	if opts.Filter != "" {
+		if paced.match, err = NewBPFMatcher(opts.Filter, reader.LinkType()); err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to build BPF filter for %s: %w", path, err)
+		}
+	}
+
+	source := gopacket.NewPacketSource(paced, reader.LinkType())
+	return source, f, nil
+}
+
+// ReplayPackets feeds every frame of a replay source into the packets channel.
+// Unlike ReceivePackets it blocks when the channel is full instead of dropping,
+// so a replayed file always reaches the sender completely.
+func ReplayPackets(packetSource *gopacket.PacketSource, iface string, proc *Processor, keep func(*SnifPacket) bool, packets chan *SnifPacket, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(packets)
+
+	var read, sent uint64
+	for packet := range packetSource.Packets() {
+		read++
+		sp, err := proc.Process(packet)
+		if err != nil {
+			continue
+		}
+		if !keep(sp) {
+			continue
+		}
+		sp.Interface = iface
+		packets <- sp
+		sent++
+	}
+	log.Printf("Replay finished: read=%d sent=%d", read, sent)
+}
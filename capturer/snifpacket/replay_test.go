@@ -0,0 +1,98 @@
+package snifpacket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// replayFixture replays a capture from testdata through a processor with
+// reassembly, as the replay mode does, and returns the processed packets.
+func replayFixture(t *testing.T, name string) []*SnifPacket {
+	t.Helper()
+	source, closer, err := OpenReplaySource("testdata/"+name, ReplayOptions{Pacing: ReplayPacingMax, KeepTimestamps: true})
+	if err != nil {
+		t.Fatalf("opening %s: %v", name, err)
+	}
+	defer closer.Close()
+
+	proc := NewProcessor(NewReassembler(ReassemblyOptions{MaxBytes: 16384, MaxStreams: 64, Timeout: time.Minute}), ProcessorOptions{})
+	var packets []*SnifPacket
+	for packet := range source.Packets() {
+		sp, err := proc.Process(packet)
+		if err != nil {
+			t.Fatalf("%s: packet %d: %v", name, len(packets), err)
+		}
+		packets = append(packets, sp)
+	}
+	return packets
+}
+
+// The fixtures are small Ethernet captures between 192.168.1.10 and its
+// router 192.168.1.1 or example.com (93.184.216.34).
+func TestReplayFixtures(t *testing.T) {
+	t.Run("dns", func(t *testing.T) {
+		packets := replayFixture(t, "dns.pcap")
+		if len(packets) != 2 {
+			t.Fatalf("got %d packets, want query and response", len(packets))
+		}
+		query, resp := packets[0].Details.DNS, packets[1].Details.DNS
+		if query == nil || !query.IsQuery || query.ID != 0x1234 ||
+			!reflect.DeepEqual(query.Questions, []SnifPacketDNSQuestion{{Name: "example.com", Type: "A"}}) {
+			t.Errorf("query: got %+v", query)
+		}
+		want := []SnifPacketDNSAnswer{{Name: "example.com", Type: "A", TTL: 300, Data: "93.184.216.34"}}
+		if resp == nil || resp.IsQuery || resp.RCode != "NOERROR" || !reflect.DeepEqual(resp.Answers, want) {
+			t.Errorf("response: got %+v, want answers %+v", resp, want)
+		}
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		// Handshake, then a ClientHello with a post-quantum key share split
+		// over two segments
+		packets := replayFixture(t, "tls.pcap")
+		if len(packets) != 5 {
+			t.Fatalf("got %d packets, want 5", len(packets))
+		}
+		if packets[3].Details.TLS != nil {
+			t.Errorf("first segment parsed before the ClientHello is complete: %+v", packets[3].Details.TLS)
+		}
+		hello := packets[4]
+		if hello.Details.Type != SnifPacketTypeTLS || hello.Details.TLS == nil {
+			t.Fatalf("second segment: got %+v, want a ClientHello", hello.Details)
+		}
+		if tls := hello.Details.TLS; tls.Sni != "example.com" || tls.TLSVersion != "TLS 1.3" {
+			t.Errorf("ClientHello: got %+v, want SNI example.com and TLS 1.3", tls)
+		}
+	})
+
+	t.Run("quic", func(t *testing.T) {
+		// The client Initial of RFC 9001 Appendix A.2
+		packets := replayFixture(t, "quic.pcap")
+		if len(packets) != 1 {
+			t.Fatalf("got %d packets, want 1", len(packets))
+		}
+		quic := packets[0].Details.QUIC
+		if quic == nil || quic.Sni != "example.com" || quic.Version != "v1" || !reflect.DeepEqual(quic.ALPN, []string{"alpn"}) {
+			t.Errorf("Initial: got %+v, want SNI example.com, v1 and ALPN alpn", quic)
+		}
+	})
+
+	t.Run("dhcp", func(t *testing.T) {
+		packets := replayFixture(t, "dhcp.pcap")
+		if len(packets) != 1 {
+			t.Fatalf("got %d packets, want 1", len(packets))
+		}
+		want := &SnifPacketDetailsDHCP{
+			Version:          4,
+			MessageType:      "Discover",
+			ClientMAC:        "02:00:00:00:00:0a",
+			Hostname:         "kitchen-speaker",
+			VendorClass:      "android-dhcp-13",
+			ParamRequestList: "1,3,6,15,26,28,51,58,59,43",
+		}
+		if got := packets[0].Details.DHCP; !reflect.DeepEqual(got, want) {
+			t.Errorf("Discover: got %+v, want %+v", got, want)
+		}
+	})
+}
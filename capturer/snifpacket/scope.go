@@ -0,0 +1,243 @@
+package snifpacket
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Traffic modes decide which packets are kept relative to the home networks.
+const (
+	// TrafficModeOutbound keeps only packets from home to remote hosts.
+	TrafficModeOutbound = "outbound"
+	// TrafficModeBidirectional keeps both directions between home and remote.
+	TrafficModeBidirectional = "bidirectional"
+	// TrafficModeLAN also keeps traffic between two home addresses.
+	TrafficModeLAN = "lan"
+	// TrafficModeAll keeps everything, including transit traffic.
+	TrafficModeAll = "all"
+)
+
+// Used when neither HOME_NETS nor the interface addresses define the home
+// network, e.g. on a SPAN port without an address.
+var privateNets = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+type ScopeOptions struct {
+	Mode string
+	// HomeNets overrides the networks taken from the interface addresses.
+	HomeNets []*net.IPNet
+	// ExcludeNets drops every packet from or to these networks.
+	ExcludeNets []*net.IPNet
+}
+
+// ParseCIDRs parses networks in CIDR notation. Bare addresses are taken as
+// single hosts.
+func ParseCIDRs(list []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network %q", s)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// InterfaceNets holds networks configured for every interface and for
+// single interfaces.
+type InterfaceNets struct {
+	Global       []*net.IPNet
+	PerInterface map[string][]*net.IPNet
+}
+
+// ParseInterfaceCIDRs parses networks like ParseCIDRs. Entries written as
+// iface=cidr only apply to that interface.
+func ParseInterfaceCIDRs(list []string) (InterfaceNets, error) {
+	nets := InterfaceNets{PerInterface: make(map[string][]*net.IPNet)}
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		iface, cidr, ok := strings.Cut(s, "=")
+		if !ok {
+			n, err := ParseCIDRs([]string{s})
+			if err != nil {
+				return InterfaceNets{}, err
+			}
+			nets.Global = append(nets.Global, n...)
+			continue
+		}
+		iface = strings.TrimSpace(iface)
+		if iface == "" {
+			return InterfaceNets{}, fmt.Errorf("invalid network %q, expected iface=cidr", s)
+		}
+		n, err := ParseCIDRs([]string{cidr})
+		if err != nil {
+			return InterfaceNets{}, err
+		}
+		if len(n) == 0 {
+			return InterfaceNets{}, fmt.Errorf("invalid network %q, expected iface=cidr", s)
+		}
+		nets.PerInterface[iface] = append(nets.PerInterface[iface], n...)
+	}
+	return nets, nil
+}
+
+// For returns the networks of iface, the global ones when it has none of
+// its own.
+func (n InterfaceNets) For(iface string) []*net.IPNet {
+	if nets := n.PerInterface[iface]; len(nets) > 0 {
+		return nets
+	}
+	return n.Global
+}
+
+// ResolveHomeNets returns the home networks for iface: the configured ones,
+// else the interface networks, else the private address ranges. Without an
+// interface, as for tunnelled or replayed traffic, the interface networks
+// are skipped.
+func ResolveHomeNets(iface string, configured []*net.IPNet) []*net.IPNet {
+	if len(configured) > 0 {
+		return configured
+	}
+	if iface == "" {
+		log.Printf("no capture interface; using private ranges as home networks")
+		nets, _ := ParseCIDRs(privateNets)
+		return nets
+	}
+
+	localNets, _, err := GetLocalAddrs(iface)
+	var nets []*net.IPNet
+	for _, n := range localNets {
+		if !n.IP.IsLinkLocalUnicast() {
+			nets = append(nets, n)
+		}
+	}
+	if len(nets) > 0 {
+		return nets
+	}
+
+	if err != nil {
+		log.Printf("failed to get local addresses for interface %s: %v; using private ranges as home networks", iface, err)
+	} else {
+		log.Printf("interface %s has no addresses; using private ranges as home networks", iface)
+	}
+	nets, _ = ParseCIDRs(privateNets)
+	return nets
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewScopeFilter returns a predicate that keeps the packets selected by the
+// traffic mode and tags their direction relative to the home networks.
+func NewScopeFilter(iface string, opts ScopeOptions) (func(*SnifPacket) bool, error) {
+	switch opts.Mode {
+	case TrafficModeOutbound, TrafficModeBidirectional, TrafficModeLAN, TrafficModeAll:
+	default:
+		return nil, fmt.Errorf("unknown traffic mode %q", opts.Mode)
+	}
+
+	homeNets := ResolveHomeNets(iface, opts.HomeNets)
+	excludeNets := opts.ExcludeNets
+
+	return func(sp *SnifPacket) bool {
+		src := net.ParseIP(sp.SrcIP)
+		dst := net.ParseIP(sp.DstIP)
+		if src == nil || dst == nil {
+			// Not IP traffic, nothing to place
+			return opts.Mode == TrafficModeAll
+		}
+		if containsIP(excludeNets, src) || containsIP(excludeNets, dst) {
+			return false
+		}
+		// DHCP clients, mDNS/SSDP announcements, ARP and neighbour
+		// discovery are sent from 0.0.0.0 or link-local addresses to
+		// broadcast or multicast, but they describe the sending device
+		if sp.Details.DHCP != nil || sp.Details.MDNS != nil || sp.Details.SSDP != nil || sp.Details.ARP != nil ||
+			sp.Details.ICMP != nil && sp.Details.ICMP.isNeighbourDiscovery() {
+			sp.Direction = SnifPacketDirectionUp
+			return true
+		}
+		srcIn := containsIP(homeNets, src)
+		dstIn := containsIP(homeNets, dst)
+		// The resolver is usually the router inside the home networks, DNS
+		// of home devices is kept anyway so the analyzer learns the names
+		// each one asked for: queries go up, responses come down to it
+		if dir, ok := dnsDirection(sp); ok && (dir == SnifPacketDirectionUp && srcIn || dir == SnifPacketDirectionDown && dstIn) {
+			sp.Direction = dir
+			return true
+		}
+
+		switch {
+		case srcIn && !dstIn:
+			sp.Direction = SnifPacketDirectionUp
+			return true
+		case !srcIn && dstIn:
+			sp.Direction = SnifPacketDirectionDown
+			return opts.Mode != TrafficModeOutbound
+		case srcIn && dstIn:
+			sp.Direction = SnifPacketDirectionLAN
+			return opts.Mode == TrafficModeLAN || opts.Mode == TrafficModeAll
+		}
+		// Transit traffic between two remote hosts
+		return opts.Mode == TrafficModeAll
+	}, nil
+}
+
+// Plain DNS; DNS over TLS can't be decoded and follows the traffic mode
+var dnsPorts = map[int]bool{53: true}
+
+// dnsDirection returns the direction of a DNS message relative to the
+// device that asked: up for queries, down for responses.
+func dnsDirection(sp *SnifPacket) (SnifPacketDirection, bool) {
+	if d := sp.Details.DNS; d != nil {
+		if d.IsQuery {
+			return SnifPacketDirectionUp, true
+		}
+		return SnifPacketDirectionDown, true
+	}
+	switch {
+	case dnsPorts[portNumber(sp.DstPort)]:
+		return SnifPacketDirectionUp, true
+	case dnsPorts[portNumber(sp.SrcPort)]:
+		return SnifPacketDirectionDown, true
+	}
+	return "", false
+}
+
+// portNumber parses a port as gopacket formats it, e.g. "53(domain)".
+func portNumber(port string) int {
+	port, _, _ = strings.Cut(port, "(")
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package snifpacket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInterfaceCIDRs(t *testing.T) {
+	nets, err := ParseInterfaceCIDRs([]string{"10.0.0.0/8", " br-guest=192.168.50.0/24", "br-iot=10.30.0.0/24", "br-iot=fd30::/64", "br-iot=10.30.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		iface string
+		want  []string
+	}{
+		{"br-lan", []string{"10.0.0.0/8"}},
+		{"", []string{"10.0.0.0/8"}},
+		{"br-guest", []string{"192.168.50.0/24"}},
+		{"br-iot", []string{"10.30.0.0/24", "fd30::/64", "10.30.0.1/32"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, n := range nets.For(tt.iface) {
+			got = append(got, n.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%q) = %v, want %v", tt.iface, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"=10.0.0.0/8", "eth0=", "eth0=10.0.0.0/33", "eth0"} {
+		if _, err := ParseInterfaceCIDRs([]string{bad}); err == nil {
+			t.Errorf("%q parsed, want an error", bad)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package snifpacket
 
 import (
 	"log"
-	"net"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -10,28 +9,8 @@ import (
 	"github.com/gopacket/gopacket"
 )
 
-func ReceivePackets(packetSource *gopacket.PacketSource, iface string, packets chan *SnifPacket, wg *sync.WaitGroup) {
+func ReceivePackets(packetSource *gopacket.PacketSource, iface string, proc *Processor, keep func(*SnifPacket) bool, packets chan *SnifPacket, wg *sync.WaitGroup) {
 	defer wg.Done()
-	defer close(packets)
-
-	localNets, _, err := GetLocalAddrs(iface)
-	filterEnabled := true
-	if err != nil {
-		log.Printf("failed to get local addresses for interface %s: %v; outgoing filtering disabled", iface, err)
-		filterEnabled = false
-	}
-	isInLocal := func(ipStr string) bool {
-		ip := net.ParseIP(ipStr)
-		if ip == nil {
-			return false
-		}
-		for _, n := range localNets {
-			if n.Contains(ip) {
-				return true
-			}
-		}
-		return false
-	}
 
 	// Diagnostics & counters
 	var received uint64
@@ -49,19 +28,15 @@ LOOP:
 				break LOOP
 			}
 
-			sp, err := ProcessPacket(packet)
+			sp, err := proc.Process(packet)
 			if err != nil {
 				continue
 			}
 
-			if filterEnabled {
-				srcIn := isInLocal(sp.SrcIP)
-				dstIn := isInLocal(sp.DstIP)
-				// Keep only packets going out of local network
-				if !srcIn || dstIn {
-					continue
-				}
+			if !keep(sp) {
+				continue
 			}
+			sp.Interface = iface
 
 			select {
 			case packets <- sp:
@@ -70,13 +45,13 @@ LOOP:
 				// channel full, drop packet
 				atomic.AddUint64(&dropped, 1)
 				if atomic.LoadUint64(&dropped)%1000 == 0 {
-					log.Printf("packets channel full, dropped=%d, received=%d, len(packets)=%d", atomic.LoadUint64(&dropped), atomic.LoadUint64(&received), len(packets))
+					log.Printf("packets channel full on %s, dropped=%d, received=%d, len(packets)=%d", iface, atomic.LoadUint64(&dropped), atomic.LoadUint64(&received), len(packets))
 				}
 			}
 
 		case <-ticker.C:
 			// periodic status
-			log.Printf("capture status: received=%d dropped=%d queue_len=%d", atomic.LoadUint64(&received), atomic.LoadUint64(&dropped), len(packets))
+			log.Printf("capture status %s: received=%d dropped=%d queue_len=%d", iface, atomic.LoadUint64(&received), atomic.LoadUint64(&dropped), len(packets))
 		}
 	}
 	log.Printf("Packet receiving goroutine for interface %s exiting", iface)
@@ -0,0 +1,236 @@
+package snifpacket
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clientHello holds the ClientHello fields used for fingerprinting.
+type clientHello struct {
+	legacyVersion     uint16
+	ciphers           []uint16
+	extensions        []uint16
+	groups            []uint16
+	pointFormats      []uint8
+	sigAlgs           []uint16
+	supportedVersions []uint16
+	alpn              []string
+	sni               string
+}
+
+// parseClientHello reads a ClientHello from a TLS handshake record. Only
+// the version is required, later fields may be cut off.
+func parseClientHello(record []byte) (*clientHello, bool) {
+	if len(record) < 11 || record[0] != 0x16 || record[5] != 0x01 {
+		return nil, false
+	}
+	ch := &clientHello{legacyVersion: binary.BigEndian.Uint16(record[9:])}
+
+	const sessionIDLenOffset = 43
+	if len(record) > sessionIDLenOffset {
+		csStart := sessionIDLenOffset + 1 + int(record[sessionIDLenOffset])
+		if len(record) >= csStart+2 {
+			csLen := int(binary.BigEndian.Uint16(record[csStart:]))
+			suites := record[csStart+2:]
+			if len(suites) > csLen {
+				suites = suites[:csLen]
+			}
+			ch.ciphers = uint16List(suites)
+		}
+	}
+
+	walkExtensions(record, func(extType uint16, ext []byte) bool {
+		ch.extensions = append(ch.extensions, extType)
+		switch extType {
+		case 0x0000:
+			if len(ext) >= 5 {
+				nameLen := int(binary.BigEndian.Uint16(ext[3:]))
+				if 5+nameLen <= len(ext) {
+					ch.sni = string(ext[5 : 5+nameLen])
+				}
+			}
+		case 0x000a:
+			if len(ext) >= 2 {
+				ch.groups = uint16List(ext[2:])
+			}
+		case 0x000b:
+			if len(ext) >= 1 {
+				ch.pointFormats = append([]uint8(nil), ext[1:]...)
+			}
+		case 0x000d:
+			if len(ext) >= 2 {
+				ch.sigAlgs = uint16List(ext[2:])
+			}
+		case 0x002b:
+			if len(ext) >= 1 {
+				ch.supportedVersions = uint16List(ext[1:])
+			}
+		}
+		return true
+	})
+	ch.alpn = extractALPN(record)
+	return ch, true
+}
+
+func uint16List(b []byte) []uint16 {
+	out := make([]uint16, 0, len(b)/2)
+	for ; len(b) >= 2; b = b[2:] {
+		out = append(out, binary.BigEndian.Uint16(b))
+	}
+	return out
+}
+
+// GREASE values (RFC 8701) are random and left out of fingerprints
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func withoutGREASE(values []uint16) []uint16 {
+	out := make([]uint16, 0, len(values))
+	for _, v := range values {
+		if !isGREASE(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// version is the highest supported_versions entry, or the legacy version
+// for clients without the extension.
+func (ch *clientHello) version() uint16 {
+	var best uint16
+	for _, v := range withoutGREASE(ch.supportedVersions) {
+		if v > best {
+			best = v
+		}
+	}
+	if best == 0 {
+		return ch.legacyVersion
+	}
+	return best
+}
+
+func tlsVersionName(v uint16) string {
+	switch v {
+	case 0x0300:
+		return "SSL 3.0"
+	case 0x0301:
+		return "TLS 1.0"
+	case 0x0302:
+		return "TLS 1.1"
+	case 0x0303:
+		return "TLS 1.2"
+	case 0x0304:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// ja3 returns the MD5 JA3 hash of the hello.
+func (ch *clientHello) ja3() string {
+	join := func(values []uint16) string {
+		parts := make([]string, 0, len(values))
+		for _, v := range withoutGREASE(values) {
+			parts = append(parts, strconv.Itoa(int(v)))
+		}
+		return strings.Join(parts, "-")
+	}
+	formats := make([]string, 0, len(ch.pointFormats))
+	for _, f := range ch.pointFormats {
+		formats = append(formats, strconv.Itoa(int(f)))
+	}
+
+	s := strings.Join([]string{
+		strconv.Itoa(int(ch.legacyVersion)),
+		join(ch.ciphers),
+		join(ch.extensions),
+		join(ch.groups),
+		strings.Join(formats, "-"),
+	}, ",")
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// ja4 returns the JA4 fingerprint, transport is 't' for TCP or 'q' for QUIC.
+func (ch *clientHello) ja4(transport byte) string {
+	ciphers := withoutGREASE(ch.ciphers)
+	extensions := withoutGREASE(ch.extensions)
+
+	version := "00"
+	switch ch.version() {
+	case 0x0304:
+		version = "13"
+	case 0x0303:
+		version = "12"
+	case 0x0302:
+		version = "11"
+	case 0x0301:
+		version = "10"
+	case 0x0300:
+		version = "s3"
+	}
+
+	sni := byte('i')
+	if ch.sni != "" {
+		sni = 'd'
+	}
+
+	alpn := "00"
+	if len(ch.alpn) > 0 && ch.alpn[0] != "" {
+		first, last := ch.alpn[0][0], ch.alpn[0][len(ch.alpn[0])-1]
+		if isAlnum(first) && isAlnum(last) {
+			alpn = string([]byte{first, last})
+		} else {
+			alpn = string([]byte{hex.EncodeToString([]byte{first})[0], hex.EncodeToString([]byte{last})[1]})
+		}
+	}
+
+	a := fmt.Sprintf("%c%s%c%02d%02d%s", transport, version, sni, min(len(ciphers), 99), min(len(extensions), 99), alpn)
+
+	sortedCiphers := sortedHex(ciphers)
+	b := truncatedSHA256(strings.Join(sortedCiphers, ","), len(sortedCiphers) == 0)
+
+	var exts []uint16
+	for _, e := range extensions {
+		// SNI and ALPN are already part of the first section
+		if e != 0x0000 && e != 0x0010 {
+			exts = append(exts, e)
+		}
+	}
+	c := strings.Join(sortedHex(exts), ",")
+	if len(ch.sigAlgs) > 0 {
+		sigs := make([]string, 0, len(ch.sigAlgs))
+		for _, s := range withoutGREASE(ch.sigAlgs) {
+			sigs = append(sigs, fmt.Sprintf("%04x", s))
+		}
+		c += "_" + strings.Join(sigs, ",")
+	}
+	return a + "_" + b + "_" + truncatedSHA256(c, len(exts) == 0)
+}
+
+func sortedHex(values []uint16) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, fmt.Sprintf("%04x", v))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func truncatedSHA256(s string, empty bool) string {
+	if empty {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,159 @@
+package snifpacket
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+type testExtension struct {
+	typ  uint16
+	data []byte
+}
+
+// testHelloRecord builds a TLS record holding a ClientHello.
+func testHelloRecord(version uint16, ciphers []uint16, extensions []testExtension) []byte {
+	u16 := func(b []byte, v int) []byte { return binary.BigEndian.AppendUint16(b, uint16(v)) }
+
+	body := u16(nil, int(version))
+	body = append(body, make([]byte, 32)...) // random
+	body = append(body, 0)                   // session ID
+	body = u16(body, 2*len(ciphers))
+	for _, c := range ciphers {
+		body = u16(body, int(c))
+	}
+	body = append(body, 1, 0) // null compression
+
+	var exts []byte
+	for _, e := range extensions {
+		exts = u16(exts, int(e.typ))
+		exts = u16(exts, len(e.data))
+		exts = append(exts, e.data...)
+	}
+	body = u16(body, len(exts))
+	body = append(body, exts...)
+
+	hs := append([]byte{0x01, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}, body...)
+	return append(u16([]byte{0x16, 0x03, 0x01}, len(hs)), hs...)
+}
+
+// testList encodes values as a list with a length prefix of prefixLen bytes.
+func testList(prefixLen int, values ...uint16) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint16(b, v)
+	}
+	if prefixLen == 1 {
+		return append([]byte{byte(len(b))}, b...)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(b))), b...)
+}
+
+func testSNI(name string) []byte {
+	entry := append([]byte{0}, binary.BigEndian.AppendUint16(nil, uint16(len(name)))...)
+	entry = append(entry, name...)
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(entry))), entry...)
+}
+
+func testALPN(protocols ...string) []byte {
+	var list []byte
+	for _, p := range protocols {
+		list = append(list, byte(len(p)))
+		list = append(list, p...)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(list))), list...)
+}
+
+func TestJA3(t *testing.T) {
+	// The example of the JA3 README:
+	// 769,47-53-5-10-49161-49162-49171-49172-50-56-19-4,0-10-11,23-24-25,0
+	const want = "ada70206e40642a3e4461f35503241d5"
+	ciphers := []uint16{47, 53, 5, 10, 49161, 49162, 49171, 49172, 50, 56, 19, 4}
+	extensions := []testExtension{
+		{0x0000, testSNI("example.com")},
+		{0x000a, testList(2, 23, 24, 25)},
+		{0x000b, []byte{1, 0}},
+	}
+
+	tests := []struct {
+		name       string
+		ciphers    []uint16
+		extensions []testExtension
+	}{
+		{"published", ciphers, extensions},
+		{"grease", append([]uint16{0x1a1a}, ciphers...), []testExtension{
+			{0xfafa, nil},
+			extensions[0],
+			{0x000a, testList(2, 0x2a2a, 23, 24, 25)},
+			extensions[2],
+			{0x0a0a, []byte{0}},
+		}},
+	}
+	for _, tt := range tests {
+		ch, ok := parseClientHello(testHelloRecord(0x0301, tt.ciphers, tt.extensions))
+		if !ok {
+			t.Fatalf("%s: ClientHello not parsed", tt.name)
+		}
+		if got := ch.ja3(); got != want {
+			t.Errorf("%s: ja3 = %s, want %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestJA4(t *testing.T) {
+	// The Chrome example of the JA4 technical details
+	ciphers := []uint16{0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035}
+	sigAlgs := testList(2, 0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601)
+	chrome := func(alpn []byte, grease bool) []byte {
+		extensions := []testExtension{
+			{0x0000, testSNI("example.com")},
+			{0x0017, nil},
+			{0xff01, []byte{0}},
+			{0x000a, testList(2, 0x001d, 0x0017, 0x0018)},
+			{0x000b, []byte{1, 0}},
+			{0x0023, nil},
+			{0x0010, alpn},
+			{0x0005, []byte{1, 0, 0, 0, 0}},
+			{0x000d, sigAlgs},
+			{0x0012, nil},
+			{0x0033, testList(2)},
+			{0x002d, []byte{1, 1}},
+			{0x002b, testList(1, 0x0304, 0x0303)},
+			{0x001b, []byte{2, 0, 2}},
+			{0x4469, testList(2)},
+			{0x0015, make([]byte, 16)},
+		}
+		cs := ciphers
+		if grease {
+			cs = append([]uint16{0x8a8a}, ciphers...)
+			extensions = append([]testExtension{{0x3a3a, nil}}, extensions...)
+			extensions = append(extensions, testExtension{0xdada, []byte{0}})
+			extensions[13].data = testList(1, 0x7a7a, 0x0304, 0x0303)
+		}
+		return testHelloRecord(0x0303, cs, extensions)
+	}
+
+	tests := []struct {
+		name      string
+		record    []byte
+		transport byte
+		want      string
+	}{
+		{"published", chrome(testALPN("h2", "http/1.1"), false), 't', "t13d1516h2_8daaf6152771_e5627efa2ab1"},
+		{"grease", chrome(testALPN("h2", "http/1.1"), true), 't', "t13d1516h2_8daaf6152771_e5627efa2ab1"},
+		{"quic", chrome(testALPN("h3"), false), 'q', "q13d1516h3_8daaf6152771_e5627efa2ab1"},
+		// Non-alphanumeric ALPN values are shown as the outer hex digits
+		{"alpn hex byte", chrome(testALPN("\xab"), false), 't', "t13d1516ab_8daaf6152771_e5627efa2ab1"},
+		{"alpn hex bytes", chrome(testALPN("\xab\xcd"), false), 't', "t13d1516ad_8daaf6152771_e5627efa2ab1"},
+		{"no alpn", chrome(testALPN(), false), 't', "t13d151600_8daaf6152771_e5627efa2ab1"},
+		{"tls 1.2 without extensions", testHelloRecord(0x0303, []uint16{0x002f}, nil), 't', "t12i010000_ba72b8082249_000000000000"},
+	}
+	for _, tt := range tests {
+		ch, ok := parseClientHello(tt.record)
+		if !ok {
+			t.Fatalf("%s: ClientHello not parsed", tt.name)
+		}
+		if got := ch.ja4(tt.transport); got != tt.want {
+			t.Errorf("%s: ja4 = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package snifpacket
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"strings"
+)
+
+// Legacy suites crypto/tls has no name for, kept so weak ones are
+// recognisable downstream.
+var legacyCipherSuiteNames = map[uint16]string{
+	0x0000: "TLS_NULL_WITH_NULL_NULL",
+	0x0001: "TLS_RSA_WITH_NULL_MD5",
+	0x0002: "TLS_RSA_WITH_NULL_SHA",
+	0x0003: "TLS_RSA_EXPORT_WITH_RC4_40_MD5",
+	0x0004: "TLS_RSA_WITH_RC4_128_MD5",
+	0x0006: "TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+	0x0008: "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0009: "TLS_RSA_WITH_DES_CBC_SHA",
+	0x0011: "TLS_DHE_DSS_EXPORT_WITH_DES40_CBC_SHA",
+	0x0014: "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0015: "TLS_DHE_RSA_WITH_DES_CBC_SHA",
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0018: "TLS_DH_anon_WITH_RC4_128_MD5",
+	0x001b: "TLS_DH_anon_WITH_3DES_EDE_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0034: "TLS_DH_anon_WITH_AES_128_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x003a: "TLS_DH_anon_WITH_AES_256_CBC_SHA",
+	0x003b: "TLS_RSA_WITH_NULL_SHA256",
+	0x009e: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009f: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xc006: "TLS_ECDHE_ECDSA_WITH_NULL_SHA",
+	0xc010: "TLS_ECDHE_RSA_WITH_NULL_SHA",
+}
+
+func tlsCipherSuiteName(id uint16) string {
+	name := tls.CipherSuiteName(id)
+	if strings.HasPrefix(name, "0x") {
+		if legacy, ok := legacyCipherSuiteNames[id]; ok {
+			return legacy
+		}
+	}
+	return name
+}
+
+// parseTLSServerHello parses the start of the server side of a TLS
+// connection: the ServerHello and, up to TLS 1.2, the leaf certificate.
+// ok is false when the data is not a ServerHello; complete is false while
+// more of the stream could still add the certificate.
+func parseTLSServerHello(data []byte) (details *SnifPacketDetailsTLSServer, complete bool, ok bool) {
+	handshake, ended, ok := tlsHandshakeBytes(data)
+	if !ok {
+		return nil, false, false
+	}
+	if len(handshake) < 4 {
+		return nil, ended, true
+	}
+	if handshake[0] != 0x02 {
+		return nil, false, false
+	}
+	helloLen := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+	if len(handshake) < 4+helloLen {
+		return nil, ended, true
+	}
+
+	details, version, ok := parseServerHelloBody(handshake[4 : 4+helloLen])
+	if !ok {
+		return nil, false, false
+	}
+	// TLS 1.3 encrypts everything after the ServerHello
+	if version >= 0x0304 {
+		return details, true, true
+	}
+
+	rest := handshake[4+helloLen:]
+	if len(rest) < 4 {
+		return details, ended, true
+	}
+	if rest[0] != 0x0b {
+		// Resumed session or anonymous key exchange
+		return details, true, true
+	}
+	// Certificate: list length, then length-prefixed DER certificates
+	if len(rest) < 4+3+3 {
+		return details, ended, true
+	}
+	certLen := int(rest[7])<<16 | int(rest[8])<<8 | int(rest[9])
+	if len(rest) < 10+certLen {
+		return details, ended, true
+	}
+	details.Certificate = parseLeafCertificate(rest[10 : 10+certLen])
+	return details, true, true
+}
+
+// tlsHandshakeBytes joins the payloads of the leading handshake records.
+// ended is set once a non-handshake record shows the handshake is over.
+func tlsHandshakeBytes(data []byte) (handshake []byte, ended bool, ok bool) {
+	if len(data) < 5 || data[0] != 0x16 || data[1] != 0x03 {
+		return nil, false, false
+	}
+	for len(data) >= 5 {
+		if data[0] != 0x16 {
+			return handshake, true, true
+		}
+		recLen := int(binary.BigEndian.Uint16(data[3:5]))
+		end := 5 + recLen
+		if end > len(data) {
+			return append(handshake, data[5:]...), false, true
+		}
+		handshake = append(handshake, data[5:end]...)
+		data = data[end:]
+	}
+	return handshake, false, true
+}
+
+func parseServerHelloBody(body []byte) (*SnifPacketDetailsTLSServer, uint16, bool) {
+	// legacy_version, random, session ID
+	if len(body) < 2+32+1 {
+		return nil, 0, false
+	}
+	version := binary.BigEndian.Uint16(body)
+	pos := 2 + 32
+	pos += 1 + int(body[pos])
+	// cipher suite and compression method
+	if len(body) < pos+3 {
+		return nil, 0, false
+	}
+	cipher := binary.BigEndian.Uint16(body[pos:])
+	pos += 3
+
+	details := &SnifPacketDetailsTLSServer{CipherSuite: cipher, Cipher: tlsCipherSuiteName(cipher)}
+	if len(body) >= pos+2 {
+		extLen := int(binary.BigEndian.Uint16(body[pos:]))
+		exts := body[pos+2:]
+		if len(exts) > extLen {
+			exts = exts[:extLen]
+		}
+		for len(exts) >= 4 {
+			extType := binary.BigEndian.Uint16(exts)
+			l := int(binary.BigEndian.Uint16(exts[2:]))
+			if len(exts) < 4+l {
+				break
+			}
+			ext := exts[4 : 4+l]
+			switch extType {
+			case 0x002b:
+				if len(ext) == 2 {
+					version = binary.BigEndian.Uint16(ext)
+				}
+			case 0x0010:
+				// A single protocol in the list
+				if len(ext) >= 3 && len(ext) >= 3+int(ext[2]) {
+					details.ALPN = string(ext[3 : 3+int(ext[2])])
+				}
+			}
+			exts = exts[4+l:]
+		}
+	}
+	details.Version = tlsVersionName(version)
+	return details, version, true
+}
+
+func parseLeafCertificate(der []byte) *SnifPacketTLSCertificate {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil
+	}
+	out := &SnifPacketTLSCertificate{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		NotBefore:          cert.NotBefore.Unix(),
+		NotAfter:           cert.NotAfter.Unix(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+	}
+	out.SANs = append(out.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		out.SANs = append(out.SANs, ip.String())
+	}
+	// Self-issued and signed with its own key; CA constraints are not
+	// checked since device certificates rarely carry them
+	out.SelfSigned = bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+	return out
+}
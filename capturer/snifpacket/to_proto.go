@@ -6,15 +6,369 @@ import (
 	pb "github.com/nrf24l01/sniffly/capture_receiver/proto"
 )
 
+// ProtoSourceID identifies this capturer implementation on the wire.
+const ProtoSourceID = "capturer-go"
+
+// ToProto converts the packet into the typed protobuf message.
 func (sp *SnifPacket) ToProto() (*pb.Packet, error) {
+	return &pb.Packet{
+		Timestamp: sp.Timestamp,
+		SourceId:  ProtoSourceID,
+		Packet:    sp.toCapturedPacket(),
+	}, nil
+}
+
+// ToJSONProto wraps the JSON-encoded packet into Packet.payload, which is
+// what receivers and analyzers without typed packet support understand.
+func (sp *SnifPacket) ToJSONProto() (*pb.Packet, error) {
 	payload, err := json.Marshal(sp)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	return &pb.Packet{
-		Timestamp:    sp.Timestamp,
-		Payload:      payload,
-		SourceId:     "capturer-go",
+		Timestamp: sp.Timestamp,
+		Payload:   payload,
+		SourceId:  ProtoSourceID,
 	}, nil
-}
\ No newline at end of file
+}
+
+func (sp *SnifPacket) toCapturedPacket() *pb.CapturedPacket {
+	out := &pb.CapturedPacket{
+		SrcIp:     sp.SrcIP,
+		DstIp:     sp.DstIP,
+		SrcMac:    sp.SrcMAC,
+		DstMac:    sp.DstMAC,
+		SrcPort:   sp.SrcPort,
+		DstPort:   sp.DstPort,
+		Size:      int64(sp.Size),
+		Protocol:  sp.Protocol,
+		Timestamp: sp.Timestamp,
+		TcpFlags:  uint32(sp.TCPFlags),
+		Interface: sp.Interface,
+		Vlan:      uint32(sp.VLAN),
+		OuterVlan: uint32(sp.OuterVLAN),
+		Details:   &pb.PacketDetails{Type: pb.PacketType(sp.Details.Type)},
+	}
+
+	switch sp.Direction {
+	case SnifPacketDirectionUp:
+		out.Direction = pb.PacketDirection_PACKET_DIRECTION_UP
+	case SnifPacketDirectionDown:
+		out.Direction = pb.PacketDirection_PACKET_DIRECTION_DOWN
+	case SnifPacketDirectionLAN:
+		out.Direction = pb.PacketDirection_PACKET_DIRECTION_LAN
+	}
+
+	if d := sp.Details.HTTP; d != nil {
+		out.Details.Http = &pb.HTTPDetails{
+			Method:        d.Method,
+			Host:          d.Host,
+			Path:          d.Path,
+			Body:          d.Body,
+			Sni:           d.Sni,
+			IsResponse:    d.IsResponse,
+			StatusCode:    int32(d.StatusCode),
+			UserAgent:     d.UserAgent,
+			ContentType:   d.ContentType,
+			ContentLength: d.ContentLength,
+			BodySize:      d.BodySize,
+		}
+	}
+	if d := sp.Details.TLS; d != nil {
+		out.Details.Tls = &pb.TLSDetails{Sni: d.Sni, TlsVersion: d.TLSVersion, Ja3: d.JA3, Ja4: d.JA4}
+	}
+	if d := sp.Details.DNS; d != nil {
+		out.Details.Dns = dnsToProto(d)
+	}
+	if d := sp.Details.FTP; d != nil {
+		out.Details.Ftp = &pb.FTPDetails{
+			Command:      d.Command,
+			Args:         d.Args,
+			ResponseCode: uint32(d.ResponseCode),
+			DataEndpoint: d.DataEndpoint,
+			Data:         d.Data,
+			Session:      d.Session,
+		}
+	}
+	if d := sp.Details.TCP; d != nil {
+		out.Details.Tcp = &pb.TCPDetails{Data: d.Data}
+	}
+	if d := sp.Details.UDP; d != nil {
+		out.Details.Udp = &pb.UDPDetails{Data: d.Data}
+	}
+	if d := sp.Details.QUIC; d != nil {
+		out.Details.Quic = &pb.QUICDetails{Sni: d.Sni, Alpn: d.ALPN, Version: d.Version, Ja3: d.JA3, Ja4: d.JA4}
+	}
+	if d := sp.Details.TLSServer; d != nil {
+		out.Details.TlsServer = tlsServerToProto(d)
+	}
+	if d := sp.Details.DHCP; d != nil {
+		out.Details.Dhcp = &pb.DHCPDetails{
+			Version:          uint32(d.Version),
+			MessageType:      d.MessageType,
+			ClientMac:        d.ClientMAC,
+			ClientIp:         d.ClientIP,
+			RequestedIp:      d.RequestedIP,
+			Hostname:         d.Hostname,
+			VendorClass:      d.VendorClass,
+			ParamRequestList: d.ParamRequestList,
+		}
+	}
+	if d := sp.Details.MDNS; d != nil {
+		out.Details.Mdns = &pb.MDNSDetails{
+			Hostname:     d.Hostname,
+			Services:     d.Services,
+			Instances:    d.Instances,
+			Model:        d.Model,
+			Manufacturer: d.Manufacturer,
+			Version:      d.Version,
+		}
+	}
+	if d := sp.Details.SSDP; d != nil {
+		out.Details.Ssdp = &pb.SSDPDetails{Method: d.Method, Server: d.Server, UserAgent: d.UserAgent, Usn: d.USN, Location: d.Location, Target: d.Target}
+	}
+	if d := sp.Details.MQTT; d != nil {
+		out.Details.Mqtt = &pb.MQTTDetails{
+			Types:         d.Types,
+			ClientId:      d.ClientID,
+			ProtocolLevel: uint32(d.ProtocolLevel),
+			KeepAlive:     uint32(d.KeepAlive),
+			HasUsername:   d.HasUsername,
+			HasPassword:   d.HasPassword,
+			Published:     d.Published,
+			Subscribed:    d.Subscribed,
+		}
+	}
+	if d := sp.Details.CoAP; d != nil {
+		out.Details.Coap = &pb.CoAPDetails{Type: d.Type, Method: d.Method, UriHost: d.URIHost, UriPath: d.URIPath}
+	}
+	if d := sp.Details.ICMP; d != nil {
+		out.Details.Icmp = &pb.ICMPDetails{
+			Version:  uint32(d.Version),
+			Type:     uint32(d.Type),
+			Code:     uint32(d.Code),
+			Name:     d.Name,
+			TargetIp: d.TargetIP,
+			LinkMac:  d.LinkMAC,
+		}
+	}
+	if d := sp.Details.ARP; d != nil {
+		out.Details.Arp = &pb.ARPDetails{Operation: d.Operation, SenderMac: d.SenderMAC, SenderIp: d.SenderIP, TargetMac: d.TargetMAC, TargetIp: d.TargetIP}
+	}
+	if d := sp.Details.IPsec; d != nil {
+		out.Details.Ipsec = &pb.IPsecDetails{Protocol: d.Protocol, Spi: d.SPI, Seq: d.Seq}
+	}
+
+	if f := sp.Flow; f != nil {
+		out.Flow = &pb.Flow{
+			Start:       f.Start,
+			End:         f.End,
+			UpBytes:     f.UpBytes,
+			DownBytes:   f.DownBytes,
+			UpPackets:   f.UpPackets,
+			DownPackets: f.DownPackets,
+			EndReason:   f.EndReason,
+		}
+	}
+
+	return out
+}
+
+// FromProto converts a typed protobuf packet back into a SnifPacket.
+func FromProto(p *pb.CapturedPacket) SnifPacket {
+	sp := SnifPacket{
+		SrcIP:     p.GetSrcIp(),
+		DstIP:     p.GetDstIp(),
+		SrcMAC:    p.GetSrcMac(),
+		DstMAC:    p.GetDstMac(),
+		SrcPort:   p.GetSrcPort(),
+		DstPort:   p.GetDstPort(),
+		Size:      int(p.GetSize()),
+		Protocol:  p.GetProtocol(),
+		Timestamp: p.GetTimestamp(),
+		TCPFlags:  uint8(p.GetTcpFlags()),
+		Interface: p.GetInterface(),
+		VLAN:      uint16(p.GetVlan()),
+		OuterVLAN: uint16(p.GetOuterVlan()),
+	}
+
+	switch p.GetDirection() {
+	case pb.PacketDirection_PACKET_DIRECTION_UP:
+		sp.Direction = SnifPacketDirectionUp
+	case pb.PacketDirection_PACKET_DIRECTION_DOWN:
+		sp.Direction = SnifPacketDirectionDown
+	case pb.PacketDirection_PACKET_DIRECTION_LAN:
+		sp.Direction = SnifPacketDirectionLAN
+	}
+
+	d := p.GetDetails()
+	sp.Details.Type = SnifPacketType(d.GetType())
+	if h := d.GetHttp(); h != nil {
+		sp.Details.HTTP = &SnifPacketDetailsHTTP{
+			Method:        h.GetMethod(),
+			Host:          h.GetHost(),
+			Path:          h.GetPath(),
+			Body:          h.GetBody(),
+			Sni:           h.GetSni(),
+			IsResponse:    h.GetIsResponse(),
+			StatusCode:    int(h.GetStatusCode()),
+			UserAgent:     h.GetUserAgent(),
+			ContentType:   h.GetContentType(),
+			ContentLength: h.GetContentLength(),
+			BodySize:      h.GetBodySize(),
+		}
+	}
+	if t := d.GetTls(); t != nil {
+		sp.Details.TLS = &SnifPacketDetailsTLS{Sni: t.GetSni(), TLSVersion: t.GetTlsVersion(), JA3: t.GetJa3(), JA4: t.GetJa4()}
+	}
+	if q := d.GetDns(); q != nil {
+		sp.Details.DNS = dnsFromProto(q)
+	}
+	if f := d.GetFtp(); f != nil {
+		sp.Details.FTP = &SnifPacketDetailsFTP{
+			Command:      f.GetCommand(),
+			Args:         f.GetArgs(),
+			ResponseCode: int(f.GetResponseCode()),
+			DataEndpoint: f.GetDataEndpoint(),
+			Data:         f.GetData(),
+			Session:      f.GetSession(),
+		}
+	}
+	if t := d.GetTcp(); t != nil {
+		sp.Details.TCP = &SnifPacketDetailsTCP{Data: t.GetData()}
+	}
+	if u := d.GetUdp(); u != nil {
+		sp.Details.UDP = &SnifPacketDetailsUDP{Data: u.GetData()}
+	}
+	if q := d.GetQuic(); q != nil {
+		sp.Details.QUIC = &SnifPacketDetailsQUIC{Sni: q.GetSni(), ALPN: q.GetAlpn(), Version: q.GetVersion(), JA3: q.GetJa3(), JA4: q.GetJa4()}
+	}
+	if t := d.GetTlsServer(); t != nil {
+		sp.Details.TLSServer = tlsServerFromProto(t)
+	}
+	if h := d.GetDhcp(); h != nil {
+		sp.Details.DHCP = &SnifPacketDetailsDHCP{
+			Version:          int(h.GetVersion()),
+			MessageType:      h.GetMessageType(),
+			ClientMAC:        h.GetClientMac(),
+			ClientIP:         h.GetClientIp(),
+			RequestedIP:      h.GetRequestedIp(),
+			Hostname:         h.GetHostname(),
+			VendorClass:      h.GetVendorClass(),
+			ParamRequestList: h.GetParamRequestList(),
+		}
+	}
+	if m := d.GetMdns(); m != nil {
+		sp.Details.MDNS = &SnifPacketDetailsMDNS{
+			Hostname:     m.GetHostname(),
+			Services:     m.GetServices(),
+			Instances:    m.GetInstances(),
+			Model:        m.GetModel(),
+			Manufacturer: m.GetManufacturer(),
+			Version:      m.GetVersion(),
+		}
+	}
+	if s := d.GetSsdp(); s != nil {
+		sp.Details.SSDP = &SnifPacketDetailsSSDP{Method: s.GetMethod(), Server: s.GetServer(), UserAgent: s.GetUserAgent(), USN: s.GetUsn(), Location: s.GetLocation(), Target: s.GetTarget()}
+	}
+	if m := d.GetMqtt(); m != nil {
+		sp.Details.MQTT = &SnifPacketDetailsMQTT{
+			Types:         m.GetTypes(),
+			ClientID:      m.GetClientId(),
+			ProtocolLevel: int(m.GetProtocolLevel()),
+			KeepAlive:     uint16(m.GetKeepAlive()),
+			HasUsername:   m.GetHasUsername(),
+			HasPassword:   m.GetHasPassword(),
+			Published:     m.GetPublished(),
+			Subscribed:    m.GetSubscribed(),
+		}
+	}
+	if c := d.GetCoap(); c != nil {
+		sp.Details.CoAP = &SnifPacketDetailsCoAP{Type: c.GetType(), Method: c.GetMethod(), URIHost: c.GetUriHost(), URIPath: c.GetUriPath()}
+	}
+	if i := d.GetIcmp(); i != nil {
+		sp.Details.ICMP = &SnifPacketDetailsICMP{
+			Version:  int(i.GetVersion()),
+			Type:     uint8(i.GetType()),
+			Code:     uint8(i.GetCode()),
+			Name:     i.GetName(),
+			TargetIP: i.GetTargetIp(),
+			LinkMAC:  i.GetLinkMac(),
+		}
+	}
+	if a := d.GetArp(); a != nil {
+		sp.Details.ARP = &SnifPacketDetailsARP{Operation: a.GetOperation(), SenderMAC: a.GetSenderMac(), SenderIP: a.GetSenderIp(), TargetMAC: a.GetTargetMac(), TargetIP: a.GetTargetIp()}
+	}
+	if i := d.GetIpsec(); i != nil {
+		sp.Details.IPsec = &SnifPacketDetailsIPsec{Protocol: i.GetProtocol(), SPI: i.GetSpi(), Seq: i.GetSeq()}
+	}
+
+	if f := p.GetFlow(); f != nil {
+		sp.Flow = &SnifPacketFlow{
+			Start:       f.GetStart(),
+			End:         f.GetEnd(),
+			UpBytes:     f.GetUpBytes(),
+			DownBytes:   f.GetDownBytes(),
+			UpPackets:   f.GetUpPackets(),
+			DownPackets: f.GetDownPackets(),
+			EndReason:   f.GetEndReason(),
+		}
+	}
+
+	return sp
+}
+
+func dnsToProto(d *SnifPacketDetailsDNS) *pb.DNSDetails {
+	out := &pb.DNSDetails{Queries: d.Queries, IsQuery: d.IsQuery, Id: uint32(d.ID), Rcode: d.RCode}
+	for _, q := range d.Questions {
+		out.Questions = append(out.Questions, &pb.DNSQuestion{Name: q.Name, Type: q.Type})
+	}
+	for _, a := range d.Answers {
+		out.Answers = append(out.Answers, &pb.DNSAnswer{Name: a.Name, Type: a.Type, Ttl: a.TTL, Data: a.Data, IpHints: a.IPHints})
+	}
+	return out
+}
+
+func dnsFromProto(q *pb.DNSDetails) *SnifPacketDetailsDNS {
+	d := &SnifPacketDetailsDNS{Queries: q.GetQueries(), IsQuery: q.GetIsQuery(), ID: uint16(q.GetId()), RCode: q.GetRcode()}
+	for _, x := range q.GetQuestions() {
+		d.Questions = append(d.Questions, SnifPacketDNSQuestion{Name: x.GetName(), Type: x.GetType()})
+	}
+	for _, a := range q.GetAnswers() {
+		d.Answers = append(d.Answers, SnifPacketDNSAnswer{Name: a.GetName(), Type: a.GetType(), TTL: a.GetTtl(), Data: a.GetData(), IPHints: a.GetIpHints()})
+	}
+	return d
+}
+
+func tlsServerToProto(d *SnifPacketDetailsTLSServer) *pb.TLSServerDetails {
+	out := &pb.TLSServerDetails{Version: d.Version, CipherSuite: uint32(d.CipherSuite), Cipher: d.Cipher, Alpn: d.ALPN}
+	if c := d.Certificate; c != nil {
+		out.Certificate = &pb.TLSCertificate{
+			Subject:            c.Subject,
+			Issuer:             c.Issuer,
+			Sans:               c.SANs,
+			NotBefore:          c.NotBefore,
+			NotAfter:           c.NotAfter,
+			SelfSigned:         c.SelfSigned,
+			SignatureAlgorithm: c.SignatureAlgorithm,
+		}
+	}
+	return out
+}
+
+func tlsServerFromProto(t *pb.TLSServerDetails) *SnifPacketDetailsTLSServer {
+	d := &SnifPacketDetailsTLSServer{Version: t.GetVersion(), CipherSuite: uint16(t.GetCipherSuite()), Cipher: t.GetCipher(), ALPN: t.GetAlpn()}
+	if c := t.GetCertificate(); c != nil {
+		d.Certificate = &SnifPacketTLSCertificate{
+			Subject:            c.GetSubject(),
+			Issuer:             c.GetIssuer(),
+			SANs:               c.GetSans(),
+			NotBefore:          c.GetNotBefore(),
+			NotAfter:           c.GetNotAfter(),
+			SelfSigned:         c.GetSelfSigned(),
+			SignatureAlgorithm: c.GetSignatureAlgorithm(),
+		}
+	}
+	return d
+}
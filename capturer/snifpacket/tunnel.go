@@ -0,0 +1,399 @@
+package snifpacket
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// Encapsulations of mirrored traffic. ERSPAN arrives over GRE and is told
+// apart by the GRE protocol type.
+const (
+	TunnelGRE    = "gre"
+	TunnelERSPAN = "erspan"
+	TunnelVXLAN  = "vxlan"
+	TunnelTZSP   = "tzsp"
+)
+
+const (
+	vxlanPort = 4789
+	tzspPort  = 37008
+)
+
+// GRE protocol types besides the EtherTypes of IPv4 and IPv6
+const (
+	greTransparentEthernet = 0x6558
+	greERSPANTypeII        = 0x88be
+	greERSPANTypeIII       = 0x22eb
+)
+
+const (
+	tzspTypeReceived    = 0
+	tzspTypeTransmitted = 1
+	tzspEncapEthernet   = 1
+	tzspTagPadding      = 0
+	tzspTagEnd          = 1
+)
+
+type TunnelOptions struct {
+	// Labels name the tunnels by sender address, "ip" or "ip/id" with the
+	// VXLAN VNI or ERSPAN session ID. Unnamed tunnels are labelled with
+	// their encapsulation, sender and ID.
+	Labels map[string]string
+	// Match filters inner frames in user space, nil keeps all.
+	Match func(data []byte, linkType layers.LinkType) bool
+}
+
+// tunnelFrame is a frame taken out of its encapsulation.
+type tunnelFrame struct {
+	data          []byte
+	linkType      layers.LinkType
+	encapsulation string
+	id            uint32
+}
+
+// NewTunnelMatcher compiles expr for the inner frames of tunnels, which are
+// Ethernet or, for GRE without a bridged header, raw IP.
+func NewTunnelMatcher(expr string) (func(data []byte, linkType layers.LinkType) bool, error) {
+	matchEthernet, err := NewBPFMatcher(expr, layers.LinkTypeEthernet)
+	if err != nil {
+		return nil, err
+	}
+	matchRaw, err := NewBPFMatcher(expr, layers.LinkTypeRaw)
+	if err != nil {
+		return nil, err
+	}
+	return func(data []byte, linkType layers.LinkType) bool {
+		if linkType == layers.LinkTypeEthernet {
+			return matchEthernet(data)
+		}
+		return matchRaw(data)
+	}, nil
+}
+
+// ParseTunnelLabels parses "ip=label" and "ip/id=label" entries.
+func ParseTunnelLabels(entries []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, label, ok := strings.Cut(entry, "=")
+		label = strings.TrimSpace(label)
+		if !ok || label == "" {
+			return nil, fmt.Errorf("invalid tunnel label %q, expected ip=label", entry)
+		}
+		addr, id, hasID := strings.Cut(strings.TrimSpace(key), "/")
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address in tunnel label %q", entry)
+		}
+		key = ip.String()
+		if hasID {
+			n, err := strconv.ParseUint(id, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ID in tunnel label %q", entry)
+			}
+			key += "/" + strconv.FormatUint(n, 10)
+		}
+		labels[key] = label
+	}
+	return labels, nil
+}
+
+// label names the tunnel a frame came through.
+func (o TunnelOptions) label(sender net.IP, f tunnelFrame) string {
+	key := sender.String()
+	withID := f.encapsulation == TunnelVXLAN || f.encapsulation == TunnelERSPAN
+	if withID {
+		if label, ok := o.Labels[key+"/"+strconv.FormatUint(uint64(f.id), 10)]; ok {
+			return label
+		}
+	}
+	if label, ok := o.Labels[key]; ok {
+		return label
+	}
+	if withID {
+		return fmt.Sprintf("%s-%s-%d", f.encapsulation, key, f.id)
+	}
+	return f.encapsulation + "-" + key
+}
+
+// ListenTunnel opens a socket for one encapsulation, given as
+// "encapsulation:address". VXLAN and TZSP listen on UDP, by default on
+// ports 4789 and 37008; GRE, which also carries ERSPAN, on a raw IP socket.
+func ListenTunnel(spec string) (net.PacketConn, string, error) {
+	encapsulation, addr, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	encapsulation = strings.ToLower(encapsulation)
+	switch encapsulation {
+	case TunnelVXLAN, TunnelTZSP:
+		if addr == "" {
+			port := vxlanPort
+			if encapsulation == TunnelTZSP {
+				port = tzspPort
+			}
+			addr = ":" + strconv.Itoa(port)
+		}
+		conn, err := net.ListenPacket("udp", addr)
+		return conn, encapsulation, err
+	case TunnelGRE, TunnelERSPAN:
+		network := "ip4:gre"
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+			network = "ip6:gre"
+		}
+		conn, err := net.ListenPacket(network, addr)
+		return conn, TunnelGRE, err
+	}
+	return nil, "", fmt.Errorf("unknown tunnel encapsulation in %q, expected gre, vxlan or tzsp", spec)
+}
+
+// decapsulate strips the headers of one encapsulation; for GRE the data
+// starts at the GRE header, for VXLAN and TZSP at the UDP payload.
+func decapsulate(encapsulation string, data []byte) (tunnelFrame, bool) {
+	switch encapsulation {
+	case TunnelGRE:
+		return decapsulateGRE(data)
+	case TunnelVXLAN:
+		return decapsulateVXLAN(data)
+	case TunnelTZSP:
+		return decapsulateTZSP(data)
+	}
+	return tunnelFrame{}, false
+}
+
+// decapsulateGRE handles GRE (RFC 2784, 2890) carrying Ethernet, IPv4 or
+// IPv6, and ERSPAN type I, II and III.
+func decapsulateGRE(data []byte) (tunnelFrame, bool) {
+	if len(data) < 4 {
+		return tunnelFrame{}, false
+	}
+	flags := binary.BigEndian.Uint16(data)
+	if flags&0x0007 != 0 {
+		// Version 1 is PPTP
+		return tunnelFrame{}, false
+	}
+	proto := binary.BigEndian.Uint16(data[2:])
+	off := 4
+	if flags&0xc000 != 0 {
+		off += 4 // checksum and offset
+	}
+	if flags&0x2000 != 0 {
+		off += 4 // key
+	}
+	seq := flags&0x1000 != 0
+	if seq {
+		off += 4
+	}
+	if len(data) < off {
+		return tunnelFrame{}, false
+	}
+	payload := data[off:]
+
+	f := tunnelFrame{data: payload, linkType: layers.LinkTypeEthernet, encapsulation: TunnelGRE}
+	switch proto {
+	case greTransparentEthernet:
+	case uint16(layers.EthernetTypeIPv4), uint16(layers.EthernetTypeIPv6):
+		f.linkType = layers.LinkTypeRaw
+	case greERSPANTypeII:
+		f.encapsulation = TunnelERSPAN
+		// Type I has no sequence number and no ERSPAN header
+		if !seq {
+			return f, true
+		}
+		if len(payload) < 8 {
+			return tunnelFrame{}, false
+		}
+		f.id = uint32(binary.BigEndian.Uint16(payload[2:]) & 0x03ff)
+		f.data = payload[8:]
+	case greERSPANTypeIII:
+		f.encapsulation = TunnelERSPAN
+		if len(payload) < 12 {
+			return tunnelFrame{}, false
+		}
+		f.id = uint32(binary.BigEndian.Uint16(payload[2:]) & 0x03ff)
+		hdr := 12
+		if payload[11]&0x01 != 0 {
+			hdr += 8 // platform specific subheader
+		}
+		if len(payload) < hdr {
+			return tunnelFrame{}, false
+		}
+		f.data = payload[hdr:]
+	default:
+		return tunnelFrame{}, false
+	}
+	return f, true
+}
+
+// decapsulateVXLAN handles VXLAN (RFC 7348) with a valid VNI.
+func decapsulateVXLAN(data []byte) (tunnelFrame, bool) {
+	if len(data) < 8 || data[0]&0x08 == 0 {
+		return tunnelFrame{}, false
+	}
+	vni := binary.BigEndian.Uint32(data[4:]) >> 8
+	return tunnelFrame{data: data[8:], linkType: layers.LinkTypeEthernet, encapsulation: TunnelVXLAN, id: vni}, true
+}
+
+// decapsulateTZSP handles received and transmitted Ethernet frames in TZSP
+// as MikroTik's packet sniffer sends them, skipping the tagged fields.
+func decapsulateTZSP(data []byte) (tunnelFrame, bool) {
+	if len(data) < 4 || data[0] != 1 {
+		return tunnelFrame{}, false
+	}
+	if data[1] != tzspTypeReceived && data[1] != tzspTypeTransmitted {
+		return tunnelFrame{}, false
+	}
+	if binary.BigEndian.Uint16(data[2:]) != tzspEncapEthernet {
+		return tunnelFrame{}, false
+	}
+	off := 4
+	for {
+		if off >= len(data) {
+			return tunnelFrame{}, false
+		}
+		tag := data[off]
+		off++
+		if tag == tzspTagEnd {
+			break
+		}
+		if tag == tzspTagPadding {
+			continue
+		}
+		if off >= len(data) {
+			return tunnelFrame{}, false
+		}
+		off += 1 + int(data[off])
+	}
+	return tunnelFrame{data: data[off:], linkType: layers.LinkTypeEthernet, encapsulation: TunnelTZSP}, true
+}
+
+// tunnelReceiver processes decapsulated frames of one socket or interface.
+type tunnelReceiver struct {
+	name    string
+	proc    *Processor
+	keep    func(*SnifPacket) bool
+	opts    TunnelOptions
+	packets chan *SnifPacket
+
+	received uint64
+	dropped  uint64
+}
+
+func (r *tunnelReceiver) handle(sender net.IP, f tunnelFrame, ts time.Time) {
+	if r.opts.Match != nil && !r.opts.Match(f.data, f.linkType) {
+		return
+	}
+	packet := gopacket.NewPacket(f.data, f.linkType, gopacket.Default)
+	md := packet.Metadata()
+	md.Timestamp = ts
+	md.CaptureLength = len(f.data)
+	md.Length = len(f.data)
+
+	sp, err := r.proc.Process(packet)
+	if err != nil {
+		return
+	}
+	if !r.keep(sp) {
+		return
+	}
+	sp.Interface = r.opts.label(sender, f)
+
+	select {
+	case r.packets <- sp:
+		atomic.AddUint64(&r.received, 1)
+	default:
+		// channel full, drop packet
+		if atomic.AddUint64(&r.dropped, 1)%1000 == 0 {
+			log.Printf("packets channel full on %s, dropped=%d, received=%d, len(packets)=%d", r.name, atomic.LoadUint64(&r.dropped), atomic.LoadUint64(&r.received), len(r.packets))
+		}
+	}
+}
+
+func (r *tunnelReceiver) status() {
+	log.Printf("tunnel status %s: received=%d dropped=%d queue_len=%d", r.name, atomic.LoadUint64(&r.received), atomic.LoadUint64(&r.dropped), len(r.packets))
+}
+
+// ReceiveTunnelSocket reads encapsulated frames from a socket opened with
+// ListenTunnel until it is closed.
+func ReceiveTunnelSocket(conn net.PacketConn, encapsulation string, proc *Processor, keep func(*SnifPacket) bool, opts TunnelOptions, packets chan *SnifPacket, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	r := &tunnelReceiver{name: encapsulation + " " + conn.LocalAddr().String(), proc: proc, keep: keep, opts: opts, packets: packets}
+	lastStatus := time.Now()
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("tunnel socket %s closed: %v", r.name, err)
+			return
+		}
+		var sender net.IP
+		switch a := addr.(type) {
+		case *net.UDPAddr:
+			sender = a.IP
+		case *net.IPAddr:
+			sender = a.IP
+		}
+		if f, ok := decapsulate(encapsulation, buf[:n]); ok {
+			r.handle(sender, f, time.Now())
+		}
+		if time.Since(lastStatus) >= 30*time.Second {
+			r.status()
+			lastStatus = time.Now()
+		}
+	}
+}
+
+// ReceiveTunnelCapture decapsulates GRE and ERSPAN, and VXLAN and TZSP on
+// their default ports from frames captured on an interface.
+func ReceiveTunnelCapture(packetSource *gopacket.PacketSource, iface string, proc *Processor, keep func(*SnifPacket) bool, opts TunnelOptions, packets chan *SnifPacket, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	r := &tunnelReceiver{name: iface, proc: proc, keep: keep, opts: opts, packets: packets}
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	pktCh := packetSource.Packets()
+	for {
+		select {
+		case packet, ok := <-pktCh:
+			if !ok {
+				log.Printf("Tunnel capture on interface %s exiting", iface)
+				return
+			}
+			network := packet.NetworkLayer()
+			if network == nil {
+				continue
+			}
+			sender := net.IP(network.NetworkFlow().Src().Raw())
+			var f tunnelFrame
+			var decapsulated bool
+			if ipProtocol(packet) == layers.IPProtocolGRE {
+				f, decapsulated = decapsulateGRE(network.LayerPayload())
+			} else if udp := packet.Layer(layers.LayerTypeUDP); udp != nil {
+				u := udp.(*layers.UDP)
+				switch u.DstPort {
+				case vxlanPort:
+					f, decapsulated = decapsulateVXLAN(u.Payload)
+				case tzspPort:
+					f, decapsulated = decapsulateTZSP(u.Payload)
+				}
+			}
+			if decapsulated {
+				r.handle(sender, f, packet.Metadata().Timestamp)
+			}
+		case <-ticker.C:
+			r.status()
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package snifpacket
 
-import "net"
+import (
+	"net"
+
+	"github.com/gopacket/gopacket/layers"
+)
 
 func indexOf(haystack, needle []byte) int {
 	for i := 0; i+len(needle) <= len(haystack); i++ {
@@ -56,3 +60,23 @@ func GetLocalAddrs(iface string) ([]*net.IPNet, string, error) {
 
 	return nets, mac, nil
 }
+
+func tcpFlags(t *layers.TCP) uint8 {
+	var flags uint8
+	if t.FIN {
+		flags |= TCPFlagFIN
+	}
+	if t.SYN {
+		flags |= TCPFlagSYN
+	}
+	if t.RST {
+		flags |= TCPFlagRST
+	}
+	if t.PSH {
+		flags |= TCPFlagPSH
+	}
+	if t.ACK {
+		flags |= TCPFlagACK
+	}
+	return flags
+}
@@ -0,0 +1,461 @@
+package spool
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentExt       = ".seg"
+	cursorFileName   = "cursor"
+	recordHeaderSize = 4
+	maxRecordSize    = 16 << 20
+	cursorSaveEvery  = time.Second
+)
+
+var ErrClosed = errors.New("spool closed")
+
+// Options bounds the spool. Zero values disable the corresponding limit.
+type Options struct {
+	// SegmentBytes and SegmentAge decide when the active segment is rotated.
+	SegmentBytes int64
+	SegmentAge   time.Duration
+	// MaxBytes and MaxAge decide when the oldest segments are discarded.
+	MaxBytes int64
+	MaxAge   time.Duration
+}
+
+// Position points right after a record inside a segment.
+type Position struct {
+	Segment uint64
+	Offset  int64
+}
+
+func (p Position) before(o Position) bool {
+	return p.Segment < o.Segment || (p.Segment == o.Segment && p.Offset < o.Offset)
+}
+
+type Record struct {
+	Data []byte
+	Pos  Position
+}
+
+type segment struct {
+	id       uint64
+	size     int64
+	created  time.Time
+	modified time.Time
+}
+
+// Spool is a bounded FIFO of opaque records stored in append-only segment
+// files. Records are read in order and only removed from disk once they are
+// acknowledged, so unacknowledged data survives reconnects and restarts.
+type Spool struct {
+	dir  string
+	opts Options
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	segments    []*segment
+	writer      *os.File
+	reader      *os.File
+	readerSeg   uint64
+	read        Position
+	acked       Position
+	ackedSaved  time.Time
+	writeClosed bool
+	closed      bool
+}
+
+func Open(dir string, opts Options) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{dir: dir, opts: opts}
+	s.cond = sync.NewCond(&s.mu)
+
+	if err := s.loadSegments(); err != nil {
+		return nil, err
+	}
+	cursor, err := s.loadCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	// Always write into a fresh segment so a record torn by a crash can only
+	// ever be at the tail of a sealed segment.
+	var nextID uint64 = 1
+	if n := len(s.segments); n > 0 {
+		nextID = s.segments[n-1].id + 1
+	}
+	if err := s.openWriter(nextID); err != nil {
+		return nil, err
+	}
+
+	s.acked = cursor
+	if first := s.segments[0]; cursor.Segment < first.id || s.segmentLocked(cursor.Segment) == nil {
+		s.acked = Position{Segment: s.firstSegmentFromLocked(cursor.Segment).id}
+	}
+	s.read = s.acked
+	s.removeAckedLocked()
+
+	pending := int64(0)
+	for _, seg := range s.segments {
+		pending += seg.size
+	}
+	log.Printf("spool: opened %s with %d segments, %d bytes pending", dir, len(s.segments), pending-s.acked.Offset)
+	return s, nil
+}
+
+// Append stores one record at the tail of the spool.
+func (s *Spool) Append(data []byte) error {
+	if len(data) > maxRecordSize {
+		return fmt.Errorf("spool: record of %d bytes exceeds limit", len(data))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.writeClosed {
+		return ErrClosed
+	}
+
+	now := time.Now()
+	active := s.segments[len(s.segments)-1]
+	if active.size > 0 && ((s.opts.SegmentBytes > 0 && active.size >= s.opts.SegmentBytes) ||
+		(s.opts.SegmentAge > 0 && now.Sub(active.created) >= s.opts.SegmentAge)) {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+		active = s.segments[len(s.segments)-1]
+	}
+
+	buf := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[recordHeaderSize:], data)
+	if _, err := s.writer.Write(buf); err != nil {
+		return err
+	}
+	active.size += int64(len(buf))
+	active.modified = now
+
+	s.enforceLimitsLocked(now)
+	s.cond.Broadcast()
+	return nil
+}
+
+// Next returns the next unread record, blocking until one is available.
+// It returns io.EOF once CloseWrite was called and everything was read.
+func (s *Spool) Next(ctx context.Context) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer stop()
+
+	for {
+		if s.closed {
+			return Record{}, ErrClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return Record{}, err
+		}
+
+		rec, ok, err := s.readLocked()
+		if err != nil {
+			return Record{}, err
+		}
+		if ok {
+			return rec, nil
+		}
+		if s.writeClosed {
+			return Record{}, io.EOF
+		}
+		s.cond.Wait()
+	}
+}
+
+// Ack marks every record up to and including pos as delivered.
+func (s *Spool) Ack(pos Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || !s.acked.before(pos) {
+		return
+	}
+	s.acked = pos
+	s.removeAckedLocked()
+
+	if time.Since(s.ackedSaved) >= cursorSaveEvery {
+		if err := s.saveCursorLocked(); err != nil {
+			log.Printf("spool: failed to save cursor: %v", err)
+		}
+	}
+}
+
+// Rewind moves the read position back to the last acknowledged record so
+// that everything in flight is sent again.
+func (s *Spool) Rewind() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read = s.acked
+}
+
+// CloseWrite stops accepting new records. Readers drain what is left and
+// then get io.EOF.
+func (s *Spool) CloseWrite() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeClosed = true
+	s.cond.Broadcast()
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.cond.Broadcast()
+
+	err := s.saveCursorLocked()
+	if s.reader != nil {
+		s.reader.Close()
+	}
+	if cerr := s.writer.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *Spool) readLocked() (Record, bool, error) {
+	for {
+		seg := s.segmentLocked(s.read.Segment)
+		if seg == nil {
+			// The segment was discarded by the limits; continue with the next one.
+			next := s.firstSegmentFromLocked(s.read.Segment)
+			if next.id == s.read.Segment {
+				return Record{}, false, nil
+			}
+			s.read = Position{Segment: next.id}
+			continue
+		}
+
+		isActive := seg == s.segments[len(s.segments)-1]
+		if s.read.Offset+recordHeaderSize > seg.size {
+			if isActive {
+				return Record{}, false, nil
+			}
+			s.read = Position{Segment: s.firstSegmentFromLocked(seg.id + 1).id}
+			continue
+		}
+
+		if s.reader == nil || s.readerSeg != seg.id {
+			if s.reader != nil {
+				s.reader.Close()
+				s.reader = nil
+			}
+			f, err := os.Open(s.segmentPath(seg.id))
+			if err != nil {
+				return Record{}, false, err
+			}
+			s.reader = f
+			s.readerSeg = seg.id
+		}
+
+		var header [recordHeaderSize]byte
+		if _, err := s.reader.ReadAt(header[:], s.read.Offset); err != nil {
+			return Record{}, false, err
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		end := s.read.Offset + recordHeaderSize + n
+		if n > maxRecordSize || end > seg.size {
+			if isActive {
+				return Record{}, false, nil
+			}
+			log.Printf("spool: truncated record in segment %d at offset %d, skipping rest of segment", seg.id, s.read.Offset)
+			s.read = Position{Segment: s.firstSegmentFromLocked(seg.id + 1).id}
+			continue
+		}
+
+		data := make([]byte, n)
+		if _, err := s.reader.ReadAt(data, s.read.Offset+recordHeaderSize); err != nil {
+			return Record{}, false, err
+		}
+		s.read.Offset = end
+		return Record{Data: data, Pos: s.read}, true, nil
+	}
+}
+
+func (s *Spool) rotateLocked() error {
+	if err := s.writer.Sync(); err != nil {
+		return err
+	}
+	if err := s.writer.Close(); err != nil {
+		return err
+	}
+	return s.openWriter(s.segments[len(s.segments)-1].id + 1)
+}
+
+func (s *Spool) openWriter(id uint64) error {
+	f, err := os.OpenFile(s.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.writer = f
+	s.segments = append(s.segments, &segment{id: id, created: now, modified: now})
+	return nil
+}
+
+// enforceLimitsLocked discards the oldest segments while the spool is over
+// its size or age budget. The active segment is never discarded.
+func (s *Spool) enforceLimitsLocked(now time.Time) {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		overSize := s.opts.MaxBytes > 0 && total > s.opts.MaxBytes
+		overAge := s.opts.MaxAge > 0 && now.Sub(oldest.modified) > s.opts.MaxAge
+		if !overSize && !overAge {
+			return
+		}
+
+		log.Printf("spool: discarding segment %d (%d bytes) to stay within limits", oldest.id, oldest.size)
+		s.removeSegmentLocked(oldest)
+		total -= oldest.size
+
+		next := Position{Segment: s.segments[0].id}
+		if s.read.before(next) {
+			s.read = next
+		}
+		if s.acked.before(next) {
+			s.acked = next
+		}
+	}
+}
+
+// removeAckedLocked deletes sealed segments that precede the ack cursor.
+func (s *Spool) removeAckedLocked() {
+	for len(s.segments) > 1 && s.segments[0].id < s.acked.Segment {
+		s.removeSegmentLocked(s.segments[0])
+	}
+}
+
+func (s *Spool) removeSegmentLocked(seg *segment) {
+	if s.reader != nil && s.readerSeg == seg.id {
+		s.reader.Close()
+		s.reader = nil
+	}
+	if err := os.Remove(s.segmentPath(seg.id)); err != nil && !os.IsNotExist(err) {
+		log.Printf("spool: failed to remove segment %d: %v", seg.id, err)
+	}
+	for i, cur := range s.segments {
+		if cur == seg {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Spool) segmentLocked(id uint64) *segment {
+	for _, seg := range s.segments {
+		if seg.id == id {
+			return seg
+		}
+	}
+	return nil
+}
+
+// firstSegmentFromLocked returns the first segment with an id of at least id,
+// falling back to the active segment.
+func (s *Spool) firstSegmentFromLocked(id uint64) *segment {
+	for _, seg := range s.segments {
+		if seg.id >= id {
+			return seg
+		}
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+func (s *Spool) loadSegments() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, &segment{
+			id:       id,
+			size:     info.Size(),
+			created:  info.ModTime(),
+			modified: info.ModTime(),
+		})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].id < s.segments[j].id })
+	return nil
+}
+
+func (s *Spool) loadCursor() (Position, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, cursorFileName))
+	if os.IsNotExist(err) {
+		return Position{}, nil
+	}
+	if err != nil {
+		return Position{}, err
+	}
+	var pos Position
+	if _, err := fmt.Sscanf(string(data), "%d %d", &pos.Segment, &pos.Offset); err != nil {
+		log.Printf("spool: ignoring unreadable cursor: %v", err)
+		return Position{}, nil
+	}
+	return pos, nil
+}
+
+func (s *Spool) saveCursorLocked() error {
+	tmp := filepath.Join(s.dir, cursorFileName+".tmp")
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", s.acked.Segment, s.acked.Offset)), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, cursorFileName)); err != nil {
+		return err
+	}
+	s.ackedSaved = time.Now()
+	return nil
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}